@@ -0,0 +1,300 @@
+package taglib
+
+import (
+	"errors"
+	"io"
+)
+
+// AACProperties contains properties specific to AAC audio, in MP4 files and ADTS streams.
+type AACProperties struct {
+	// ObjectType is the MPEG-4 audio object type of the core codec, such as 2 for AAC LC, leaving out the SBR and PS
+	// extensions
+	ObjectType uint
+	// SBR reports whether the stream signals spectral band replication, decoded at twice the sample rate of the core
+	SBR bool
+	// PS reports whether the stream signals parametric stereo, decoding one channel to two
+	PS bool
+	// Profile names the object type and extensions, "Main", "LC", "SSR", "LTP", "HE-AAC" for SBR, "HE-AACv2" for SBR
+	// and PS, "LD", "ELD", or "xHE-AAC". Empty if unknown
+	Profile string
+}
+
+// readAACProperties reads the audio specific config of the first AAC track of an MP4 file, or the header of the first
+// frame of an ADTS stream. It returns nil for other files. Streams which leave SBR and PS implicit, as every ADTS stream
+// does, report their core profile.
+func readAACProperties(f fileReader) (*AACProperties, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	start, err := id3v2TagSize(f)
+	if err != nil {
+		return nil, err
+	}
+	var magic [8]byte
+	if _, err := f.ReadAt(magic[:], start); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	switch {
+	case string(magic[4:8]) == "ftyp":
+		return mp4AACProperties(f, info.Size()), nil
+	case magic[0] == 0xff && magic[1]&0xf6 == 0xf0: // sync word, and layer 0
+		// the 2 bit profile is the object type less one
+		return newAACProperties(uint(magic[2]>>6)+1, false, false), nil
+	}
+	return nil, nil
+}
+
+func newAACProperties(objectType uint, sbr, ps bool) *AACProperties {
+	p := &AACProperties{ObjectType: objectType, SBR: sbr, PS: ps}
+	switch {
+	case sbr && ps:
+		p.Profile = "HE-AACv2"
+	case sbr:
+		p.Profile = "HE-AAC"
+	default:
+		p.Profile = aacObjectTypeProfiles[objectType]
+	}
+	return p
+}
+
+var aacObjectTypeProfiles = map[uint]string{
+	1:  "Main",
+	2:  "LC",
+	3:  "SSR",
+	4:  "LTP",
+	23: "LD",
+	39: "ELD",
+	42: "xHE-AAC",
+}
+
+// mp4AACProperties reads the "esds" atom of the first "mp4a" sample entry in the sample tables of an MP4 file.
+func mp4AACProperties(r io.ReaderAt, end int64) *AACProperties {
+	for _, data := range mp4SampleEntryAtoms(r, end, "mp4a", "esds") {
+		// a full atom, with a version and flags first
+		if len(data) < 4 {
+			continue
+		}
+		if p := esdsAACProperties(data[4:]); p != nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// mp4SampleEntryAtoms reads the bodies of the child atoms of type child in the audio sample entries of type format, in
+// the sample tables of every track of an MP4 file.
+func mp4SampleEntryAtoms(r io.ReaderAt, end int64, format, child string) [][]byte {
+	var bodies [][]byte
+	for _, stsd := range mp4AtomsAt(r, 0, end, "moov", "trak", "mdia", "minf", "stbl", "stsd") {
+		// a full atom with a version, flags, and an entry count, then the sample entries
+		for _, entry := range mp4Atoms(r, stsd.offset+stsd.headerSize+8, stsd.offset+stsd.size) {
+			if entry.typ != format {
+				continue
+			}
+			var version [2]byte
+			if _, err := r.ReadAt(version[:], entry.offset+entry.headerSize+8); err != nil {
+				continue
+			}
+			// the audio sample entry is 28 bytes, with 16 or 36 more in QuickTime sound versions 1 and 2
+			children := entry.offset + entry.headerSize + 28
+			switch version[1] {
+			case 1:
+				children += 16
+			case 2:
+				children += 36
+			}
+			for _, atom := range mp4Atoms(r, children, entry.offset+entry.size) {
+				if atom.typ != child || atom.size-atom.headerSize > 1<<16 {
+					continue
+				}
+				data := make([]byte, atom.size-atom.headerSize)
+				if _, err := r.ReadAt(data, atom.offset+atom.headerSize); err != nil {
+					continue
+				}
+				bodies = append(bodies, data)
+			}
+		}
+	}
+	return bodies
+}
+
+// mp4AtomsAt lists the atoms at the end of path, such as the "stsd" atoms of every track.
+func mp4AtomsAt(r io.ReaderAt, offset, end int64, path ...string) []mp4Atom {
+	var found []mp4Atom
+	for _, atom := range mp4Atoms(r, offset, end) {
+		if atom.typ != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			found = append(found, atom)
+			continue
+		}
+		found = append(found, mp4AtomsAt(r, atom.offset+atom.headerSize, atom.offset+atom.size, path[1:]...)...)
+	}
+	return found
+}
+
+// esdsAACProperties reads the ES descriptor of an "esds" atom, whose decoder config holds the object type indication,
+// and for MPEG-4 audio the audio specific config.
+func esdsAACProperties(data []byte) *AACProperties {
+	tag, es, _ := mp4Descriptor(data)
+	if tag != 0x03 || len(es) < 3 {
+		return nil
+	}
+	flags := es[2]
+	es = es[3:]
+	if flags&0x80 != 0 { // stream dependence
+		es = es[min(2, len(es)):]
+	}
+	if flags&0x40 != 0 && len(es) > 0 { // URL
+		es = es[min(1+int(es[0]), len(es)):]
+	}
+	if flags&0x20 != 0 { // OCR stream
+		es = es[min(2, len(es)):]
+	}
+
+	tag, config, _ := mp4Descriptor(es)
+	if tag != 0x04 || len(config) < 13 {
+		return nil
+	}
+	switch config[0] {
+	case 0x40: // MPEG-4 audio
+	case 0x66, 0x67, 0x68: // MPEG-2 AAC Main, LC, and SSR
+		return newAACProperties(uint(config[0]-0x66)+1, false, false)
+	default:
+		return nil
+	}
+	for rest := config[13:]; len(rest) > 0; {
+		tag, info, next := mp4Descriptor(rest)
+		if tag == 0x05 {
+			return parseAudioSpecificConfig(info)
+		}
+		if tag == 0 {
+			break
+		}
+		rest = next
+	}
+	return nil
+}
+
+// mp4Descriptor splits the first descriptor of data into its tag and body, and the data after it. The tag is 0 if data
+// is too short.
+func mp4Descriptor(data []byte) (tag byte, body, rest []byte) {
+	if len(data) < 2 {
+		return 0, nil, nil
+	}
+	tag = data[0]
+	var size int
+	i := 1
+	for ; i < len(data) && i <= 4; i++ {
+		size = size<<7 | int(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			break
+		}
+	}
+	i++
+	if i > len(data) || size > len(data)-i {
+		return 0, nil, nil
+	}
+	return tag, data[i : i+size], data[i+size:]
+}
+
+// parseAudioSpecificConfig reads the object type of an MPEG-4 audio specific config, and its SBR and PS extensions,
+// signalled explicitly as the object type, or backward compatibly after the config of the core.
+func parseAudioSpecificConfig(data []byte) *AACProperties {
+	b := bitReader{data: data}
+	objectType := b.objectType()
+	b.sampleRateIndex()
+	channelConfig := b.read(4)
+
+	var sbr, ps bool
+	if objectType == 5 || objectType == 29 {
+		sbr, ps = true, objectType == 29
+		b.sampleRateIndex()
+		objectType = b.objectType()
+	}
+	if b.err {
+		return nil
+	}
+
+	switch objectType {
+	case 1, 2, 3, 4, 6, 7, 17, 19, 20, 21, 22, 23:
+	default:
+		return newAACProperties(objectType, sbr, ps)
+	}
+	if channelConfig == 0 {
+		// a program config element, which is rare enough not to be parsed for the extensions after it
+		return newAACProperties(objectType, sbr, ps)
+	}
+
+	// the general audio specific config
+	b.read(1) // frame length
+	if b.read(1) == 1 {
+		b.read(14) // core coder delay
+	}
+	extension := b.read(1)
+	if objectType == 6 || objectType == 20 {
+		b.read(3) // layer
+	}
+	if extension == 1 {
+		switch objectType {
+		case 22:
+			b.read(16) // substream count, and layer length
+		case 17, 19, 20, 23:
+			b.read(3) // resilience flags
+		}
+		b.read(1) // extension flag 3
+	}
+
+	if !sbr && b.remaining() >= 16 && b.read(11) == 0x2b7 && b.objectType() == 5 && b.read(1) == 1 {
+		sbr = true
+		b.sampleRateIndex()
+		if b.remaining() >= 12 && b.read(11) == 0x548 {
+			ps = b.read(1) == 1
+		}
+	}
+	if b.err {
+		return newAACProperties(objectType, false, false)
+	}
+	return newAACProperties(objectType, sbr, ps)
+}
+
+// bitReader reads big endian bit fields, setting err if it reads past the end.
+type bitReader struct {
+	data []byte
+	pos  int
+	err  bool
+}
+
+func (b *bitReader) read(n int) uint {
+	var v uint
+	for range n {
+		if b.pos >= len(b.data)*8 {
+			b.err = true
+			return 0
+		}
+		v = v<<1 | uint(b.data[b.pos/8]>>(7-b.pos%8)&1)
+		b.pos++
+	}
+	return v
+}
+
+func (b *bitReader) remaining() int { return len(b.data)*8 - b.pos }
+
+func (b *bitReader) objectType() uint {
+	if t := b.read(5); t != 31 {
+		return t
+	}
+	return 32 + b.read(6)
+}
+
+func (b *bitReader) sampleRateIndex() {
+	if b.read(4) == 0xf {
+		b.read(24) // explicit sample rate
+	}
+}
@@ -0,0 +1,55 @@
+package taglib_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestAACProperties(t *testing.T) {
+	t.Parallel()
+
+	// the decoder specific info of eg.m4a, an LC config with a backward compatible SBR extension turned off
+	lc := []byte{0x05, 0x80, 0x80, 0x80, 0x05, 0x12, 0x10, 0x56, 0xe5, 0x00}
+	withConfig := func(config []byte) []byte {
+		return bytes.Replace(egM4a, lc[5:], config, 1)
+	}
+
+	tcases := []struct {
+		name string
+		data []byte
+		want *taglib.AACProperties
+	}{
+		{"eg.m4a", egM4a, &taglib.AACProperties{ObjectType: 2, Profile: "LC"}},
+		{"sbr.m4a", withConfig([]byte{0x12, 0x10, 0x56, 0xe5, 0x80}), &taglib.AACProperties{ObjectType: 2, SBR: true, Profile: "HE-AAC"}},
+		{"ps.m4a", withConfig([]byte{0xea, 0x0a, 0x08, 0x00, 0x00}), &taglib.AACProperties{ObjectType: 2, SBR: true, PS: true, Profile: "HE-AACv2"}},
+		{"eg.aac", adtsStream(1), &taglib.AACProperties{ObjectType: 2, Profile: "LC"}},
+		{"main.aac", adtsStream(0), &taglib.AACProperties{ObjectType: 1, Profile: "Main"}},
+		{"eg.flac", egFLAC, nil},
+		{"eg.mp3", egMP3, nil},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			properties, err := taglib.ReadProperties(tmpf(t, tc.data, tc.name))
+			nilErr(t, err)
+			if tc.want == nil {
+				eq(t, properties.AAC, nil)
+				return
+			}
+			if properties.AAC == nil {
+				t.Fatalf("no aac properties")
+			}
+			eq(t, *properties.AAC, *tc.want)
+		})
+	}
+}
+
+// adtsStream is a stream of silent 44.1 kHz stereo ADTS frames with the given profile.
+func adtsStream(profile byte) []byte {
+	const length = 7 + 200
+	header := []byte{0xff, 0xf1, profile<<6 | 4<<2, 2<<6 | length>>11, length >> 3 & 0xff, length&7<<5 | 0x1f, 0xfc}
+	return bytes.Repeat(append(header, make([]byte, length-7)...), 100)
+}
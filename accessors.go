@@ -0,0 +1,60 @@
+package taglib
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GetAll returns the values of key in tags, as read by [ReadTags] or [ReadTagsKeyed], or nil if there are none. Keys
+// match regardless of case.
+func GetAll[K ~string](tags map[K][]string, key K) []string {
+	if vs, ok := tags[key]; ok {
+		return vs
+	}
+	return tags[K(strings.ToUpper(string(key)))]
+}
+
+// First returns the first value of key in tags, or "" if there is none.
+func First[K ~string](tags map[K][]string, key K) string {
+	if vs := GetAll(tags, key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// GetInt parses the first value of key in tags as an integer, such as a [TrackNumber] or [BPM]. For track and disc
+// numbers written as "3/12", it returns the number before the slash. It reports false if the key is missing or its
+// value isn't a number.
+func GetInt[K ~string](tags map[K][]string, key K) (int, bool) {
+	v, _, _ := strings.Cut(First(tags, key), "/")
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// GetFloat parses the first value of key in tags as a number, such as a [ReplayGainTrackGain], leaving out a trailing
+// "dB" unit. It reports false if the key is missing or its value isn't a number.
+func GetFloat[K ~string](tags map[K][]string, key K) (float64, bool) {
+	v := strings.TrimSpace(First(tags, key))
+	v = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(v, "dB"), "DB"))
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// GetBool parses the first value of key in tags as a flag, such as [Compilation]. "1", "true", and "yes" are true, and
+// "0", "false", and "no" are false, regardless of case. It reports false if the key is missing or its value is none
+// of these.
+func GetBool[K ~string](tags map[K][]string, key K) (value, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(First(tags, key))) {
+	case "1", "true", "yes":
+		return true, true
+	case "0", "false", "no":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,48 @@
+package taglib_test
+
+import (
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestAccessors(t *testing.T) {
+	t.Parallel()
+
+	tags := map[string][]string{
+		taglib.Title:               {"Title", "Subtitle"},
+		taglib.TrackNumber:         {"3/12"},
+		taglib.BPM:                 {"not a number"},
+		taglib.ReplayGainTrackGain: {"-6.48 dB"},
+		taglib.Compilation:         {"1"},
+	}
+
+	eq(t, len(taglib.GetAll(tags, taglib.Title)), 2)
+	eq(t, taglib.First(tags, taglib.Title), "Title")
+	eq(t, taglib.First(tags, "title"), "Title")
+	eq(t, taglib.First(tags, taglib.Artist), "")
+
+	n, ok := taglib.GetInt(tags, taglib.TrackNumber)
+	eq(t, ok, true)
+	eq(t, n, 3)
+	_, ok = taglib.GetInt(tags, taglib.BPM)
+	eq(t, ok, false)
+	_, ok = taglib.GetInt(tags, taglib.DiscNumber)
+	eq(t, ok, false)
+
+	gain, ok := taglib.GetFloat(tags, taglib.ReplayGainTrackGain)
+	eq(t, ok, true)
+	eq(t, gain, -6.48)
+
+	compilation, ok := taglib.GetBool(tags, taglib.Compilation)
+	eq(t, ok, true)
+	eq(t, compilation, true)
+	_, ok = taglib.GetBool(tags, taglib.Title)
+	eq(t, ok, false)
+
+	// maps keyed by taglib.Key work too
+	keyed := map[taglib.Key][]string{taglib.TrackNumber: {"7"}}
+	n, ok = taglib.GetInt(keyed, taglib.TrackNumber)
+	eq(t, ok, true)
+	eq(t, n, 7)
+}
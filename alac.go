@@ -0,0 +1,49 @@
+package taglib
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// ALACProperties contains properties specific to Apple Lossless audio in MP4 files, from the magic cookie of its
+// sample entry.
+type ALACProperties struct {
+	// BitDepth is the bit depth of the samples, such as 16 or 24
+	BitDepth uint
+	// FramesPerPacket is the number of sample frames in each packet, 4096 by default
+	FramesPerPacket uint
+	// MaxFrameBytes is the size of the largest packet in bytes, 0 if unknown
+	MaxFrameBytes uint
+	// AverageBitrate is the average bitrate declared by the encoder in kbit/s, 0 if unknown
+	AverageBitrate uint
+}
+
+// readALACProperties reads the magic cookie of the first ALAC track of an MP4 file. It returns nil for other files.
+func readALACProperties(f fileReader) (*ALACProperties, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var magic [8]byte
+	if _, err := f.ReadAt(magic[:], 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if string(magic[4:8]) != "ftyp" {
+		return nil, nil
+	}
+
+	for _, data := range mp4SampleEntryAtoms(f, info.Size(), "alac", "alac") {
+		// a full atom with a version and flags, then the 24 byte ALAC specific config
+		if len(data) < 4+24 {
+			continue
+		}
+		config := data[4:]
+		return &ALACProperties{
+			FramesPerPacket: uint(binary.BigEndian.Uint32(config[0:])),
+			BitDepth:        uint(config[5]),
+			MaxFrameBytes:   uint(binary.BigEndian.Uint32(config[12:])),
+			AverageBitrate:  uint(binary.BigEndian.Uint32(config[16:]) / 1000),
+		}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package taglib_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestALACProperties(t *testing.T) {
+	t.Parallel()
+
+	properties, err := taglib.ReadProperties(tmpf(t, alacM4a(t), "alac.m4a"))
+	nilErr(t, err)
+	if properties.ALAC == nil {
+		t.Fatalf("no alac properties")
+	}
+	eq(t, *properties.ALAC, taglib.ALACProperties{BitDepth: 24, FramesPerPacket: 4096, MaxFrameBytes: 24588, AverageBitrate: 2116})
+	eq(t, properties.AAC, nil)
+	eq(t, properties.BitsPerSample, 24)
+
+	properties, err = taglib.ReadProperties(tmpf(t, egM4a, "eg.m4a"))
+	nilErr(t, err)
+	eq(t, properties.ALAC, nil)
+}
+
+// alacM4a is eg.m4a with its AAC sample entry replaced by a 24 bit ALAC one, and a free atom making up the difference
+// in size.
+func alacM4a(t testing.TB) []byte {
+	i := bytes.Index(egM4a, []byte("stsd")) - 4
+	size := int(binary.BigEndian.Uint32(egM4a[i:]))
+
+	config := binary.BigEndian.AppendUint32(nil, 4096) // frames per packet
+	config = append(config, 0, 24, 40, 10, 14, 2)      // version, bit depth, rice parameters, channels
+	config = binary.BigEndian.AppendUint16(config, 255)
+	config = binary.BigEndian.AppendUint32(config, 24588)   // max frame bytes
+	config = binary.BigEndian.AppendUint32(config, 2116800) // average bitrate
+	config = binary.BigEndian.AppendUint32(config, 44100)
+	cookie := atom("alac", append(make([]byte, 4), config...))
+
+	// the 28 bytes of the audio sample entry are kept
+	entry := atom("alac", append(bytes.Clone(egM4a[i+24:i+24+28]), cookie...))
+	stsd := atom("stsd", append(bytes.Clone(egM4a[i+8:i+16]), entry...))
+	if len(stsd)+8 > size {
+		t.Fatalf("alac entry doesn't fit")
+	}
+	free := atom("free", make([]byte, size-len(stsd)-8))
+
+	var b []byte
+	b = append(b, egM4a[:i]...)
+	b = append(b, stsd...)
+	b = append(b, free...)
+	return append(b, egM4a[i+size:]...)
+}
+
+func atom(name string, data []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	b = append(b, name...)
+	return append(b, data...)
+}
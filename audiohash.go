@@ -0,0 +1,458 @@
+package taglib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+// HashAudio writes the audio data of the file at path to h, leaving out tags, other metadata, and padding. Files with
+// the same audio stream hash the same regardless of their tags, without decoding the audio.
+//
+// The audio data is the frames of FLAC files, the packets after the headers of Ogg streams, the "data" and "SSND"
+// chunks of WAV and AIFF files, the sample chunks of DSF and DSDIFF files, the "mdat" atoms of MP4 files, and the data
+// object of ASF files. For MPEG and other formats, it is everything between a leading ID3v2 tag and trailing ID3v1,
+// APEv2, and Lyrics3 tags.
+func HashAudio(path string, h hash.Hash) (err error) {
+	defer wrapErr(&err, "hash audio", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	regions, err := audioRegions(f)
+	if err != nil {
+		return err
+	}
+	if regions == nil {
+		return walkOggAudio(f, func(_ int64, data []byte) error {
+			_, err := h.Write(data)
+			return err
+		})
+	}
+
+	for _, r := range regions {
+		if _, err := io.Copy(h, io.NewSectionReader(f, r.start, r.end-r.start)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadAudioRange reports where the audio data of the file at path begins and the length up to where it ends, after
+// any leading ID3v2 tag and metadata such as FLAC blocks, and before trailing ID3v1, APEv2, and Lyrics3 tags. Servers
+// can serve the range as the raw stream, and map byte range seeks onto it.
+//
+// The range covers the audio data found by [HashAudio], and anything between its parts, such as the page headers of
+// Ogg streams or the chunks between the "data" chunks of WAV files.
+func ReadAudioRange(path string) (offset, length int64, err error) {
+	defer wrapErr(&err, "read audio range", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	offset, length, _, err = audioExtent(f)
+	return offset, length, err
+}
+
+// audioExtent finds the range of the audio data of a file, as reported by [ReadAudioRange], and the size of the audio
+// data within it, as hashed by [HashAudio], without reading it, other than the one pass over the pages of Ogg streams.
+func audioExtent(f fileReader) (offset, length, size int64, err error) {
+	regions, err := audioRegions(f)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if regions == nil {
+		err := walkOggAudio(f, func(offset int64, data []byte) error {
+			if regions == nil {
+				regions = []region{{offset, offset}}
+			}
+			regions[0].end = offset + int64(len(data))
+			size += int64(len(data))
+			return nil
+		})
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if regions == nil {
+			return 0, 0, 0, fmt.Errorf("find audio data: %w", ErrInvalidFile)
+		}
+	} else {
+		for _, r := range regions {
+			size += r.end - r.start
+		}
+	}
+	start, end := regions[0].start, regions[len(regions)-1].end
+	return start, end - start, size, nil
+}
+
+// readAudioExtent computes the average bitrate in kbit/s of the audio data of a file of the given size and length in
+// seconds, and the size of the file outside its audio range. Both are 0 if the audio data can't be found.
+func readAudioExtent(f fileReader, fileSize int64, seconds float64) (averageBitrate float64, metadataSize int64, err error) {
+	_, length, size, err := audioExtent(f)
+	if errors.Is(err, ErrInvalidFile) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if seconds > 0 {
+		averageBitrate = float64(size) * 8 / seconds / 1000
+	}
+	return averageBitrate, fileSize - length, nil
+}
+
+// audioRegions finds the audio data of a file, or returns nil for Ogg files, whose audio is read packet by packet
+// with [walkOggAudio].
+func audioRegions(f fileReader) ([]region, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+
+	start, err := id3v2TagSize(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var magic [16]byte
+	if _, err := f.ReadAt(magic[:], start); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if string(magic[:4]) == "OggS" {
+		return nil, nil
+	}
+
+	var regions []region
+	switch {
+	case string(magic[:4]) == "fLaC":
+		blocks, err := readFLACBlocks(f)
+		if err != nil {
+			return nil, err
+		}
+		last := blocks[len(blocks)-1]
+		end, err := trailingTagsStart(f, size)
+		if err != nil {
+			return nil, err
+		}
+		regions = []region{{last.offset + 4 + int64(last.length), end}}
+	case isWideWAV(magic[:]):
+		if w := wideWAVChunks(f, size); w != nil && w.data.end > w.data.start {
+			regions = []region{w.data}
+		}
+	case string(magic[:4]) == "RIFF":
+		regions = chunkRegions(f, start+12, size, binary.LittleEndian, 4, "data")
+	case string(magic[:4]) == "FORM":
+		regions = chunkRegions(f, start+12, size, binary.BigEndian, 4, "SSND")
+	case string(magic[:4]) == "FRM8":
+		regions = chunkRegions(f, start+16, size, binary.BigEndian, 8, "DSD ", "DST ")
+	case string(magic[:4]) == "DSD ":
+		regions = dsfRegions(f, size)
+	case string(magic[4:8]) == "ftyp":
+		regions = mp4Regions(f, size)
+	case bytes.Equal(magic[:], asfHeaderGUID):
+		regions = asfRegions(f, size)
+	default:
+		end, err := trailingTagsStart(f, size)
+		if err != nil {
+			return nil, err
+		}
+		start, err = skipZeros(f, start, end)
+		if err != nil {
+			return nil, err
+		}
+		regions = []region{{start, end}}
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("find audio data: %w", ErrInvalidFile)
+	}
+	return regions, nil
+}
+
+// region is a range of a file from start to end.
+type region struct {
+	start, end int64
+}
+
+// chunkRegions finds the payloads of the chunks with the given IDs in an IFF style container, where each chunk has a
+// 4 byte ID and a size of sizeLen bytes not counting the header, and odd sized chunks are padded.
+func chunkRegions(r io.ReaderAt, offset, end int64, order binary.ByteOrder, sizeLen int, ids ...string) []region {
+	var regions []region
+	header := make([]byte, 4+sizeLen)
+	for offset+int64(len(header)) <= end {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			break
+		}
+		var size int64
+		if sizeLen == 8 {
+			size = int64(order.Uint64(header[4:]))
+		} else {
+			size = int64(order.Uint32(header[4:]))
+		}
+		payload := offset + int64(len(header))
+		if size < 0 || size > end-payload {
+			size = end - payload
+		}
+		for _, id := range ids {
+			if string(header[:4]) == id {
+				regions = append(regions, region{payload, payload + size})
+			}
+		}
+		offset = payload + size + size%2
+	}
+	return regions
+}
+
+// dsfRegions finds the samples of a DSF file, in the chunk after the "DSD " and "fmt " chunks. Chunk sizes include
+// their 12 byte headers.
+func dsfRegions(r io.ReaderAt, end int64) []region {
+	var header [12]byte
+	for offset := int64(0); offset+12 <= end; {
+		if _, err := r.ReadAt(header[:], offset); err != nil {
+			break
+		}
+		size := int64(binary.LittleEndian.Uint64(header[4:]))
+		if size < 12 || size > end-offset {
+			size = end - offset
+		}
+		if string(header[:4]) == "data" {
+			return []region{{offset + 12, offset + size}}
+		}
+		offset += size
+	}
+	return nil
+}
+
+// mp4Regions finds the top level "mdat" atoms of an MP4 file.
+func mp4Regions(r io.ReaderAt, end int64) []region {
+	var regions []region
+	for _, atom := range mp4Atoms(r, 0, end) {
+		if atom.typ == "mdat" {
+			regions = append(regions, region{atom.offset + atom.headerSize, atom.offset + atom.size})
+		}
+	}
+	return regions
+}
+
+// mp4Atom is an atom of an MP4 file, where size includes the header.
+type mp4Atom struct {
+	typ                      string
+	offset, headerSize, size int64
+}
+
+// mp4Atoms lists the atoms between offset and end, such as the top level of a file or the children of another atom.
+func mp4Atoms(r io.ReaderAt, offset, end int64) []mp4Atom {
+	var atoms []mp4Atom
+	var header [16]byte
+	for offset+8 <= end {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			break
+		}
+		headerSize, size := int64(8), int64(binary.BigEndian.Uint32(header[:]))
+		switch size {
+		case 0: // to the end of the file
+			size = end - offset
+		case 1: // 64 bit size
+			if _, err := r.ReadAt(header[8:], offset+8); err != nil {
+				return atoms
+			}
+			headerSize, size = 16, int64(binary.BigEndian.Uint64(header[8:]))
+		}
+		if size < headerSize || size > end-offset {
+			size = end - offset
+		}
+		atoms = append(atoms, mp4Atom{string(header[4:8]), offset, headerSize, size})
+		offset += size
+	}
+	return atoms
+}
+
+var (
+	asfHeaderGUID = []byte{0x30, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11, 0xa6, 0xd9, 0x00, 0xaa, 0x00, 0x62, 0xce, 0x6c}
+	asfDataGUID   = []byte{0x36, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11, 0xa6, 0xd9, 0x00, 0xaa, 0x00, 0x62, 0xce, 0x6c}
+)
+
+// asfRegions finds the data object of an ASF file. Object sizes include their 24 byte headers.
+func asfRegions(r io.ReaderAt, end int64) []region {
+	var header [24]byte
+	for offset := int64(0); offset+24 <= end; {
+		if _, err := r.ReadAt(header[:], offset); err != nil {
+			break
+		}
+		size := int64(binary.LittleEndian.Uint64(header[16:]))
+		if size < 24 || size > end-offset {
+			size = end - offset
+		}
+		if bytes.Equal(header[:16], asfDataGUID) {
+			return []region{{offset + 24, offset + size}}
+		}
+		offset += size
+	}
+	return nil
+}
+
+// walkOggAudio calls fn with the offset and data of each packet segment of the first logical stream after its header
+// packets, reading the pages in one buffered pass. Page headers are left out too, since their sequence numbers and
+// checksums change when a comment header grows onto another page. The walk stops at the end of the file or at data
+// which isn't a page, such as a trailing ID3v1 tag, and fails with [ErrInvalidFile] on a truncated page.
+func walkOggAudio(r io.ReaderAt, fn func(offset int64, data []byte) error) error {
+	packets := oggHeaderPackets(r, 1)
+	if len(packets) == 0 {
+		return fmt.Errorf("read first packet: %w", ErrInvalidFile)
+	}
+	id := packets[0]
+
+	isHeader := func(packet int, _ byte) bool { return packet < 1 }
+	switch {
+	case bytes.HasPrefix(id, []byte("\x01vorbis")):
+		isHeader = func(packet int, _ byte) bool { return packet < 3 }
+	case bytes.HasPrefix(id, []byte("OpusHead")):
+		isHeader = func(packet int, _ byte) bool { return packet < 2 }
+	case bytes.HasPrefix(id, []byte("Speex   ")) && len(id) >= 80:
+		extra := int(binary.LittleEndian.Uint32(id[76:]))
+		isHeader = func(packet int, _ byte) bool { return packet < 2+extra }
+	case bytes.HasPrefix(id, []byte("\x7fFLAC")):
+		// metadata packets start with a block header, audio frames with a sync code
+		isHeader = func(packet int, first byte) bool { return packet < 1 || first != 0xff }
+	}
+
+	br := bufio.NewReaderSize(io.NewSectionReader(r, 0, math.MaxInt64), 64<<10)
+	var header [27]byte
+	var segments, data [255]byte
+	var serial uint32
+	var packet int
+	var firstByte byte
+	first := true
+	for offset := int64(0); ; {
+		n, err := io.ReadFull(br, header[:])
+		if n < 4 || string(header[:4]) != "OggS" {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("page header at %d: %w", offset, ErrInvalidFile)
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:])
+		if offset == 0 {
+			serial = pageSerial
+		}
+		if _, err := io.ReadFull(br, segments[:header[26]]); err != nil {
+			return fmt.Errorf("page segments at %d: %w", offset, ErrInvalidFile)
+		}
+		offset += 27 + int64(header[26])
+
+		for _, size := range segments[:header[26]] {
+			if _, err := io.ReadFull(br, data[:size]); err != nil {
+				return fmt.Errorf("segment at %d: %w", offset, ErrInvalidFile)
+			}
+			if pageSerial == serial && size > 0 {
+				if first {
+					firstByte = data[0]
+				}
+				first = false
+				if !isHeader(packet, firstByte) {
+					if err := fn(offset, data[:size]); err != nil {
+						return err
+					}
+				}
+			}
+			offset += int64(size)
+			if size < 255 && pageSerial == serial {
+				packet++
+				first = true
+			}
+		}
+	}
+}
+
+// trailingTagsStart returns the offset where the ID3v1, APEv2, and Lyrics3v2 tags at the end of a file start.
+func trailingTagsStart(r io.ReaderAt, end int64) (int64, error) {
+	tags, err := trailingTags(r, end)
+	if err != nil {
+		return 0, err
+	}
+	if len(tags) > 0 {
+		return tags[len(tags)-1].Offset, nil
+	}
+	return end, nil
+}
+
+// trailingTags finds the ID3v1, APEv2, and Lyrics3v2 tags at the end of a file, which can follow each other in any
+// order. They are returned from the last to the first.
+func trailingTags(r io.ReaderAt, end int64) ([]TagRegion, error) {
+	var tags []TagRegion
+	for {
+		switch {
+		case end >= 128 && hasAt(r, end-128, "TAG"):
+			end -= 128
+			tags = append(tags, TagRegion{Kind: "ID3v1", Offset: end, Length: 128})
+		case end >= 32 && hasAt(r, end-32, "APETAGEX"):
+			var footer [32]byte
+			if _, err := r.ReadAt(footer[:], end-32); err != nil {
+				return nil, err
+			}
+			size := int64(binary.LittleEndian.Uint32(footer[12:])) // items and footer
+			if binary.LittleEndian.Uint32(footer[20:])&(1<<31) != 0 {
+				size += 32 // header
+			}
+			if size > end {
+				return tags, nil
+			}
+			end -= size
+			tags = append(tags, TagRegion{Kind: "APEv2", Offset: end, Length: size})
+		case end >= 15 && hasAt(r, end-9, "LYRICS200"):
+			var sizeField [6]byte
+			if _, err := r.ReadAt(sizeField[:], end-15); err != nil {
+				return nil, err
+			}
+			size, err := strconv.Atoi(string(sizeField[:]))
+			if err != nil || int64(size)+15 > end {
+				return tags, nil
+			}
+			end -= int64(size) + 15
+			tags = append(tags, TagRegion{Kind: "Lyrics3v2", Offset: end, Length: int64(size) + 15})
+		default:
+			return tags, nil
+		}
+	}
+}
+
+func hasAt(r io.ReaderAt, offset int64, s string) bool {
+	b := make([]byte, len(s))
+	if _, err := r.ReadAt(b, offset); err != nil {
+		return false
+	}
+	return string(b) == s
+}
+
+// skipZeros returns the offset of the first non zero byte from start, such as after padding following an ID3v2 tag.
+func skipZeros(r io.ReaderAt, start, end int64) (int64, error) {
+	buf := make([]byte, 4096)
+	for start < end {
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), end-start)], start)
+		for _, b := range buf[:n] {
+			if b != 0 {
+				return start, nil
+			}
+			start++
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return start, nil
+}
@@ -0,0 +1,103 @@
+package taglib_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestHashAudio(t *testing.T) {
+	t.Parallel()
+
+	empty := sha256.Sum256(nil)
+
+	for _, path := range append(testPaths(t), nichePaths(t)...) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			before := sha256.New()
+			nilErr(t, taglib.HashAudio(path, before))
+
+			// tags and an image which grow the metadata
+			err := taglib.WriteTags(path, map[string][]string{
+				taglib.Title:   {"Title"},
+				taglib.Comment: {string(bytes.Repeat([]byte("comment "), 1024))},
+			}, taglib.Clear)
+			nilErr(t, err)
+			nilErr(t, taglib.WriteImage(path, coverJPG))
+
+			after := sha256.New()
+			nilErr(t, taglib.HashAudio(path, after))
+
+			eq(t, string(before.Sum(nil)), string(after.Sum(nil)))
+
+			// the Vorbis example has header packets only
+			if filepath.Base(path) != "eg.ogg" {
+				eq(t, string(after.Sum(nil)) != string(empty[:]), true)
+			}
+		})
+	}
+}
+
+func TestHashAudioOgg(t *testing.T) {
+	t.Parallel()
+
+	want := sha256.New()
+	nilErr(t, taglib.HashAudio(tmpf(t, egOpus, "eg.opus"), want))
+
+	// data after the last page ends the stream
+	trailing := append(bytes.Clone(egOpus), "TAG"+strings.Repeat("\x00", 125)...)
+	got := sha256.New()
+	nilErr(t, taglib.HashAudio(tmpf(t, trailing, "eg.opus"), got))
+	eq(t, string(got.Sum(nil)), string(want.Sum(nil)))
+
+	// a page cut short doesn't
+	err := taglib.HashAudio(tmpf(t, egOpus[:len(egOpus)-10], "eg.opus"), sha256.New())
+	eq(t, errors.Is(err, taglib.ErrInvalidFile), true)
+}
+
+func TestReadAudioRange(t *testing.T) {
+	t.Parallel()
+
+	// an MP3 file with a leading ID3v2 tag and a trailing ID3v1 tag
+	path := tmpf(t, egMP3, "eg.mp3")
+	nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, 0))
+	data, err := os.ReadFile(path)
+	nilErr(t, err)
+
+	offset, length, err := taglib.ReadAudioRange(path)
+	nilErr(t, err)
+	regions, err := taglib.ReadTagRegions(path)
+	nilErr(t, err)
+	eq(t, regions[0].Kind, "ID3v2")
+	eq(t, regions[len(regions)-1].Kind, "ID3v1")
+	eq(t, offset >= regions[0].Offset+regions[0].Length, true)
+	eq(t, offset+length, regions[len(regions)-1].Offset)
+
+	// the range holds the audio data hashed by HashAudio
+	want := sha256.New()
+	nilErr(t, taglib.HashAudio(path, want))
+	got := sha256.Sum256(data[offset : offset+length])
+	eq(t, string(got[:]), string(want.Sum(nil)))
+
+	// a FLAC file, whose audio follows the last metadata block
+	path = tmpf(t, egFLAC, "eg.flac")
+	offset, length, err = taglib.ReadAudioRange(path)
+	nilErr(t, err)
+	regions, err = taglib.ReadTagRegions(path)
+	nilErr(t, err)
+	last := regions[len(regions)-1]
+	eq(t, offset, last.Offset+last.Length)
+	eq(t, offset+length, int64(len(egFLAC)))
+
+	// an Ogg file, whose audio follows the header packets
+	path = tmpf(t, egOpus, "eg.opus")
+	offset, length, err = taglib.ReadAudioRange(path)
+	nilErr(t, err)
+	eq(t, offset > 0, true)
+	eq(t, offset+length, int64(len(egOpus)))
+}
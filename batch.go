@@ -0,0 +1,95 @@
+package taglib
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// WriteTagsBatch writes the metadata key-value pairs of each path in files, like [WriteTags] with opts, for retagging
+// an album in one call. Files in the same directory are written one after another with one module, rather than
+// instantiating a module for each. A file which fails doesn't stop the others. It returns the error of each file which
+// failed, keyed by its path in files, and nil if all were written.
+func WriteTagsBatch(files map[string]map[string][]string, opts WriteOption) map[string]error {
+	errs := map[string]error{}
+	byDir := map[string][]string{}
+	abs := map[string]string{}
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		p, err := filepath.Abs(path)
+		if err != nil {
+			errs[path] = &Error{Op: "write tags", Path: path, Err: fmt.Errorf("make path abs %w", err)}
+			continue
+		}
+		abs[path] = p
+		byDir[filepath.Dir(p)] = append(byDir[filepath.Dir(p)], path)
+	}
+
+	cfg := newWriteConfig(opts)
+	for _, dir := range slices.Sorted(maps.Keys(byDir)) {
+		b := batchWriter{dir: dir, maxMemory: batchMaxMemory}
+		for _, path := range byDir[dir] {
+			if err := b.write(abs[path], files[path], cfg); err != nil {
+				errs[path] = &Error{Op: "write tags", Path: abs[path], Err: err}
+			}
+		}
+		b.close()
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// batchMaxMemory is the size the memory of a batch module may grow to before it is replaced. Wasm memory never
+// shrinks, and the binary has no free export, so the arguments and results of every call are leaked and a module
+// reused for a large directory would otherwise grow without limit.
+const batchMaxMemory = 64 << 20
+
+// batchWriter writes the files of one directory, instantiating its module on the first write, and again once its
+// memory has grown past maxMemory.
+type batchWriter struct {
+	dir       string
+	maxMemory uint32
+	mod       *module
+}
+
+func (b *batchWriter) write(path string, tags map[string][]string, cfg writeConfig) error {
+	start := time.Now()
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	limits := currentLimits()
+	if err := checkLimits(path, limits); err != nil {
+		return err
+	}
+
+	tags, keyErrs := validTags(readKeyRule(path), tags, cfg)
+	if len(keyErrs) > 0 && len(tags) == 0 && cfg.opts&Clear == 0 {
+		return errors.Join(keyErrs...)
+	}
+
+	if b.mod == nil {
+		mod, err := newModuleDir(b.dir, false, limits, start)
+		if err != nil {
+			return fmt.Errorf("init module: %w", err)
+		}
+		b.mod = &mod
+	}
+	err := saveTagsModule(b.mod, path, tags, nil, cfg)
+	if err != nil || b.mod.mod.Memory().Size() > b.maxMemory {
+		// a failed call may leave the module in a bad state, so the next file gets a new one
+		b.close()
+	}
+	return joinKeyErrors(keyErrs, err)
+}
+
+func (b *batchWriter) close() {
+	if b.mod != nil {
+		b.mod.close()
+		b.mod = nil
+	}
+}
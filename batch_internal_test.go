@@ -0,0 +1,39 @@
+package taglib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBatchWriterRecyclesModule(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("testdata/eg.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "eg.mp3")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tags := map[string][]string{"TITLE": {"Title"}}
+
+	b := batchWriter{dir: dir, maxMemory: batchMaxMemory}
+	defer b.close()
+	if err := b.write(path, tags, newWriteConfig(0)); err != nil {
+		t.Fatal(err)
+	}
+	if b.mod == nil {
+		t.Fatal("module replaced below the memory limit")
+	}
+
+	b.maxMemory = b.mod.mod.Memory().Size() - 1
+	if err := b.write(path, tags, newWriteConfig(0)); err != nil {
+		t.Fatal(err)
+	}
+	if b.mod != nil {
+		t.Fatal("module kept past the memory limit")
+	}
+}
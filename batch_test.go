@@ -0,0 +1,45 @@
+package taglib_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestWriteTagsBatch(t *testing.T) {
+	t.Parallel()
+
+	album := t.TempDir()
+	track1 := filepath.Join(album, "1.flac")
+	track2 := filepath.Join(album, "2.mp3")
+	nilErr(t, os.WriteFile(track1, egFLAC, 0o644))
+	nilErr(t, os.WriteFile(track2, egMP3, 0o644))
+	other := tmpf(t, egM4a, "3.m4a")
+	missing := filepath.Join(album, "missing.flac")
+
+	errs := taglib.WriteTagsBatch(map[string]map[string][]string{
+		track1:  {taglib.Album: {"Album"}, taglib.TrackNumber: {"1"}},
+		track2:  {taglib.Album: {"Album"}, taglib.TrackNumber: {"2"}},
+		other:   {taglib.Album: {"Other"}},
+		missing: {taglib.Album: {"Album"}},
+	}, taglib.Clear)
+	eq(t, len(errs), 1)
+	eq(t, errors.Is(errs[missing], fs.ErrNotExist), true)
+
+	for path, want := range map[string]map[string][]string{
+		track1: {taglib.Album: {"Album"}, taglib.TrackNumber: {"1"}},
+		track2: {taglib.Album: {"Album"}, taglib.TrackNumber: {"2"}},
+		other:  {taglib.Album: {"Other"}},
+	} {
+		tags, err := taglib.ReadTags(path)
+		nilErr(t, err)
+		tagEq(t, tags, want)
+	}
+
+	errs = taglib.WriteTagsBatch(map[string]map[string][]string{track1: {taglib.Title: {"Title"}}}, 0)
+	eq(t, errs == nil, true)
+}
@@ -0,0 +1,125 @@
+package taglib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cacheConfig is the cache directory set with [SetCacheDir], which takes precedence over the environment.
+var cacheConfig struct {
+	dir string
+	set bool
+}
+
+// SetCacheDir sets the directory of the on-disk cache of the compiled Wasm module, which makes the first call of later
+// processes faster. An empty dir disables the cache. It takes precedence over the GO_TAGLIB_CACHE_DIR and
+// GO_TAGLIB_NOCACHE environment variables, and fails once the module has been compiled by the first read or write.
+func SetCacheDir(dir string) error {
+	runtimeState.Lock()
+	defer runtimeState.Unlock()
+	if runtimeState.rc.Load() != nil {
+		return fmt.Errorf("set cache dir: module already compiled")
+	}
+	cacheConfig.dir, cacheConfig.set = dir, true
+	return nil
+}
+
+// defaultCacheDir is the base directory of the compilation cache unless one is set.
+var defaultCacheDir = filepath.Join(os.TempDir(), "go-taglib-wasm")
+
+// cacheDir returns the base directory of the compilation cache, or "" if it is disabled. By default it is
+// "go-taglib-wasm" in the temporary directory.
+func cacheDir() string {
+	switch {
+	case cacheConfig.set:
+		return cacheConfig.dir
+	case os.Getenv("GO_TAGLIB_NOCACHE") != "":
+		return ""
+	case os.Getenv("GO_TAGLIB_CACHE_DIR") != "":
+		return os.Getenv("GO_TAGLIB_CACHE_DIR")
+	}
+	return defaultCacheDir
+}
+
+// binaryCacheDir returns the directory for the cache of bin, in a "go-taglib" directory in base so that a base
+// shared with other programs is left alone. It marks the directory as used, and removes the directories of other
+// binaries unused for [cacheMaxAge].
+func binaryCacheDir(base string, bin []byte) string {
+	sum := sha256.Sum256(bin)
+	name := hex.EncodeToString(sum[:8])
+	owned := filepath.Join(base, "go-taglib")
+	now := time.Now()
+	_ = os.Chtimes(filepath.Join(owned, name), now, now)
+	pruneCache(owned, name, binaryCacheEntry)
+	if base == defaultCacheDir {
+		// versions before the cache was split by binary wrote wazero's directories to the default base directly
+		pruneCache(base, "", legacyCacheEntry)
+	}
+	return filepath.Join(owned, name)
+}
+
+// cacheMaxAge is how long the directory of a binary is kept unused. Each process marks the directory of its binary as
+// used when it starts, so programs running different versions of the package keep each other's directories.
+const cacheMaxAge = 30 * 24 * time.Hour
+
+var (
+	// binaryCacheEntry matches the directories of each binary
+	binaryCacheEntry = regexp.MustCompile(`^[0-9a-f]{16}$`)
+	// legacyCacheEntry matches the directories wazero wrote before the cache was split by binary
+	legacyCacheEntry = regexp.MustCompile(`^wazero-.+$`)
+)
+
+// cacheLockName is the lock file of a cache directory, held while the module is compiled into it and while it is
+// pruned. One lock for the whole directory means no lock files are left behind by pruned entries.
+const cacheLockName = "lock"
+
+// pruneCache removes the directories in dir matching stale other than keep which are unused for [cacheMaxAge], and the
+// lock files older versions of the package kept next to each. Nothing is pruned while another process holds the lock
+// of dir, nor on platforms without file locks. Errors are ignored, since another process may be using or removing the
+// same entries.
+func pruneCache(dir, keep string, stale *regexp.Regexp) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	lock, ok, err := lockFile(filepath.Join(dir, cacheLockName), false)
+	if err != nil || !ok {
+		return
+	}
+	defer lock.Close()
+
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if name, ok := strings.CutSuffix(e.Name(), ".lock"); ok && !e.IsDir() && stale.MatchString(name) {
+			_ = os.Remove(path)
+			continue
+		}
+		if !e.IsDir() || e.Name() == keep || !stale.MatchString(e.Name()) {
+			continue
+		}
+		if info, err := e.Info(); err != nil || time.Since(info.ModTime()) < cacheMaxAge {
+			continue
+		}
+		_ = os.RemoveAll(path)
+	}
+}
+
+// lockCacheDir locks the cache directory of the binaries containing dir while dir is filled, so that processes starting
+// at once wait for the first to compile the module and then read it from the cache, rather than each compiling it. It
+// is best effort: if the lock can't be taken, the cache is used without it. The returned function releases the lock.
+func lockCacheDir(dir string) (unlock func()) {
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return func() {}
+	}
+	lock, ok, err := lockFile(filepath.Join(filepath.Dir(dir), cacheLockName), true)
+	if err != nil || !ok {
+		return func() {}
+	}
+	return func() { lock.Close() }
+}
@@ -0,0 +1,74 @@
+//go:build unix
+
+package taglib_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestCacheProcesses(t *testing.T) {
+	if path := os.Getenv("GO_TAGLIB_TEST_READ"); path != "" {
+		_, err := taglib.ReadTags(path)
+		nilErr(t, err)
+		return
+	}
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := tmpf(t, egFLAC, "eg.flac")
+
+	// the entries of another binary are pruned once unused for long, and those of other programs sharing the
+	// directory are left
+	stale := filepath.Join(dir, "go-taglib", "0123456789abcdef")
+	nilErr(t, os.MkdirAll(stale, 0o755))
+	old := time.Now().AddDate(0, -2, 0)
+	nilErr(t, os.Chtimes(stale, old, old))
+	recent := filepath.Join(dir, "go-taglib", "fedcba9876543210")
+	nilErr(t, os.MkdirAll(recent, 0o755))
+	// as are the lock files older versions kept next to each entry
+	nilErr(t, os.WriteFile(stale+".lock", nil, 0o644))
+	for _, name := range []string{"0123456789abcdef", "wazero-v1-amd64"} {
+		nilErr(t, os.Mkdir(filepath.Join(dir, name), 0o755))
+	}
+
+	// processes starting at once share the cache
+	errs := make([]error, 4)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestCacheProcesses$")
+			cmd.Env = append(os.Environ(), "GO_TAGLIB_TEST_READ="+path, "GO_TAGLIB_CACHE_DIR="+dir)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs[i] = fmt.Errorf("%w: %s", err, out)
+			}
+		}()
+	}
+	wg.Wait()
+	nilErr(t, errors.Join(errs...))
+
+	entries, err := os.ReadDir(dir)
+	nilErr(t, err)
+	eq(t, len(entries), 3)
+
+	// the directory of the binary, the recently used directory, and the lock of the whole directory
+	entries, err = os.ReadDir(filepath.Join(dir, "go-taglib"))
+	nilErr(t, err)
+	eq(t, len(entries), 3)
+	_, err = os.Stat(stale)
+	eq(t, errors.Is(err, os.ErrNotExist), true)
+	_, err = os.Stat(recent)
+	nilErr(t, err)
+	_, err = os.Stat(stale + ".lock")
+	eq(t, errors.Is(err, os.ErrNotExist), true)
+}
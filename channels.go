@@ -0,0 +1,103 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// readChannelLayout names the channel configuration of a file with the given number of channels, from the channel
+// mode of MPEG frames, the channel mask of WAVE_FORMAT_EXTENSIBLE WAV files, and the channel orders defined for FLAC,
+// Vorbis, and Opus. Other formats only have a layout for mono and stereo. It returns "" if the layout is unknown.
+func readChannelLayout(f fileReader, channels uint, mpeg *MPEGProperties) (string, error) {
+	if mpeg != nil {
+		return mpeg.ChannelMode, nil
+	}
+
+	start, err := id3v2TagSize(f)
+	if err != nil {
+		return "", err
+	}
+	var magic [4]byte
+	if _, err := f.ReadAt(magic[:], start); err != nil && err != io.EOF {
+		return "", err
+	}
+
+	switch string(magic[:]) {
+	case "fLaC":
+		return channelOrderLayout(channels), nil
+	case "OggS":
+		packets := oggHeaderPackets(f, 1)
+		// Opus streams with mapping family 255 have channels in no defined order
+		if len(packets) > 0 && bytes.HasPrefix(packets[0], []byte("OpusHead")) && len(packets[0]) > 18 && packets[0][18] > 1 {
+			return "", nil
+		}
+		return channelOrderLayout(channels), nil
+	case "RIFF":
+		info, err := f.Stat()
+		if err != nil {
+			return "", err
+		}
+		for _, r := range chunkRegions(f, start+12, info.Size(), binary.LittleEndian, 4, "fmt ") {
+			var format [24]byte
+			if r.end-r.start < int64(len(format)) {
+				break
+			}
+			if _, err := f.ReadAt(format[:], r.start); err != nil {
+				return "", err
+			}
+			if binary.LittleEndian.Uint16(format[0:]) == 0xfffe { // WAVE_FORMAT_EXTENSIBLE
+				return channelMasks[binary.LittleEndian.Uint32(format[20:])], nil
+			}
+		}
+	}
+	return channelCountLayout(channels), nil
+}
+
+// channelCountLayout names the layouts which follow from the number of channels alone.
+func channelCountLayout(channels uint) string {
+	switch channels {
+	case 1:
+		return "mono"
+	case 2:
+		return "stereo"
+	}
+	return ""
+}
+
+// channelOrderLayout names the layouts of the channel orders shared by FLAC, Vorbis, and Opus mapping family 1.
+func channelOrderLayout(channels uint) string {
+	switch channels {
+	case 3:
+		return "3.0"
+	case 4:
+		return "quad"
+	case 5:
+		return "5.0"
+	case 6:
+		return "5.1"
+	case 7:
+		return "6.1"
+	case 8:
+		return "7.1"
+	}
+	return channelCountLayout(channels)
+}
+
+// channelMasks names the speaker positions of WAVE_FORMAT_EXTENSIBLE channel masks.
+var channelMasks = map[uint32]string{
+	0x004: "mono",
+	0x003: "stereo",
+	0x00b: "2.1",
+	0x007: "3.0",
+	0x033: "quad",
+	0x603: "quad",
+	0x037: "5.0",
+	0x607: "5.0",
+	0x03f: "5.1",
+	0x60f: "5.1",
+	0x70f: "6.1",
+	0x13f: "6.1",
+	0x63f: "7.1",
+	0x0ff: "7.1",
+}
@@ -0,0 +1,58 @@
+package taglib_test
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestChannelLayout(t *testing.T) {
+	t.Parallel()
+
+	want := map[string]string{
+		"eg.flac": "stereo",
+		"eg.mp3":  "joint stereo",
+		"eg.wav":  "mono",
+		"eg.opus": "stereo",
+	}
+	for _, path := range testPaths(t) {
+		layout, ok := want[filepath.Base(path)]
+		if !ok {
+			continue
+		}
+		properties, err := taglib.ReadProperties(path)
+		nilErr(t, err)
+		eq(t, properties.ChannelLayout, layout)
+	}
+}
+
+func TestChannelLayoutWAVExtensible(t *testing.T) {
+	t.Parallel()
+
+	// a WAVE_FORMAT_EXTENSIBLE header for 5.1 16 bit PCM, with a few silent frames
+	le := binary.LittleEndian
+	format := le.AppendUint16(nil, 0xfffe)
+	format = le.AppendUint16(format, 6)                                                            // channels
+	format = le.AppendUint32(format, 48000)                                                        // sample rate
+	format = le.AppendUint32(format, 48000*6*2)                                                    // bytes per second
+	format = le.AppendUint16(format, 6*2)                                                          // block align
+	format = le.AppendUint16(format, 16)                                                           // bits per sample
+	format = le.AppendUint16(format, 22)                                                           // extension size
+	format = le.AppendUint16(format, 16)                                                           // valid bits per sample
+	format = le.AppendUint32(format, 0x3f)                                                         // channel mask, FL FR FC LFE BL BR
+	format = append(format, "\x01\x00\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71"...) // PCM sub format
+
+	chunk := func(id string, data []byte) []byte {
+		return append(le.AppendUint32([]byte(id), uint32(len(data))), data...)
+	}
+	body := append([]byte("WAVE"), chunk("fmt ", format)...)
+	body = append(body, chunk("data", make([]byte, 6*2*480))...)
+	path := tmpf(t, append(le.AppendUint32([]byte("RIFF"), uint32(len(body))), body...), "surround.wav")
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.Channels, 6)
+	eq(t, properties.ChannelLayout, "5.1")
+}
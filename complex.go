@@ -0,0 +1,383 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Complex property keys used by TagLib.
+const (
+	// ComplexPicture holds embedded images, with "data", "mimeType", "description", and "pictureType" values, and for
+	// FLAC pictures "width", "height", "colorDepth", and "numColors"
+	ComplexPicture = "PICTURE"
+	// ComplexGeneralObject holds ID3v2 GEOB frames, with "data", "mimeType", "description", and "fileName" values
+	ComplexGeneralObject = "GENERALOBJECT"
+)
+
+// ReadComplexPropertyKeys reads the keys of the complex properties in a file at the given path which
+// [ReadComplexProperties] reads, [ComplexPicture] and [ComplexGeneralObject].
+func ReadComplexPropertyKeys(path string) (_ []string, err error) {
+	defer wrapErr(&err, "read complex property keys", path)
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("make path abs %w", err)
+	}
+
+	mod, err := newModuleRO(path)
+	if err != nil {
+		return nil, fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	return readComplexPropertyKeysModule(&mod, path)
+}
+
+// ReadComplexProperties reads the values of the complex property key in a file at the given path, in file order. Each
+// value is a map of names to values, such as []byte for "data", string for "mimeType", and int for "width". Only
+// [ComplexPicture] and [ComplexGeneralObject] are read, and other keys have no values.
+func ReadComplexProperties(path string, key string) (_ []map[string]any, err error) {
+	defer wrapErr(&err, "read complex properties", path)
+	return readComplexProperties(path, key)
+}
+
+func readComplexProperties(path string, key string) (_ []map[string]any, err error) {
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("make path abs %w", err)
+	}
+
+	mod, err := newModuleRO(path)
+	if err != nil {
+		return nil, fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	return readComplexPropertiesModule(&mod, path, key)
+}
+
+// WriteComplexProperties replaces the values of the complex property key in a file at path, with values of the Go
+// types read by [ReadComplexProperties]. No values remove the property. Only [ComplexPicture] and
+// [ComplexGeneralObject] can be written, and other keys fail with an error wrapping [errors.ErrUnsupported] before the
+// file is changed.
+func WriteComplexProperties(path string, key string, values []map[string]any) (err error) {
+	defer wrapErr(&err, "write complex properties", path)
+	return writeComplexProperties(path, key, values)
+}
+
+func writeComplexProperties(path string, key string, values []map[string]any) (err error) {
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("make path abs %w", err)
+	}
+
+	mod, err := newModule(path)
+	if err != nil {
+		return fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	return writeComplexPropertiesModule(&mod, path, key, values)
+}
+
+// readComplexPropertyKeysModule lists the complex properties that [readComplexPropertiesModule] reads.
+func readComplexPropertyKeysModule(mod *module, path string) ([]string, error) {
+	keys := []string{}
+	for _, key := range []string{ComplexPicture, ComplexGeneralObject} {
+		values, err := readComplexPropertiesModule(mod, path, key)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// readComplexPropertiesModule reads [ComplexPicture] through the image exports and [ComplexGeneralObject] from the
+// ID3v2 tag in Go. Other keys have no values.
+func readComplexPropertiesModule(mod *module, path string, key string) ([]map[string]any, error) {
+	switch strings.ToUpper(key) {
+	case ComplexPicture:
+		return readPicturesModule(mod, path)
+	case ComplexGeneralObject:
+		return readID3v2Objects(path)
+	}
+	return []map[string]any{}, nil
+}
+
+// writeComplexPropertiesModule is the counterpart of [readComplexPropertiesModule].
+func writeComplexPropertiesModule(mod *module, path string, key string, values []map[string]any) error {
+	switch strings.ToUpper(key) {
+	case ComplexPicture:
+		return writePicturesModule(mod, path, values)
+	case ComplexGeneralObject:
+		return writeID3v2Objects(path, values)
+	}
+	return fmt.Errorf("complex property %q: %w", key, errors.ErrUnsupported)
+}
+
+// readPicturesModule reads each image listed by the file properties with taglib_file_read_image. TagLib leaves the
+// FLAC attributes out of the listing, so for FLAC files they are read from the PICTURE blocks, which TagLib keeps in file
+// order.
+func readPicturesModule(mod *module, path string) ([]map[string]any, error) {
+	var raw wasmFileProperties
+	if err := mod.call("taglib_file_read_properties", &raw, wasmString(wasmPath(path))); err != nil {
+		return nil, fmt.Errorf("call: %w", err)
+	}
+	blocks, err := readFLACPictures(path)
+	if err != nil {
+		return nil, fmt.Errorf("read flac pictures: %w", err)
+	}
+
+	values := make([]map[string]any, 0, len(raw.imageDescs))
+	for i, row := range raw.imageDescs {
+		parts := strings.SplitN(row, "\t", 4)
+		if len(parts) < 3 {
+			continue
+		}
+		var data wasmBytes
+		if err := mod.call("taglib_file_read_image", &data, wasmString(wasmPath(path)), wasmInt(i)); err != nil {
+			return nil, fmt.Errorf("call: %w", err)
+		}
+		v := map[string]any{
+			"data":        []byte(data),
+			"pictureType": parts[0],
+			"description": parts[1],
+			"mimeType":    parts[2],
+		}
+		if len(blocks) == len(raw.imageDescs) {
+			if offset, ok := flacPictureAttrs(blocks[i]); ok {
+				attrs := blocks[i][offset:]
+				v["width"] = int(binary.BigEndian.Uint32(attrs[0:]))
+				v["height"] = int(binary.BigEndian.Uint32(attrs[4:]))
+				v["colorDepth"] = int(binary.BigEndian.Uint32(attrs[8:]))
+				v["numColors"] = int(binary.BigEndian.Uint32(attrs[12:]))
+			}
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// writePicturesModule replaces the images of a file one index at a time with taglib_file_write_image, then removes
+// the ones past the end. The attributes TagLib doesn't take there are patched into the PICTURE blocks of FLAC files.
+func writePicturesModule(mod *module, path string, values []map[string]any) error {
+	for i, v := range values {
+		if data, _ := v["data"].([]byte); len(data) == 0 {
+			return fmt.Errorf("picture %d has no data", i)
+		}
+	}
+	var raw wasmFileProperties
+	if err := mod.call("taglib_file_read_properties", &raw, wasmString(wasmPath(path))); err != nil {
+		return fmt.Errorf("call: %w", err)
+	}
+
+	writeImage := func(index int, data []byte, typ, description, mimeType string) error {
+		var out wasmBool
+		if err := mod.call("taglib_file_write_image", &out, wasmString(wasmPath(path)), wasmBytes(data), wasmInt(len(data)), wasmInt(index), wasmString(typ), wasmString(description), wasmString(mimeType)); err != nil {
+			return fmt.Errorf("call: %w", err)
+		}
+		if !out {
+			return ErrSavingFile
+		}
+		return nil
+	}
+	var attrs bool
+	for i, v := range values {
+		data, _ := v["data"].([]byte)
+		typ, _ := v["pictureType"].(string)
+		description, _ := v["description"].(string)
+		mimeType, _ := v["mimeType"].(string)
+		if err := writeImage(i, data, typ, description, mimeType); err != nil {
+			return err
+		}
+		_, ok := v["width"]
+		attrs = attrs || ok
+	}
+	for range len(raw.imageDescs) - len(values) {
+		if err := writeImage(len(values), nil, "", "", ""); err != nil {
+			return err
+		}
+	}
+
+	if blocks, err := readFLACPictures(path); err != nil || !attrs || len(blocks) != len(values) {
+		return err
+	}
+	return editFLACBlocks(path, func(blocks []flacBlockData) []flacBlockData {
+		i := 0
+		for _, block := range blocks {
+			if block.typ != flacBlockPicture {
+				continue
+			}
+			if offset, ok := flacPictureAttrs(block.data); ok {
+				for j, name := range []string{"width", "height", "colorDepth", "numColors"} {
+					n, _ := values[i][name].(int)
+					binary.BigEndian.PutUint32(block.data[offset+4*j:], uint32(n))
+				}
+			}
+			i++
+		}
+		return blocks
+	})
+}
+
+// readID3v2Objects reads the GEOB frames of the ID3v2 tag of a file at path as [ComplexGeneralObject] values.
+func readID3v2Objects(path string) ([]map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	tag, _, err := readID3v2Tag(f)
+	if err != nil || tag == nil {
+		return []map[string]any{}, err
+	}
+
+	values := []map[string]any{}
+	for _, frame := range id3v2Frames(tag) {
+		if id3v2FrameID(tag[3], frame.ID) != "GEOB" {
+			continue
+		}
+		// the encoding, the Latin-1 MIME type, then the terminated file name and description, and the data
+		data, ok := id3v2FrameContent(tag[3], byte(frame.Flags), frame.Data)
+		if !ok || len(data) < 1 {
+			continue
+		}
+		mimeType, rest := splitID3v2Text(0, data[1:])
+		fileName, rest := splitID3v2Text(data[0], rest)
+		description, rest := splitID3v2Text(data[0], rest)
+		values = append(values, map[string]any{
+			"data":        bytes.Clone(rest),
+			"mimeType":    mimeType,
+			"fileName":    fileName,
+			"description": description,
+		})
+	}
+	return values, nil
+}
+
+// writeID3v2Objects replaces the GEOB frames of the ID3v2 tag of a file at path. Files without ID3v2 tags return an
+// error wrapping [errors.ErrUnsupported].
+func writeID3v2Objects(path string, values []map[string]any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	format, err := nativeFormatOf(f, path)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if format != nativeID3v2 {
+		return fmt.Errorf("general objects outside id3v2 tags: %w", errors.ErrUnsupported)
+	}
+
+	return editID3v2Tag(path, func(major byte, frames []ID3v2Frame) ([]ID3v2Frame, error) {
+		frames = slices.DeleteFunc(frames, func(f ID3v2Frame) bool { return f.ID == "GEOB" })
+		for _, v := range values {
+			data, _ := v["data"].([]byte)
+			mimeType, _ := v["mimeType"].(string)
+			fileName, _ := v["fileName"].(string)
+			description, _ := v["description"].(string)
+			encoding := id3v2Encoding(major, fileName, description)
+			b := appendID3v2Text([]byte{encoding}, 0, mimeType, true)
+			b = appendID3v2Text(b, encoding, fileName, true)
+			b = appendID3v2Text(b, encoding, description, true)
+			frames = append(frames, ID3v2Frame{ID: "GEOB", Data: append(b, data...)})
+		}
+		return frames, nil
+	})
+}
+
+// Picture is an embedded image with its attributes, read and written through the [ComplexPicture] complex property.
+type Picture struct {
+	// Data is the image data
+	Data []byte
+	// Type is the picture type (e.g., [PictureFrontCover], [PictureBackCover])
+	Type PictureType
+	// Description is a textual description of the image
+	Description string
+	// MIMEType is the MIME type of the image (e.g., "image/jpeg")
+	MIMEType string
+	// Width, Height, ColorDepth, and NumColors describe the image in FLAC and Ogg pictures, and are 0 if unknown or
+	// not stored by the format
+	Width, Height, ColorDepth, NumColors int
+}
+
+// ReadPictures reads all embedded images and their attributes from a file at the given path.
+func ReadPictures(path string) (_ []Picture, err error) {
+	defer wrapErr(&err, "read pictures", path)
+	return readPictures(path)
+}
+
+func readPictures(path string) ([]Picture, error) {
+	values, err := readComplexProperties(path, ComplexPicture)
+	if err != nil {
+		return nil, err
+	}
+	pictures := make([]Picture, 0, len(values))
+	for _, v := range values {
+		data, _ := v["data"].([]byte)
+		typ, _ := v["pictureType"].(string)
+		description, _ := v["description"].(string)
+		mimeType, _ := v["mimeType"].(string)
+		width, _ := v["width"].(int)
+		height, _ := v["height"].(int)
+		colorDepth, _ := v["colorDepth"].(int)
+		numColors, _ := v["numColors"].(int)
+		pictures = append(pictures, Picture{
+			Data:        data,
+			Type:        PictureType(typ),
+			Description: description,
+			MIMEType:    mimeType,
+			Width:       width,
+			Height:      height,
+			ColorDepth:  colorDepth,
+			NumColors:   numColors,
+		})
+	}
+	return pictures, nil
+}
+
+// WritePictures replaces all embedded images in a file at path with pictures, in order. Unlike [WriteImageOptions],
+// all attributes are written on formats which store them. An empty Type is written as [PictureFrontCover], and an empty
+// MIMEType is detected from the data. No pictures remove all images.
+func WritePictures(path string, pictures []Picture) (err error) {
+	defer wrapErr(&err, "write pictures", path)
+	values := make([]map[string]any, 0, len(pictures))
+	for _, p := range pictures {
+		if err := checkPictureSize(currentLimits(), int64(len(p.Data))); err != nil {
+			return err
+		}
+		typ := p.Type
+		if typ == "" {
+			typ = PictureFrontCover
+		}
+		mimeType := p.MIMEType
+		if mimeType == "" {
+			mimeType = detectImageMIME(p.Data)
+		}
+		v := map[string]any{
+			"data":        p.Data,
+			"pictureType": string(typ),
+			"description": p.Description,
+			"mimeType":    mimeType,
+		}
+		if p.Width != 0 || p.Height != 0 || p.ColorDepth != 0 || p.NumColors != 0 {
+			v["width"] = p.Width
+			v["height"] = p.Height
+			v["colorDepth"] = p.ColorDepth
+			v["numColors"] = p.NumColors
+		}
+		values = append(values, v)
+	}
+	return writeComplexProperties(path, ComplexPicture, values)
+}
@@ -0,0 +1,119 @@
+package taglib_test
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestReadPictures(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	pictures, err := taglib.ReadPictures(path)
+	nilErr(t, err)
+	eq(t, len(pictures), 2)
+	eq(t, pictures[0].Type, taglib.PictureFrontCover)
+	eq(t, pictures[0].Description, "The first image")
+	eq(t, pictures[0].MIMEType, "image/png")
+	eq(t, pictures[1].Type, taglib.PictureLeadArtist)
+	eq(t, pictures[1].Description, "The second image")
+	eq(t, pictures[1].MIMEType, "image/jpeg")
+
+	for i, p := range pictures {
+		img, err := taglib.ReadImageOptions(path, i)
+		nilErr(t, err)
+		eq(t, len(p.Data) > 0, true)
+		eq(t, bytes.Equal(p.Data, img), true)
+	}
+}
+
+func TestWritePictures(t *testing.T) {
+	t.Parallel()
+
+	t.Run("flac", func(t *testing.T) {
+		t.Parallel()
+
+		path := tmpf(t, egFLAC, "eg.flac")
+		nilErr(t, taglib.WritePictures(path, []taglib.Picture{
+			{Data: coverJPG, Type: taglib.PictureBackCover, Description: "back", Width: 10, Height: 20, ColorDepth: 24},
+		}))
+
+		pictures, err := taglib.ReadPictures(path)
+		nilErr(t, err)
+		eq(t, len(pictures), 1)
+		eq(t, bytes.Equal(pictures[0].Data, coverJPG), true)
+		eq(t, pictures[0].Type, taglib.PictureBackCover)
+		eq(t, pictures[0].Description, "back")
+		eq(t, pictures[0].MIMEType, "image/png") // cover.jpg holds a PNG
+		eq(t, pictures[0].Width, 10)
+		eq(t, pictures[0].Height, 20)
+		eq(t, pictures[0].ColorDepth, 24)
+		eq(t, pictures[0].NumColors, 0)
+
+		nilErr(t, taglib.WritePictures(path, nil))
+		pictures, err = taglib.ReadPictures(path)
+		nilErr(t, err)
+		eq(t, len(pictures), 0)
+	})
+
+	t.Run("mp3", func(t *testing.T) {
+		t.Parallel()
+
+		path := tmpf(t, egMP3, "eg.mp3")
+		nilErr(t, taglib.WritePictures(path, []taglib.Picture{
+			{Data: coverJPG, Description: "front"},
+			{Data: coverJPG[:len(coverJPG)/2], Type: taglib.PictureArtist, Description: "half", MIMEType: "image/x-test"},
+		}))
+
+		pictures, err := taglib.ReadPictures(path)
+		nilErr(t, err)
+		eq(t, len(pictures), 2)
+		eq(t, bytes.Equal(pictures[0].Data, coverJPG), true)
+		eq(t, pictures[0].Type, taglib.PictureFrontCover)
+		eq(t, pictures[0].Description, "front")
+		eq(t, pictures[0].MIMEType, "image/png") // cover.jpg holds a PNG
+		eq(t, bytes.Equal(pictures[1].Data, coverJPG[:len(coverJPG)/2]), true)
+		eq(t, pictures[1].Type, taglib.PictureArtist)
+		eq(t, pictures[1].Description, "half")
+		eq(t, pictures[1].MIMEType, "image/x-test")
+
+		tags, err := taglib.ReadTags(path)
+		nilErr(t, err)
+		eq(t, len(tags) > 0, true) // the other frames survive
+	})
+}
+
+func TestComplexGeneralObject(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	object := map[string]any{
+		"data":        []byte("\x00\x01binary\xff"),
+		"mimeType":    "application/octet-stream",
+		"fileName":    "état.bin",
+		"description": "an object",
+	}
+	nilErr(t, taglib.WriteComplexProperties(path, taglib.ComplexGeneralObject, []map[string]any{object}))
+	nilErr(t, taglib.WriteImage(path, coverJPG))
+
+	keys, err := taglib.ReadComplexPropertyKeys(path)
+	nilErr(t, err)
+	slices.Sort(keys)
+	eq(t, slices.Equal(keys, []string{taglib.ComplexGeneralObject, taglib.ComplexPicture}), true)
+
+	values, err := taglib.ReadComplexProperties(path, taglib.ComplexGeneralObject)
+	nilErr(t, err)
+	eq(t, len(values), 1)
+	eq(t, bytes.Equal(values[0]["data"].([]byte), object["data"].([]byte)), true)
+	eq(t, values[0]["mimeType"], object["mimeType"])
+	eq(t, values[0]["fileName"], object["fileName"])
+	eq(t, values[0]["description"], object["description"])
+
+	nilErr(t, taglib.WriteComplexProperties(path, taglib.ComplexGeneralObject, nil))
+	values, err = taglib.ReadComplexProperties(path, taglib.ComplexGeneralObject)
+	nilErr(t, err)
+	eq(t, len(values), 0)
+}
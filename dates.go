@@ -0,0 +1,89 @@
+package taglib
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDate is returned by [ParseDate] for values which aren't dates.
+var ErrInvalidDate = fmt.Errorf("invalid date")
+
+// DatePrecision is how much of a date a tag value gives, from the year alone to the second.
+type DatePrecision uint8
+
+const (
+	DateYear DatePrecision = iota + 1
+	DateMonth
+	DateDay
+	DateHour
+	DateMinute
+	DateSecond
+)
+
+// dateLayouts are the layouts of each precision, the ISO 8601 forms of ID3v2.4 timestamps first.
+var dateLayouts = []struct {
+	layout    string
+	precision DatePrecision
+}{
+	{"2006", DateYear},
+	{"2006-01", DateMonth},
+	{"2006-01-02", DateDay},
+	{"2006-01-02T15", DateHour},
+	{"2006-01-02T15:04", DateMinute},
+	{"2006-01-02T15:04:05", DateSecond},
+	{"2006-01-02T15:04:05Z07:00", DateSecond},
+	{"2006-01-02 15:04:05", DateSecond},
+	{"2006-01-02 15:04", DateMinute},
+	{"2006/01/02", DateDay},
+	{"2006/01", DateMonth},
+	{"2006.01.02", DateDay},
+	{"20060102", DateDay},
+}
+
+// ParseDate parses the value of a date key such as [Date], [OriginalDate], or [ReleaseDate], from a year alone
+// ("2024"), a month ("2024-05"), a day ("2024-05-01"), or an ID3v2.4 timestamp ("2024-05-01T12:30:00"), along with the
+// timestamps with a zone which iTunes writes to MP4 files. It returns the date in UTC, or its own zone if it has one,
+// and how much of it the value gives. Other values fail with [ErrInvalidDate].
+func ParseDate(value string) (time.Time, DatePrecision, error) {
+	value = strings.TrimSpace(value)
+	for _, l := range dateLayouts {
+		if len(value) != len(l.layout) && !strings.HasSuffix(l.layout, "Z07:00") {
+			continue
+		}
+		if t, err := time.Parse(l.layout, value); err == nil {
+			return t, l.precision, nil
+		}
+	}
+	return time.Time{}, 0, fmt.Errorf("parse %q: %w", value, ErrInvalidDate)
+}
+
+// FormatDate formats t to the given precision in the ISO 8601 form of [ParseDate], such as "2024-05" for [DateMonth].
+// This is the form TagLib writes to each format: as is to Vorbis comments, APE, and MP4 tags, to the TDRC frame of
+// ID3v2.4 tags, and split into the TYER, TDAT, and TIME frames of ID3v2.3 tags. The WM/Year of ASF files and the
+// ICRD field of RIFF INFO chunks are commonly read as a year alone, so pass [DateYear] for those.
+func FormatDate(t time.Time, precision DatePrecision) string {
+	switch precision {
+	case DateYear:
+		return t.Format("2006")
+	case DateMonth:
+		return t.Format("2006-01")
+	case DateHour:
+		return t.Format("2006-01-02T15")
+	case DateMinute:
+		return t.Format("2006-01-02T15:04")
+	case DateSecond:
+		return t.Format("2006-01-02T15:04:05")
+	}
+	return t.Format("2006-01-02")
+}
+
+// GetDate parses the first value of key in tags with [ParseDate]. It reports false if the key is missing or its value
+// isn't a date.
+func GetDate[K ~string](tags map[K][]string, key K) (time.Time, DatePrecision, bool) {
+	t, precision, err := ParseDate(First(tags, key))
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return t, precision, true
+}
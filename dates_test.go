@@ -0,0 +1,57 @@
+package taglib_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestParseDate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		value     string
+		want      time.Time
+		precision taglib.DatePrecision
+	}{
+		{"2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), taglib.DateYear},
+		{"2024-05", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), taglib.DateMonth},
+		{"2024-05-17", time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC), taglib.DateDay},
+		{"2024-05-17T12:30", time.Date(2024, 5, 17, 12, 30, 0, 0, time.UTC), taglib.DateMinute},
+		{"2024-05-17T12:30:15", time.Date(2024, 5, 17, 12, 30, 15, 0, time.UTC), taglib.DateSecond},
+		{"2024-05-17T07:00:00Z", time.Date(2024, 5, 17, 7, 0, 0, 0, time.UTC), taglib.DateSecond},
+		{" 2024/05/17 ", time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC), taglib.DateDay},
+	} {
+		got, precision, err := taglib.ParseDate(tc.value)
+		nilErr(t, err)
+		eq(t, got.Equal(tc.want), true)
+		eq(t, precision, tc.precision)
+
+		// formatting gives back the canonical form
+		again, _, err := taglib.ParseDate(taglib.FormatDate(got, precision))
+		nilErr(t, err)
+		eq(t, again.Equal(got), true)
+	}
+
+	_, _, err := taglib.ParseDate("last tuesday")
+	eq(t, errors.Is(err, taglib.ErrInvalidDate), true)
+
+	day := time.Date(2024, 5, 17, 12, 30, 15, 0, time.UTC)
+	eq(t, taglib.FormatDate(day, taglib.DateYear), "2024")
+	eq(t, taglib.FormatDate(day, taglib.DateMonth), "2024-05")
+	eq(t, taglib.FormatDate(day, taglib.DateDay), "2024-05-17")
+	eq(t, taglib.FormatDate(day, taglib.DateSecond), "2024-05-17T12:30:15")
+
+	// written and read back through TagLib
+	path := tmpf(t, egMP3, "eg.mp3")
+	err = taglib.WriteTags(path, map[string][]string{taglib.Date: {taglib.FormatDate(day, taglib.DateDay)}}, 0)
+	nilErr(t, err)
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	got, precision, ok := taglib.GetDate(tags, taglib.Date)
+	eq(t, ok, true)
+	eq(t, precision, taglib.DateDay)
+	eq(t, got.Equal(time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)), true)
+}
@@ -0,0 +1,255 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// EncoderInfo identifies the encoder of a file, from whichever of these sources the format has.
+type EncoderInfo struct {
+	// Encoder is the encoder version from the LAME tag of MP3 files, such as "LAME3.100", or the vendor string of the
+	// Vorbis comment of FLAC and Ogg files
+	Encoder string
+	// Preset is the LAME preset, such as "V0", "extreme", or an ABR bitrate like "192", empty if unset
+	Preset string
+	// Settings is the software and settings used for encoding, from the ID3v2 TSSE frame
+	Settings string
+	// EncodedBy is the person or organisation who encoded the file, from the ID3v2 TENC frame
+	EncodedBy string
+}
+
+// readEncoderInfo reads the encoder identification TagLib doesn't expose. Unrecognised, malformed, or truncated data is
+// left out.
+func readEncoderInfo(f fileReader) (EncoderInfo, error) {
+	var info EncoderInfo
+
+	stat, err := f.Stat()
+	if err != nil {
+		return EncoderInfo{}, err
+	}
+	tagSize, err := id3v2TagSize(f)
+	if err != nil {
+		return EncoderInfo{}, err
+	}
+	if tagSize > 0 {
+		// the size is the header's claim, so only what the file holds is read
+		tag, err := io.ReadAll(io.NewSectionReader(f, 0, min(tagSize, stat.Size())))
+		if err != nil {
+			return EncoderInfo{}, err
+		}
+		frames := id3v2TextFrames(tag)
+		info.Settings = frames["TSSE"]
+		info.EncodedBy = frames["TENC"]
+	}
+
+	var magic [4]byte
+	if _, err := f.ReadAt(magic[:], tagSize); err != nil {
+		if errors.Is(err, io.EOF) {
+			return info, nil
+		}
+		return EncoderInfo{}, err
+	}
+
+	switch {
+	case string(magic[:]) == "fLaC":
+		info.Encoder = flacVendor(f)
+	case string(magic[:]) == "OggS":
+		info.Encoder = oggVendor(f)
+	case isMPEGHeader(magic[:]):
+		info.Encoder, info.Preset = lameTag(f, tagSize)
+	}
+	return info, nil
+}
+
+func flacVendor(r io.ReaderAt) string {
+	blocks, err := readFLACBlocks(r)
+	if err != nil {
+		return ""
+	}
+	for _, block := range blocks {
+		if block.typ != flacBlockComment {
+			continue
+		}
+		data, err := block.read(r)
+		if err != nil {
+			return ""
+		}
+		return commentVendor(data)
+	}
+	return ""
+}
+
+// oggVendor reads the vendor string from the comment header, the second packet of the first logical stream.
+func oggVendor(r io.ReaderAt) string {
+	packets := oggHeaderPackets(r, 2)
+	if len(packets) < 2 {
+		return ""
+	}
+	id, comment := packets[0], packets[1]
+	switch {
+	case bytes.HasPrefix(id, []byte("\x01vorbis")) && bytes.HasPrefix(comment, []byte("\x03vorbis")):
+		return commentVendor(comment[7:])
+	case bytes.HasPrefix(id, []byte("OpusHead")) && bytes.HasPrefix(comment, []byte("OpusTags")):
+		return commentVendor(comment[8:])
+	case bytes.HasPrefix(id, []byte("Speex   ")):
+		return commentVendor(comment)
+	case bytes.HasPrefix(id, []byte("\x7fFLAC")) && len(comment) > 4 && comment[0]&0x7f == flacBlockComment:
+		return commentVendor(comment[4:])
+	}
+	return ""
+}
+
+// oggMaxHeaderPacket bounds how much of a header packet is read, enough for a vendor string without reading the
+// embedded pictures which can follow it.
+const oggMaxHeaderPacket = 1 << 16
+
+// oggHeaderPackets reads the first n packets of the first logical stream, truncating long packets.
+func oggHeaderPackets(r io.ReaderAt, n int) [][]byte {
+	var packets [][]byte
+	var packet []byte
+	var serial uint32
+	for offset := int64(0); len(packets) < n; {
+		var header [27]byte
+		if _, err := r.ReadAt(header[:], offset); err != nil || string(header[:4]) != "OggS" {
+			return packets
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:])
+		if offset == 0 {
+			serial = pageSerial
+		}
+		segments := make([]byte, header[26])
+		if _, err := r.ReadAt(segments, offset+27); err != nil {
+			return packets
+		}
+		offset += 27 + int64(len(segments))
+
+		for _, size := range segments {
+			if pageSerial == serial && len(packets) < n && len(packet) < oggMaxHeaderPacket {
+				data := make([]byte, size)
+				if _, err := r.ReadAt(data, offset); err != nil {
+					return packets
+				}
+				packet = append(packet, data...)
+			}
+			offset += int64(size)
+			if size < 255 && pageSerial == serial {
+				packets = append(packets, packet)
+				packet = nil
+			}
+		}
+	}
+	return packets
+}
+
+// commentVendor reads the vendor string at the start of a Vorbis comment.
+func commentVendor(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if uint64(size) > uint64(len(data)-4) {
+		return ""
+	}
+	return string(data[4 : 4+size])
+}
+
+func isMPEGHeader(b []byte) bool {
+	return b[0] == 0xff && b[1]&0xe0 == 0xe0 && // sync
+		b[1]&0x18 != 0x08 && // version
+		b[1]&0x06 != 0 && // layer
+		b[2]&0xf0 != 0xf0 && // bitrate
+		b[2]&0x0c != 0x0c // sample rate
+}
+
+// lameTag reads the encoder and preset from the LAME tag, which follows the Xing or Info header in the first frame.
+func lameTag(r io.ReaderAt, offset int64) (string, string) {
+	frame := make([]byte, 4+2+32+120+36)
+	n, _ := r.ReadAt(frame, offset)
+	frame = frame[:n]
+	if len(frame) < 4 || !isMPEGHeader(frame) {
+		return "", ""
+	}
+
+	mpeg1 := frame[1]&0x18 == 0x18
+	mono := frame[3]&0xc0 == 0xc0
+	pos := 4
+	if frame[1]&0x01 == 0 {
+		pos += 2 // CRC
+	}
+	switch { // side information
+	case mpeg1 && !mono:
+		pos += 32
+	case mpeg1 || !mono:
+		pos += 17
+	default:
+		pos += 9
+	}
+
+	if len(frame) < pos+8 {
+		return "", ""
+	}
+	if id := string(frame[pos : pos+4]); id != "Xing" && id != "Info" {
+		return "", ""
+	}
+	flags := binary.BigEndian.Uint32(frame[pos+4:])
+	pos += 8
+	for _, field := range []struct {
+		flag uint32
+		size int
+	}{{0x1, 4}, {0x2, 4}, {0x4, 100}, {0x8, 4}} {
+		if flags&field.flag != 0 {
+			pos += field.size
+		}
+	}
+
+	if len(frame) < pos+9 {
+		return "", ""
+	}
+	encoder := string(bytes.TrimRight(frame[pos:pos+9], "\x00 "))
+	for _, c := range encoder {
+		if c < 0x20 || c > 0x7e {
+			return "", ""
+		}
+	}
+
+	// the preset is only written by LAME itself, in the low 11 bits of its 2 bytes
+	const presetOffset = 26
+	if len(frame) < pos+presetOffset+2 || !bytes.HasPrefix(frame[pos:], []byte("LAME")) {
+		return encoder, ""
+	}
+	preset := int(binary.BigEndian.Uint16(frame[pos+presetOffset:]) & 0x07ff)
+	return encoder, lamePreset(preset)
+}
+
+func lamePreset(preset int) string {
+	switch {
+	case preset == 0:
+		return ""
+	case preset >= 8 && preset <= 320:
+		return strconv.Itoa(preset) // ABR
+	case preset >= 410 && preset <= 500 && preset%10 == 0:
+		return "V" + strconv.Itoa((500-preset)/10)
+	}
+	switch preset {
+	case 1000:
+		return "r3mix"
+	case 1001:
+		return "standard"
+	case 1002:
+		return "extreme"
+	case 1003:
+		return "insane"
+	case 1004:
+		return "fast standard"
+	case 1005:
+		return "fast extreme"
+	case 1006:
+		return "medium"
+	case 1007:
+		return "fast medium"
+	}
+	return ""
+}
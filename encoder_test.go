@@ -0,0 +1,67 @@
+package taglib_test
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestEncoderInfo(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		data    []byte
+		encoder string
+	}{
+		{"eg.mp3", egMP3, "Lavc61.19"},
+		{"eg.flac", egFLAC, "Lavf61.1.100"},
+		{"eg.ogg", egOgg, "Lavf61.7.100"},
+		{"eg.opus", egOpus, "go-taglib"},
+		{"eg.oga", egOggFLAC, "go-taglib"},
+		{"eg.spx", egSpeex, "go-taglib"},
+		{"eg.m4a", egM4a, ""},
+	} {
+		properties, err := taglib.ReadProperties(tmpf(t, tc.data, tc.name))
+		nilErr(t, err)
+		eq(t, properties.EncoderInfo.Encoder, tc.encoder)
+	}
+
+	// the LAME tag of the Info frame, with the preset for -V0
+	data := slices.Clone(egMP3)
+	i := bytes.Index(data, []byte("Lavc61.19"))
+	copy(data[i:], "LAME3.100")
+	data[i+26], data[i+27] = 0x01, 0xf4
+
+	path := tmpf(t, data, "eg.mp3")
+	err := taglib.WriteTags(path, map[string][]string{
+		taglib.Encoding:  {"LAME 3.100 -V0"},
+		taglib.EncodedBy: {"Someone"},
+	}, 0)
+	nilErr(t, err)
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.EncoderInfo, taglib.EncoderInfo{
+		Encoder:   "LAME3.100",
+		Preset:    "V0",
+		Settings:  "LAME 3.100 -V0",
+		EncodedBy: "Someone",
+	})
+}
+
+func TestEncoderInfoTruncated(t *testing.T) {
+	t.Parallel()
+
+	// a tag cut off by the end of the file, and one claiming more than the file holds, have no encoder info
+	tagSize := 10 + (int(egMP3[6])<<21 | int(egMP3[7])<<14 | int(egMP3[8])<<7 | int(egMP3[9]))
+	huge := slices.Clone(egMP3)
+	copy(huge[6:10], []byte{0x7f, 0x7f, 0x7f, 0x7f})
+	for _, data := range [][]byte{egMP3[:tagSize/2], huge} {
+		properties, err := taglib.ReadProperties(tmpf(t, data, "eg.mp3"))
+		nilErr(t, err)
+		eq(t, properties.EncoderInfo, taglib.EncoderInfo{})
+	}
+}
@@ -0,0 +1,434 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// FLACCueSheet contains the fields of a FLAC CUESHEET metadata block.
+type FLACCueSheet struct {
+	// MediaCatalogNumber is the UPC/EAN of the disc, empty if unset
+	MediaCatalogNumber string
+	// LeadIn is the number of lead-in samples, only meaningful for CD-DA
+	LeadIn uint64
+	// CD reports whether the cue sheet corresponds to a Compact Disc
+	CD bool
+	// Tracks contains every track in the cue sheet, including the lead-out track last
+	Tracks []FLACCueSheetTrack
+}
+
+// FLACCueSheetTrack is a track in a [FLACCueSheet].
+type FLACCueSheetTrack struct {
+	// Offset is the start of the track in samples, relative to the start of the audio
+	Offset uint64
+	// Number is the track number. The lead-out track is 170 for CD-DA and 255 otherwise
+	Number uint8
+	// ISRC is the International Standard Recording Code of the track, empty if unset
+	ISRC string
+	// Audio is false for data tracks
+	Audio bool
+	// PreEmphasis reports whether the track was mastered with pre-emphasis
+	PreEmphasis bool
+	// Indexes contains the index points of the track. The lead-out track has none
+	Indexes []FLACCueSheetIndex
+}
+
+// FLACCueSheetIndex is an index point of a [FLACCueSheetTrack].
+type FLACCueSheetIndex struct {
+	// Offset is the index point in samples, relative to the start of the track
+	Offset uint64
+	// Number is the index point number, where 1 marks the start of the track and 0 the pregap
+	Number uint8
+}
+
+// ReadFLACCueSheet reads the CUESHEET metadata block from a FLAC file at the given path. It returns nil if the file
+// has no cue sheet.
+func ReadFLACCueSheet(path string) (_ *FLACCueSheet, err error) {
+	defer wrapErr(&err, "read flac cue sheet", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	blocks, err := readFLACBlocks(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		if block.typ != flacBlockCueSheet {
+			continue
+		}
+		data, err := block.read(f)
+		if err != nil {
+			return nil, fmt.Errorf("read block: %w", err)
+		}
+		return parseFLACCueSheet(data)
+	}
+	return nil, nil
+}
+
+const (
+	flacCueSheetHeaderSize = 396
+	flacCueSheetTrackSize  = 36
+	flacCueSheetIndexSize  = 12
+)
+
+func parseFLACCueSheet(data []byte) (*FLACCueSheet, error) {
+	if len(data) < flacCueSheetHeaderSize {
+		return nil, fmt.Errorf("cue sheet too short: %w", ErrInvalidFile)
+	}
+
+	var cueSheet FLACCueSheet
+	cueSheet.MediaCatalogNumber = chunkString(data[0:128])
+	cueSheet.LeadIn = binary.BigEndian.Uint64(data[128:136])
+	cueSheet.CD = data[136]&0x80 != 0
+
+	numTracks := int(data[395])
+	data = data[flacCueSheetHeaderSize:]
+
+	for range numTracks {
+		if len(data) < flacCueSheetTrackSize {
+			return nil, fmt.Errorf("cue sheet track too short: %w", ErrInvalidFile)
+		}
+		track := FLACCueSheetTrack{
+			Offset:      binary.BigEndian.Uint64(data[0:8]),
+			Number:      data[8],
+			ISRC:        chunkString(data[9:21]),
+			Audio:       data[21]&0x80 == 0,
+			PreEmphasis: data[21]&0x40 != 0,
+		}
+
+		numIndexes := int(data[35])
+		data = data[flacCueSheetTrackSize:]
+
+		for range numIndexes {
+			if len(data) < flacCueSheetIndexSize {
+				return nil, fmt.Errorf("cue sheet index too short: %w", ErrInvalidFile)
+			}
+			track.Indexes = append(track.Indexes, FLACCueSheetIndex{
+				Offset: binary.BigEndian.Uint64(data[0:8]),
+				Number: data[8],
+			})
+			data = data[flacCueSheetIndexSize:]
+		}
+
+		cueSheet.Tracks = append(cueSheet.Tracks, track)
+	}
+
+	return &cueSheet, nil
+}
+
+// FLACSeekPoint is an entry of a FLAC SEEKTABLE metadata block.
+type FLACSeekPoint struct {
+	// SampleNumber is the first sample of the target frame
+	SampleNumber uint64
+	// Offset is the byte offset of the target frame, relative to the first frame
+	Offset uint64
+	// Samples is the number of samples in the target frame
+	Samples uint16
+}
+
+// flacSeekPointPlaceholder is the sample number of placeholder seek points, which reserve space in the table.
+const flacSeekPointPlaceholder = 1<<64 - 1
+
+// IsPlaceholder reports whether the seek point is a placeholder which doesn't point to a frame.
+func (p FLACSeekPoint) IsPlaceholder() bool {
+	return p.SampleNumber == flacSeekPointPlaceholder
+}
+
+// ReadFLACSeekTable reads the entries of the SEEKTABLE metadata block from a FLAC file at the given path. It returns
+// nil if the file has no seek table, and an empty slice if the table has no entries.
+func ReadFLACSeekTable(path string) (_ []FLACSeekPoint, err error) {
+	defer wrapErr(&err, "read flac seek table", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	blocks, err := readFLACBlocks(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		if block.typ != flacBlockSeekTable {
+			continue
+		}
+		data, err := block.read(f)
+		if err != nil {
+			return nil, fmt.Errorf("read block: %w", err)
+		}
+		return parseFLACSeekTable(data), nil
+	}
+	return nil, nil
+}
+
+const flacSeekPointSize = 18
+
+func parseFLACSeekTable(data []byte) []FLACSeekPoint {
+	points := make([]FLACSeekPoint, 0, len(data)/flacSeekPointSize)
+	for ; len(data) >= flacSeekPointSize; data = data[flacSeekPointSize:] {
+		points = append(points, FLACSeekPoint{
+			SampleNumber: binary.BigEndian.Uint64(data[0:8]),
+			Offset:       binary.BigEndian.Uint64(data[8:16]),
+			Samples:      binary.BigEndian.Uint16(data[16:18]),
+		})
+	}
+	return points
+}
+
+// FLACApplication is a FLAC APPLICATION metadata block, which holds data for third party applications.
+type FLACApplication struct {
+	// ID is the registered 4 byte application ID, such as "CUES" or "riff"
+	ID string
+	// Data is the raw payload following the ID
+	Data []byte
+}
+
+// ReadFLACApplications reads all APPLICATION metadata blocks from a FLAC file at the given path, in file order.
+func ReadFLACApplications(path string) (_ []FLACApplication, err error) {
+	defer wrapErr(&err, "read flac applications", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	blocks, err := readFLACBlocks(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var applications []FLACApplication
+	for _, block := range blocks {
+		if block.typ != flacBlockApplication || block.length < 4 {
+			continue
+		}
+		data, err := block.read(f)
+		if err != nil {
+			return nil, fmt.Errorf("read block: %w", err)
+		}
+		applications = append(applications, FLACApplication{
+			ID:   string(data[:4]),
+			Data: data[4:],
+		})
+	}
+	return applications, nil
+}
+
+// WriteFLACApplication replaces all APPLICATION metadata blocks with the given ID in a FLAC file at path with a single
+// block holding data. A nil data removes the blocks.
+func WriteFLACApplication(path string, id string, data []byte) (err error) {
+	defer wrapErr(&err, "write flac application", path)
+	if len(id) != 4 {
+		return fmt.Errorf("application id %q is not 4 bytes", id)
+	}
+
+	return editFLACBlocks(path, func(blocks []flacBlockData) []flacBlockData {
+		blocks = slices.DeleteFunc(blocks, func(b flacBlockData) bool {
+			return b.typ == flacBlockApplication && bytes.HasPrefix(b.data, []byte(id))
+		})
+		if data != nil {
+			blocks = append(blocks, flacBlockData{typ: flacBlockApplication, data: append([]byte(id), data...)})
+		}
+		return blocks
+	})
+}
+
+// FLAC metadata block types
+const (
+	flacBlockStreamInfo  byte = 0
+	flacBlockPadding     byte = 1
+	flacBlockApplication byte = 2
+	flacBlockSeekTable   byte = 3
+	flacBlockComment     byte = 4
+	flacBlockCueSheet    byte = 5
+	flacBlockPicture     byte = 6
+)
+
+// flacBlock locates a metadata block in a FLAC file without reading its data.
+type flacBlock struct {
+	typ    byte
+	offset int64 // of the 4 byte block header
+	length uint32
+}
+
+func (b flacBlock) read(r io.ReaderAt) ([]byte, error) {
+	data := make([]byte, b.length)
+	if _, err := r.ReadAt(data, b.offset+4); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// flacBlockData is a metadata block read fully into memory.
+type flacBlockData struct {
+	typ  byte
+	data []byte
+}
+
+// flacDefaultPadding is the padding added when the metadata outgrows its current space and the file is rewritten.
+const flacDefaultPadding = 4096
+
+// flacMaxBlockLength is the largest block the 24 bit length field of the block header can describe.
+const flacMaxBlockLength = 1<<24 - 1
+
+// editFLACBlocks replaces the metadata blocks of the FLAC file at path with the result of edit, which is passed all
+// blocks except padding. The metadata is written in place if it fits in the space of the old blocks and their
+// padding, otherwise the file is rewritten with [flacDefaultPadding].
+func editFLACBlocks(path string, edit func([]flacBlockData) []flacBlockData) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	blocks, err := readFLACBlocks(f)
+	if err != nil {
+		return err
+	}
+
+	var datas []flacBlockData
+	for _, block := range blocks {
+		if block.typ == flacBlockPadding {
+			continue
+		}
+		data, err := block.read(f)
+		if err != nil {
+			return fmt.Errorf("read block: %w", err)
+		}
+		datas = append(datas, flacBlockData{typ: block.typ, data: data})
+	}
+
+	datas = edit(datas)
+	if len(datas) == 0 || datas[0].typ != flacBlockStreamInfo {
+		return fmt.Errorf("first block must be streaminfo: %w", ErrSavingFile)
+	}
+
+	metaStart := blocks[0].offset
+	last := blocks[len(blocks)-1]
+	metaEnd := last.offset + 4 + int64(last.length)
+
+	var size int64
+	for _, d := range datas {
+		if len(d.data) > flacMaxBlockLength {
+			return fmt.Errorf("block of %d bytes too large: %w", len(d.data), ErrSavingFile)
+		}
+		size += 4 + int64(len(d.data))
+	}
+
+	switch space := metaEnd - metaStart; {
+	case size == space:
+		return writeFLACBlocksAt(f, metaStart, datas)
+	case size+4 <= space && space-size-4 <= flacMaxBlockLength:
+		datas = append(datas, flacBlockData{typ: flacBlockPadding, data: make([]byte, space-size-4)})
+		return writeFLACBlocksAt(f, metaStart, datas)
+	}
+
+	datas = append(datas, flacBlockData{typ: flacBlockPadding, data: make([]byte, flacDefaultPadding)})
+	return rewriteRange(f, metaStart, metaEnd, encodeFLACBlocks(datas))
+}
+
+func encodeFLACBlocks(datas []flacBlockData) []byte {
+	var b bytes.Buffer
+	for i, d := range datas {
+		typ := d.typ
+		if i == len(datas)-1 {
+			typ |= 0x80
+		}
+		b.Write([]byte{typ, byte(len(d.data) >> 16), byte(len(d.data) >> 8), byte(len(d.data))})
+		b.Write(d.data)
+	}
+	return b.Bytes()
+}
+
+func writeFLACBlocksAt(f *os.File, offset int64, datas []flacBlockData) error {
+	if _, err := f.WriteAt(encodeFLACBlocks(datas), offset); err != nil {
+		return fmt.Errorf("write blocks: %w", err)
+	}
+	return nil
+}
+
+// readFLACBlocks reads the metadata block headers of a FLAC file, after any leading ID3v2 tag. If the file is cut
+// short, the blocks before the cut are returned with the error.
+func readFLACBlocks(r io.ReaderAt) ([]flacBlock, error) {
+	start, err := id3v2TagSize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, start); err != nil || !bytes.Equal(magic, []byte("fLaC")) {
+		return nil, ErrInvalidFile
+	}
+
+	var blocks []flacBlock
+	offset := start + 4
+	for {
+		var header [4]byte
+		if _, err := r.ReadAt(header[:], offset); err != nil {
+			return blocks, fmt.Errorf("read block header: %w", ErrInvalidFile)
+		}
+		block := flacBlock{
+			typ:    header[0] & 0x7f,
+			offset: offset,
+			length: uint32(header[1])<<16 | uint32(header[2])<<8 | uint32(header[3]),
+		}
+		blocks = append(blocks, block)
+
+		offset += 4 + int64(block.length)
+		if header[0]&0x80 != 0 {
+			break
+		}
+	}
+	return blocks, nil
+}
+
+// flacPictureAttrs returns the offset of the width, height, colour depth, and number of colours in the data of a
+// PICTURE block, four big endian numbers after the picture type and the length prefixed MIME type and description.
+func flacPictureAttrs(data []byte) (int, bool) {
+	offset := 4
+	for range 2 {
+		if offset+4 > len(data) {
+			return 0, false
+		}
+		offset += 4 + int(binary.BigEndian.Uint32(data[offset:]))
+	}
+	if offset+16 > len(data) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// readFLACPictures reads the PICTURE blocks of a FLAC file at path in file order. It returns nil for other files.
+func readFLACPictures(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	blocks, err := readFLACBlocks(f)
+	if err != nil {
+		return nil, nil
+	}
+	var pictures [][]byte
+	for _, block := range blocks {
+		if block.typ != flacBlockPicture {
+			continue
+		}
+		data, err := block.read(f)
+		if err != nil {
+			return nil, fmt.Errorf("read block: %w", err)
+		}
+		pictures = append(pictures, data)
+	}
+	return pictures, nil
+}
@@ -0,0 +1,182 @@
+package taglib_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestReadFLACCueSheet(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	cueSheet, err := taglib.ReadFLACCueSheet(path)
+	nilErr(t, err)
+	eq(t, cueSheet, nil)
+
+	exp := &taglib.FLACCueSheet{
+		MediaCatalogNumber: "1234567890123",
+		LeadIn:             88200,
+		CD:                 true,
+		Tracks: []taglib.FLACCueSheetTrack{
+			{Offset: 0, Number: 1, ISRC: "USABC1234567", Audio: true, Indexes: []taglib.FLACCueSheetIndex{{Offset: 0, Number: 1}}},
+			{Offset: 588 * 100, Number: 2, Audio: true, PreEmphasis: true, Indexes: []taglib.FLACCueSheetIndex{{Offset: 0, Number: 0}, {Offset: 588 * 75, Number: 1}}},
+			{Offset: 588 * 300, Number: 170},
+		},
+	}
+
+	path = tmpf(t, flacWithBlock(egFLAC, 5, encodeCueSheet(exp)), "eg.flac")
+	cueSheet, err = taglib.ReadFLACCueSheet(path)
+	nilErr(t, err)
+	if !reflect.DeepEqual(cueSheet, exp) {
+		t.Fatalf("%+v != %+v", cueSheet, exp)
+	}
+
+	// other tags still readable
+	_, err = taglib.ReadTags(path)
+	nilErr(t, err)
+}
+
+func TestReadFLACCueSheetInvalid(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	_, err := taglib.ReadFLACCueSheet(path)
+	eq(t, errors.Is(err, taglib.ErrInvalidFile), true)
+}
+
+// flacWithBlock inserts a metadata block after the STREAMINFO block of a FLAC file
+func flacWithBlock(flac []byte, typ byte, data []byte) []byte {
+	const streamInfoEnd = 4 + 4 + 34
+	header := []byte{typ, byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))}
+
+	var b bytes.Buffer
+	b.Write(flac[:streamInfoEnd])
+	b.Write(header)
+	b.Write(data)
+	b.Write(flac[streamInfoEnd:])
+	return b.Bytes()
+}
+
+func encodeCueSheet(c *taglib.FLACCueSheet) []byte {
+	var b bytes.Buffer
+	b.Write(padded(c.MediaCatalogNumber, 128))
+	binary.Write(&b, binary.BigEndian, c.LeadIn)
+	flags := make([]byte, 259)
+	if c.CD {
+		flags[0] = 0x80
+	}
+	b.Write(flags)
+	b.WriteByte(byte(len(c.Tracks)))
+	for _, track := range c.Tracks {
+		binary.Write(&b, binary.BigEndian, track.Offset)
+		b.WriteByte(track.Number)
+		b.Write(padded(track.ISRC, 12))
+		flags := make([]byte, 14)
+		if !track.Audio {
+			flags[0] |= 0x80
+		}
+		if track.PreEmphasis {
+			flags[0] |= 0x40
+		}
+		b.Write(flags)
+		b.WriteByte(byte(len(track.Indexes)))
+		for _, index := range track.Indexes {
+			binary.Write(&b, binary.BigEndian, index.Offset)
+			b.WriteByte(index.Number)
+			b.Write(make([]byte, 3))
+		}
+	}
+	return b.Bytes()
+}
+
+func padded(s string, n int) []byte {
+	b := make([]byte, n)
+	copy(b, s)
+	return b
+}
+
+func TestFLACApplications(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	applications, err := taglib.ReadFLACApplications(path)
+	nilErr(t, err)
+	eq(t, len(applications), 0)
+
+	// grows past the existing padding, so the file is rewritten
+	big := bytes.Repeat([]byte{1}, 8192)
+	nilErr(t, taglib.WriteFLACApplication(path, "CUES", big))
+	nilErr(t, taglib.WriteFLACApplication(path, "riff", []byte("chunks")))
+
+	applications, err = taglib.ReadFLACApplications(path)
+	nilErr(t, err)
+	eq(t, len(applications), 2)
+	eq(t, applications[0].ID, "CUES")
+	eq(t, bytes.Equal(applications[0].Data, big), true)
+	eq(t, applications[1].ID, "riff")
+	eq(t, string(applications[1].Data), "chunks")
+
+	// replaces in place
+	nilErr(t, taglib.WriteFLACApplication(path, "CUES", []byte("small")))
+	applications, err = taglib.ReadFLACApplications(path)
+	nilErr(t, err)
+	eq(t, len(applications), 2)
+	eq(t, applications[1].ID, "CUES")
+	eq(t, string(applications[1].Data), "small")
+
+	nilErr(t, taglib.WriteFLACApplication(path, "CUES", nil))
+	applications, err = taglib.ReadFLACApplications(path)
+	nilErr(t, err)
+	eq(t, len(applications), 1)
+
+	// the rest of the file is intact
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, len(tags) > 0, true)
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.Length > 0, true)
+
+	err = taglib.WriteFLACApplication(path, "toolong", nil)
+	eq(t, err != nil, true)
+}
+
+func TestReadFLACSeekTable(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	points, err := taglib.ReadFLACSeekTable(path)
+	nilErr(t, err)
+	eq(t, points == nil, true)
+
+	exp := []taglib.FLACSeekPoint{
+		{SampleNumber: 0, Offset: 0, Samples: 4608},
+		{SampleNumber: 441000, Offset: 123456, Samples: 4608},
+		{SampleNumber: 1<<64 - 1},
+	}
+
+	var table bytes.Buffer
+	for _, p := range exp {
+		binary.Write(&table, binary.BigEndian, p)
+	}
+
+	path = tmpf(t, flacWithBlock(egFLAC, 3, table.Bytes()), "eg.flac")
+	points, err = taglib.ReadFLACSeekTable(path)
+	nilErr(t, err)
+	if !reflect.DeepEqual(points, exp) {
+		t.Fatalf("%+v != %+v", points, exp)
+	}
+	eq(t, points[1].IsPlaceholder(), false)
+	eq(t, points[2].IsPlaceholder(), true)
+
+	path = tmpf(t, flacWithBlock(egFLAC, 3, nil), "eg.flac")
+	points, err = taglib.ReadFLACSeekTable(path)
+	nilErr(t, err)
+	eq(t, points != nil && len(points) == 0, true)
+}
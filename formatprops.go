@@ -0,0 +1,220 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// readFormatProperties fills the format specific properties of the file f, which the binary doesn't report, from the
+// headers of the file.
+func readFormatProperties(f fileReader, p *Properties) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	start, err := id3v2TagSize(f)
+	if err != nil {
+		return err
+	}
+	var magic [12]byte
+	if _, err := f.ReadAt(magic[:], start); err != nil && err != io.EOF {
+		return err
+	}
+
+	switch {
+	case string(magic[:4]) == "fLaC":
+		if blocks, err := readFLACBlocks(f); err == nil && blocks[0].typ == flacBlockStreamInfo {
+			if data, err := blocks[0].read(f); err == nil {
+				flacStreamInfoProperties(data, p)
+			}
+		}
+	case string(magic[:4]) == "OggS":
+		oggFormatProperties(f, p)
+	case string(magic[:4]) == "RIFF" && string(magic[8:12]) == "WAVE":
+		wavFormatProperties(f, start, size, p)
+	case string(magic[:4]) == "FORM" && (string(magic[8:12]) == "AIFF" || string(magic[8:12]) == "AIFC"):
+		aiffFormatProperties(f, start, size, p)
+	case string(magic[:4]) == "DSD ":
+		dsfFormatProperties(f, start, p)
+	case string(magic[:4]) == "FRM8":
+		dsdiffFormatProperties(f, start, size, p)
+	case string(magic[:4]) == "wvpk":
+		wavPackFormatProperties(f, start, p)
+	case string(magic[:4]) == "MPCK" && p.MPC == nil:
+		p.MPC = &MPCProperties{StreamVersion: 8}
+	case string(magic[:3]) == "MP+" && p.MPC == nil:
+		// SV7 and earlier, with the version in the low nibble
+		p.MPC = &MPCProperties{StreamVersion: uint(magic[3] & 0xf)}
+	}
+	return nil
+}
+
+// oggFormatProperties reads the properties of the first logical stream of an Ogg file from its identification header.
+func oggFormatProperties(r io.ReaderAt, p *Properties) {
+	packets := oggHeaderPackets(r, 1)
+	if len(packets) == 0 {
+		return
+	}
+	id := packets[0]
+	switch {
+	case bytes.HasPrefix(id, []byte("\x7fFLAC")) && len(id) >= 17:
+		// the mapping version and header count, then "fLaC" and the header of the STREAMINFO block
+		flacStreamInfoProperties(id[17:], p)
+	case bytes.HasPrefix(id, []byte("\x01vorbis")) && len(id) >= 28 && p.Vorbis == nil:
+		// the version, channels, and sample rate, then the bitrates, of which those not set are 0 or negative
+		bitrate := func(b []byte) uint { return uint(max(int32(binary.LittleEndian.Uint32(b)), 0)) }
+		p.Vorbis = &VorbisProperties{
+			Version:        uint(binary.LittleEndian.Uint32(id[7:])),
+			BitrateMaximum: bitrate(id[16:]),
+			BitrateNominal: bitrate(id[20:]),
+			BitrateMinimum: bitrate(id[24:]),
+		}
+		if p.NominalBitrate == 0 {
+			p.NominalBitrate = p.Vorbis.BitrateNominal / 1000
+		}
+	case bytes.HasPrefix(id, []byte("OpusHead")) && len(id) >= 19 && p.Opus == nil:
+		p.Opus = &OpusProperties{
+			Version:              uint(id[8]),
+			PreSkip:              uint(binary.LittleEndian.Uint16(id[10:])),
+			InputSampleRate:      uint(binary.LittleEndian.Uint32(id[12:])),
+			OutputGain:           float64(int16(binary.LittleEndian.Uint16(id[16:]))) / 256, // Q7.8
+			ChannelMappingFamily: uint(id[18]),
+		}
+	}
+}
+
+// flacStreamInfoProperties reads the sample frames and bit depth from the data of a FLAC STREAMINFO block.
+func flacStreamInfoProperties(data []byte, p *Properties) {
+	if len(data) < 18 {
+		return
+	}
+	// 20 bits of sample rate, 3 of channels, 5 of bits per sample less one, and 36 of total samples
+	v := binary.BigEndian.Uint64(data[10:])
+	if p.SampleFrames == 0 {
+		p.SampleFrames = v & (1<<36 - 1)
+	}
+	if p.BitsPerSample == 0 {
+		p.BitsPerSample = uint(v>>36&0x1f) + 1
+	}
+}
+
+// wavFormatProperties reads the bit depth of a WAV file from its "fmt " chunk, and its sample frames from the size of its "data" chunk for PCM, or from its
+// "fact" chunk for compressed formats.
+func wavFormatProperties(r io.ReaderAt, start, end int64, p *Properties) {
+	format := readFirstChunk(r, start+12, end, binary.LittleEndian, "fmt ", 16)
+	if len(format) < 16 {
+		return
+	}
+	formatTag := binary.LittleEndian.Uint16(format)
+	channels := uint64(binary.LittleEndian.Uint16(format[2:]))
+	bits := uint64(binary.LittleEndian.Uint16(format[14:]))
+
+	if p.BitsPerSample == 0 {
+		p.BitsPerSample = uint(bits)
+	}
+	if p.SampleFrames != 0 {
+		return
+	}
+	pcm := formatTag == 1 || formatTag == 0xfffe // PCM, or extensible
+	if data := chunkRegions(r, start+12, end, binary.LittleEndian, 4, "data"); pcm && len(data) > 0 && channels > 0 && bits > 0 {
+		p.SampleFrames = uint64(data[0].end-data[0].start) / (channels * ((bits + 7) / 8))
+		return
+	}
+	if fact := readFirstChunk(r, start+12, end, binary.LittleEndian, "fact", 4); len(fact) == 4 {
+		p.SampleFrames = uint64(binary.LittleEndian.Uint32(fact))
+	}
+}
+
+// aiffFormatProperties reads the sample frames and bit depth of an AIFF or AIFF-C file from its "COMM" chunk.
+func aiffFormatProperties(r io.ReaderAt, start, end int64, p *Properties) {
+	comm := readFirstChunk(r, start+12, end, binary.BigEndian, "COMM", 18)
+	if len(comm) < 18 {
+		return
+	}
+	if p.SampleFrames == 0 {
+		p.SampleFrames = uint64(binary.BigEndian.Uint32(comm[2:]))
+	}
+	if p.BitsPerSample == 0 {
+		p.BitsPerSample = uint(binary.BigEndian.Uint16(comm[6:]))
+	}
+}
+
+// dsfFormatProperties reads the sample count and bit depth of a DSF file from its "fmt " chunk, which follows the 28
+// byte "DSD " chunk and has a 12 byte header.
+func dsfFormatProperties(r io.ReaderAt, start int64, p *Properties) {
+	var format [36]byte
+	if _, err := r.ReadAt(format[:], start+28+12); err != nil {
+		return
+	}
+	if p.SampleFrames == 0 {
+		p.SampleFrames = binary.LittleEndian.Uint64(format[24:])
+	}
+	if p.BitsPerSample == 0 {
+		p.BitsPerSample = uint(binary.LittleEndian.Uint32(format[20:]))
+	}
+}
+
+// dsdiffFormatProperties reads the sample count of a DSDIFF file from the size of its "DSD " chunk and the channel
+// count in its "PROP" chunk. DSD streams have a bit depth of 1.
+func dsdiffFormatProperties(r io.ReaderAt, start, end int64, p *Properties) {
+	props := chunkRegions(r, start+16, end, binary.BigEndian, 8, "PROP")
+	if len(props) == 0 || !hasAt(r, props[0].start, "SND ") {
+		return
+	}
+	chnl := chunkRegions(r, props[0].start+4, props[0].end, binary.BigEndian, 8, "CHNL")
+	if len(chnl) == 0 {
+		return
+	}
+	var channels [2]byte
+	if _, err := r.ReadAt(channels[:], chnl[0].start); err != nil {
+		return
+	}
+	dsd := chunkRegions(r, start+16, end, binary.BigEndian, 8, "DSD ")
+	if n := uint64(binary.BigEndian.Uint16(channels[:])); len(dsd) > 0 && n > 0 && p.SampleFrames == 0 {
+		p.SampleFrames = uint64(dsd[0].end-dsd[0].start) * 8 / n
+	}
+	if p.BitsPerSample == 0 {
+		p.BitsPerSample = 1
+	}
+}
+
+// wavPackFormatProperties reads the version, mode, sample count, and bit depth of a WavPack file from the header of
+// its first block.
+func wavPackFormatProperties(r io.ReaderAt, start int64, p *Properties) {
+	var header [32]byte
+	if _, err := r.ReadAt(header[:], start); err != nil {
+		return
+	}
+	flags := binary.LittleEndian.Uint32(header[24:])
+	if p.WavPack == nil {
+		p.WavPack = &WavPackProperties{
+			Version:  uint(binary.LittleEndian.Uint16(header[8:])),
+			Lossless: flags&0x8 == 0, // hybrid
+		}
+	}
+	// the total is 40 bits, with the upper 8 before the lower 32, which are all set if it's unknown
+	if total := binary.LittleEndian.Uint32(header[12:]); total != 0xffffffff && p.SampleFrames == 0 {
+		p.SampleFrames = uint64(header[11])<<32 | uint64(total)
+	}
+	if p.BitsPerSample == 0 {
+		// bytes per sample less one, and the shift of samples narrower than those bytes
+		p.BitsPerSample = uint(flags&0x3+1)*8 - uint(flags>>13&0x1f)
+	}
+}
+
+// readFirstChunk reads up to n bytes of the payload of the first chunk with the given ID in an IFF style container
+// with 4 byte sizes, or returns nil if there is none.
+func readFirstChunk(r io.ReaderAt, offset, end int64, order binary.ByteOrder, id string, n int64) []byte {
+	regions := chunkRegions(r, offset, end, order, 4, id)
+	if len(regions) == 0 {
+		return nil
+	}
+	data := make([]byte, min(n, regions[0].end-regions[0].start))
+	if _, err := r.ReadAt(data, regions[0].start); err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,715 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+// ID3v2Header describes the layout of an ID3v2 tag.
+type ID3v2Header struct {
+	// MajorVersion is the ID3v2 version, 2, 3, or 4
+	MajorVersion uint
+	// Revision is the revision of the major version, usually 0
+	Revision uint
+	// Size is the size of the tag in bytes, excluding the header and footer
+	Size uint
+	// Unsynchronisation reports whether unsynchronisation is applied to the tag
+	Unsynchronisation bool
+	// ExtendedHeader reports whether the tag has an extended header
+	ExtendedHeader bool
+	// Experimental reports whether the tag is marked as experimental
+	Experimental bool
+	// Footer reports whether the tag has a footer, which is only defined for ID3v2.4
+	Footer bool
+	// CRC reports whether the extended header carries a CRC-32 of the tag data
+	CRC bool
+}
+
+// ReadID3v2Header reads the header of the ID3v2 tag at the start of a file at the given path, as found in MP3, FLAC,
+// TrueAudio, and AAC files. It returns nil if the file doesn't start with an ID3v2 tag.
+func ReadID3v2Header(path string) (_ *ID3v2Header, err error) {
+	defer wrapErr(&err, "read id3v2 header", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	var header [10]byte
+	if _, err := f.ReadAt(header[:], 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if !isID3v2Header(header[:]) {
+		return nil, nil
+	}
+
+	flags := header[5]
+	h := &ID3v2Header{
+		MajorVersion:      uint(header[3]),
+		Revision:          uint(header[4]),
+		Size:              uint(syncsafe(header[6:10])),
+		Unsynchronisation: flags&0x80 != 0,
+		ExtendedHeader:    flags&0x40 != 0 && header[3] >= 3,
+		Experimental:      flags&0x20 != 0,
+		Footer:            flags&0x10 != 0 && header[3] >= 4,
+	}
+	if !h.ExtendedHeader {
+		return h, nil
+	}
+
+	var ext [6]byte
+	if _, err := f.ReadAt(ext[:], 10); err != nil {
+		return nil, fmt.Errorf("read extended header: %w", ErrInvalidFile)
+	}
+	switch h.MajorVersion {
+	case 3:
+		// size, then 2 flag bytes
+		h.CRC = ext[4]&0x80 != 0
+	case 4:
+		// syncsafe size, number of flag bytes, then the flags
+		h.CRC = ext[5]&0x20 != 0
+	}
+	return h, nil
+}
+
+// ID3v2LanguageText is the content of a COMM comment or USLT lyrics frame, which are keyed by language and description.
+type ID3v2LanguageText struct {
+	// Language is the ISO-639-2 code of the text, such as "eng". "XXX" marks an unknown language
+	Language string
+	// Description is a short description of the text, often empty
+	Description string
+	// Text is the comment or lyrics
+	Text string
+}
+
+// ReadID3v2Comments reads all COMM frames from the ID3v2 tag of a file at the given path, in tag order. Formats
+// without an ID3v2 tag return no comments.
+func ReadID3v2Comments(path string) (_ []ID3v2LanguageText, err error) {
+	defer wrapErr(&err, "read id3v2 comments", path)
+	return readID3v2LanguageFrames(path, "COMM")
+}
+
+// WriteID3v2Comments replaces all COMM frames in the ID3v2 tag of a file at path. MPEG, TrueAudio, WAV, AIFF, and DSF
+// files without a tag get an ID3v2.4 tag, while other formats without one, and ID3v2.2 tags, return an error wrapping
+// [errors.ErrUnsupported]. An empty Language is written as "XXX".
+func WriteID3v2Comments(path string, comments []ID3v2LanguageText) (err error) {
+	defer wrapErr(&err, "write id3v2 comments", path)
+	return writeID3v2LanguageFrames(path, "COMM", comments)
+}
+
+// ReadID3v2Lyrics reads all USLT frames from the ID3v2 tag of a file at the given path, in tag order. Formats
+// without an ID3v2 tag return no lyrics.
+func ReadID3v2Lyrics(path string) (_ []ID3v2LanguageText, err error) {
+	defer wrapErr(&err, "read id3v2 lyrics", path)
+	return readID3v2LanguageFrames(path, "USLT")
+}
+
+// WriteID3v2Lyrics replaces all USLT frames in the ID3v2 tag of a file at path, which is created as by
+// [WriteID3v2Comments]. An empty Language is written as "XXX".
+func WriteID3v2Lyrics(path string, lyrics []ID3v2LanguageText) (err error) {
+	defer wrapErr(&err, "write id3v2 lyrics", path)
+	return writeID3v2LanguageFrames(path, "USLT", lyrics)
+}
+
+func readID3v2LanguageFrames(path string, id string) ([]ID3v2LanguageText, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	tag, _, err := readID3v2Tag(f)
+	if err != nil || tag == nil {
+		return nil, err
+	}
+
+	var texts []ID3v2LanguageText
+	for _, frame := range id3v2Frames(tag) {
+		if id3v2FrameID(tag[3], frame.ID) != id {
+			continue
+		}
+		// the encoding, the language, then the terminated description and the text
+		data, ok := id3v2FrameContent(tag[3], byte(frame.Flags), frame.Data)
+		if !ok || len(data) < 4 {
+			continue
+		}
+		description, rest := splitID3v2Text(data[0], data[4:])
+		text, _ := splitID3v2Text(data[0], rest)
+		texts = append(texts, ID3v2LanguageText{
+			Language:    string(data[1:4]),
+			Description: description,
+			Text:        text,
+		})
+	}
+	return texts, nil
+}
+
+func writeID3v2LanguageFrames(path string, id string, texts []ID3v2LanguageText) error {
+	for _, t := range texts {
+		if t.Language != "" && len(t.Language) != 3 {
+			return fmt.Errorf("language %q is not 3 characters", t.Language)
+		}
+	}
+
+	return editID3v2Tag(path, func(major byte, frames []ID3v2Frame) ([]ID3v2Frame, error) {
+		frames = slices.DeleteFunc(frames, func(f ID3v2Frame) bool { return f.ID == id })
+		for _, t := range texts {
+			frames = append(frames, ID3v2Frame{ID: id, Data: renderID3v2LanguageText(major, t)})
+		}
+		return frames, nil
+	})
+}
+
+// ID3v2Credit is a pair from a TIPL involved people or TMCL musician credits frame.
+type ID3v2Credit struct {
+	// Role is the involvement such as "producer", or the instrument such as "violin" for musician credits
+	Role string
+	// Person is the name of the person or group credited
+	Person string
+}
+
+// ReadID3v2InvolvedPeople reads the pairs of the TIPL frame from the ID3v2 tag of a file at the given path. ID3v2.3
+// IPLS frames are read as TIPL. Formats without an ID3v2 tag return no credits.
+func ReadID3v2InvolvedPeople(path string) (_ []ID3v2Credit, err error) {
+	defer wrapErr(&err, "read id3v2 involved people", path)
+	return readID3v2Credits(path, "TIPL")
+}
+
+// WriteID3v2InvolvedPeople replaces the TIPL frame in the ID3v2 tag of a file at path, which is created as by
+// [WriteID3v2Comments]. No credits remove the frame. ID3v2.3 tags store the credits in an IPLS frame.
+func WriteID3v2InvolvedPeople(path string, credits []ID3v2Credit) (err error) {
+	defer wrapErr(&err, "write id3v2 involved people", path)
+	return writeID3v2Credits(path, "TIPL", credits)
+}
+
+// ReadID3v2MusicianCredits reads the pairs of the TMCL frame from the ID3v2 tag of a file at the given path. Formats
+// without an ID3v2 tag return no credits.
+func ReadID3v2MusicianCredits(path string) (_ []ID3v2Credit, err error) {
+	defer wrapErr(&err, "read id3v2 musician credits", path)
+	return readID3v2Credits(path, "TMCL")
+}
+
+// WriteID3v2MusicianCredits replaces the TMCL frame in the ID3v2 tag of a file at path, which is created as by
+// [WriteID3v2Comments]. No credits remove the frame. ID3v2.3 has no such frame, so ID3v2.3 tags return an error
+// wrapping [errors.ErrUnsupported].
+func WriteID3v2MusicianCredits(path string, credits []ID3v2Credit) (err error) {
+	defer wrapErr(&err, "write id3v2 musician credits", path)
+	return writeID3v2Credits(path, "TMCL", credits)
+}
+
+func readID3v2Credits(path string, id string) ([]ID3v2Credit, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	tag, _, err := readID3v2Tag(f)
+	if err != nil || tag == nil {
+		return nil, err
+	}
+
+	for _, frame := range id3v2Frames(tag) {
+		frameID := id3v2FrameID(tag[3], frame.ID)
+		if frameID == "IPLS" {
+			frameID = "TIPL"
+		}
+		if frameID != id {
+			continue
+		}
+		data, ok := id3v2FrameContent(tag[3], byte(frame.Flags), frame.Data)
+		if !ok || len(data) == 0 {
+			continue
+		}
+
+		// the fields alternate between role and person, a trailing role has no person
+		fields := splitID3v2Texts(data[0], data[1:])
+		var credits []ID3v2Credit
+		for i := 0; i < len(fields); i += 2 {
+			credit := ID3v2Credit{Role: fields[i]}
+			if i+1 < len(fields) {
+				credit.Person = fields[i+1]
+			}
+			credits = append(credits, credit)
+		}
+		return credits, nil
+	}
+	return nil, nil
+}
+
+func writeID3v2Credits(path string, id string, credits []ID3v2Credit) error {
+	var fields []string
+	for _, c := range credits {
+		fields = append(fields, c.Role, c.Person)
+	}
+
+	return editID3v2Tag(path, func(major byte, frames []ID3v2Frame) ([]ID3v2Frame, error) {
+		// ID3v2.3 has IPLS for involved people, and nothing for musician credits
+		stored := id
+		if major == 3 {
+			if id == "TMCL" {
+				return nil, fmt.Errorf("TMCL frame in id3v2.3 tag: %w", errors.ErrUnsupported)
+			}
+			stored = "IPLS"
+		}
+		frames = slices.DeleteFunc(frames, func(f ID3v2Frame) bool { return f.ID == stored })
+		if len(fields) > 0 {
+			frames = append(frames, ID3v2Frame{ID: stored, Data: renderID3v2Texts(major, fields)})
+		}
+		return frames, nil
+	})
+}
+
+// ID3v2Frame is a frame of an ID3v2 tag as stored in the file.
+type ID3v2Frame struct {
+	// ID is the frame ID as stored, with three characters in ID3v2.2 tags, such as "TT2", and four in later versions
+	ID string
+	// Flags are the status and format flags of the frame, 0 in ID3v2.2 tags
+	Flags uint16
+	// Data is the content of the frame after its header, which is still compressed, encrypted, or unsynchronised if
+	// the flags say so
+	Data []byte
+}
+
+// ReadID3v2Frames reads the frames of the ID3v2 tag of a file at the given path in file order, from the start of the
+// file or from the ID3 chunk of WAV and AIFF files. Frame IDs are reported as stored, so the three character IDs of
+// ID3v2.2 tags, which TagLib converts when reading and upgrades to ID3v2.4 or ID3v2.3 when saving, are visible. It
+// returns nil if the file has no ID3v2 tag.
+func ReadID3v2Frames(path string) (_ []ID3v2Frame, err error) {
+	defer wrapErr(&err, "read id3v2 frames", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	tag, _, err := readID3v2Tag(f)
+	if err != nil {
+		return nil, err
+	}
+	return id3v2Frames(tag), nil
+}
+
+// readID3v2Tag reads the ID3v2 tag at the start of a file, in the first ID3 chunk of a WAV or AIFF file, or at the end
+// of a DSF file, and reports whether it is in a WAV chunk. It returns nil if there is no tag.
+func readID3v2Tag(f fileReader) ([]byte, bool, error) {
+	loc, err := locateID3v2Tag(f, "")
+	if errors.Is(err, errors.ErrUnsupported) || errors.Is(err, ErrInvalidFile) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if loc.end == loc.start {
+		return nil, false, nil
+	}
+	// the size is the header's claim, so a tag cut off by the end of the file is read as far as it goes
+	tag, err := io.ReadAll(io.NewSectionReader(f, loc.start, loc.end-loc.start))
+	if err != nil {
+		return nil, false, fmt.Errorf("read id3v2 tag: %w", err)
+	}
+	if !isID3v2Header(tag) {
+		return nil, false, nil
+	}
+	return tag, loc.form != nil && loc.form.isWAV(), nil
+}
+
+// id3v2Frames splits the frames of an ID3v2 tag, stopping at the padding or at a frame which overruns the tag.
+func id3v2Frames(tag []byte) []ID3v2Frame {
+	major, body, ok := id3v2Body(tag)
+	if !ok {
+		return nil
+	}
+	var frames []ID3v2Frame
+	headerSize := id3v2FrameHeaderSize(major)
+	for len(body) >= headerSize && body[0] != 0 {
+		id, size, _ := id3v2FrameHeader(major, body)
+		if size > len(body)-headerSize {
+			break
+		}
+		var flags uint16
+		if major > 2 {
+			flags = binary.BigEndian.Uint16(body[8:10])
+		}
+		frames = append(frames, ID3v2Frame{ID: id, Flags: flags, Data: body[headerSize : headerSize+size]})
+		body = body[headerSize+size:]
+	}
+	return frames
+}
+
+// id3v2TextFrames reads the first value of each text frame in an ID3v2 tag, keyed by ID3v2.3 and ID3v2.4 frame ID.
+// Compressed and encrypted frames are skipped.
+func id3v2TextFrames(tag []byte) map[string]string {
+	frames := map[string]string{}
+	major, body, ok := id3v2Body(tag)
+	if !ok {
+		return frames
+	}
+
+	headerSize := id3v2FrameHeaderSize(major)
+	for len(body) >= headerSize && body[0] != 0 {
+		id, size, format := id3v2FrameHeader(major, body)
+		if major == 2 {
+			id = id3v22Frames[id]
+		}
+		if size > len(body)-headerSize {
+			break
+		}
+		data, ok := id3v2FrameContent(major, format, body[headerSize:headerSize+size])
+		body = body[headerSize+size:]
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(id, "T") && id != "TXXX" && len(data) > 0 {
+			if _, ok := frames[id]; !ok {
+				frames[id] = decodeID3v2Text(data[0], data[1:])
+			}
+		}
+	}
+	return frames
+}
+
+// id3v2FrameContent returns the content of a frame with the given format flags, after undoing frame level
+// unsynchronisation and skipping a grouping ID or data length indicator. It reports false for compressed and
+// encrypted frames.
+func id3v2FrameContent(major, format byte, data []byte) ([]byte, bool) {
+	switch major {
+	case 3:
+		if format&0xc0 != 0 { // compressed or encrypted
+			return nil, false
+		}
+		if format&0x20 != 0 && len(data) > 0 { // group
+			data = data[1:]
+		}
+	case 4:
+		if format&0x0c != 0 { // compressed or encrypted
+			return nil, false
+		}
+		if format&0x01 != 0 && len(data) >= 4 { // data length indicator
+			data = data[4:]
+		}
+		if format&0x02 != 0 {
+			data = deunsynchronise(data)
+		}
+	}
+	return data, true
+}
+
+// id3v2Body returns the version and the frames and padding of an ID3v2 tag, after undoing tag level
+// unsynchronisation and skipping any extended header and footer.
+func id3v2Body(tag []byte) (byte, []byte, bool) {
+	if len(tag) < 10 || !isID3v2Header(tag) {
+		return 0, nil, false
+	}
+	major, flags := tag[3], tag[5]
+	body := tag[10:]
+	if major >= 4 && flags&0x10 != 0 && len(body) >= 10 {
+		body = body[:len(body)-10] // footer
+	}
+	if major < 4 && flags&0x80 != 0 {
+		body = deunsynchronise(body)
+	}
+	if major > 2 && flags&0x40 != 0 && len(body) >= 4 {
+		// the ID3v2.4 size includes itself, the ID3v2.3 one doesn't
+		size := int(binary.BigEndian.Uint32(body))
+		if major == 4 {
+			size = int(syncsafe(body))
+		} else {
+			size += 4
+		}
+		body = body[min(size, len(body)):]
+	}
+	return major, body, true
+}
+
+func id3v2FrameHeaderSize(major byte) int {
+	if major == 2 {
+		return 6
+	}
+	return 10
+}
+
+// id3v2FrameHeader reads the ID, size, and format flags of the frame at the start of body, which has at least
+// [id3v2FrameHeaderSize] bytes.
+func id3v2FrameHeader(major byte, body []byte) (string, int, byte) {
+	switch major {
+	case 2:
+		return string(body[:3]), int(body[3])<<16 | int(body[4])<<8 | int(body[5]), 0
+	case 3:
+		return string(body[:4]), int(binary.BigEndian.Uint32(body[4:])), body[9]
+	default:
+		return string(body[:4]), int(syncsafe(body[4:])), body[9]
+	}
+}
+
+// id3v2Padding returns the size of the padding after the frames of an ID3v2 tag, or 0 if the frames can't be read.
+func id3v2Padding(tag []byte) int64 {
+	major, body, ok := id3v2Body(tag)
+	if !ok {
+		return 0
+	}
+	headerSize := id3v2FrameHeaderSize(major)
+	for len(body) >= headerSize && body[0] != 0 {
+		_, size, _ := id3v2FrameHeader(major, body)
+		if size > len(body)-headerSize {
+			return 0
+		}
+		body = body[headerSize+size:]
+	}
+	for _, b := range body {
+		if b != 0 {
+			return 0
+		}
+	}
+	return int64(len(body))
+}
+
+// id3v22Frames maps ID3v2.2 frame IDs to the ID3v2.4 frames TagLib converts them to when reading. Frames not
+// listed here, such as the deprecated RVA and EQU frames, are dropped when the tag is saved.
+var id3v22Frames = map[string]string{
+	"CNT": "PCNT", "COM": "COMM", "CRA": "AENC", "ETC": "ETCO", "GEO": "GEOB", "IPL": "TIPL", "MCI": "MCDI",
+	"MLL": "MLLT", "PIC": "APIC", "POP": "POPM", "REV": "RVRB", "SLT": "SYLT", "STC": "SYTC", "TAL": "TALB",
+	"TBP": "TBPM", "TCM": "TCOM", "TCO": "TCON", "TCP": "TCMP", "TCR": "TCOP", "TDY": "TDLY", "TEN": "TENC",
+	"TFT": "TFLT", "TKE": "TKEY", "TLA": "TLAN", "TLE": "TLEN", "TMT": "TMED", "TOA": "TOPE", "TOF": "TOFN",
+	"TOL": "TOLY", "TOR": "TDOR", "TOT": "TOAL", "TP1": "TPE1", "TP2": "TPE2", "TP3": "TPE3", "TP4": "TPE4",
+	"TPA": "TPOS", "TPB": "TPUB", "TRC": "TSRC", "TRD": "TDRC", "TRK": "TRCK", "TS2": "TSO2", "TSA": "TSOA",
+	"TSC": "TSOC", "TSP": "TSOP", "TSS": "TSSE", "TST": "TSOT", "TT1": "TIT1", "TT2": "TIT2", "TT3": "TIT3",
+	"TXT": "TEXT", "TXX": "TXXX", "TYE": "TDRC", "UFI": "UFID", "ULT": "USLT", "WAF": "WOAF", "WAR": "WOAR",
+	"WAS": "WOAS", "WCM": "WCOM", "WCP": "WCOP", "WPB": "WPUB", "WXX": "WXXX",
+}
+
+// decodeID3v2Text decodes the first value of a text frame with the given encoding byte.
+func decodeID3v2Text(encoding byte, data []byte) string {
+	switch encoding {
+	case 1, 2: // UTF-16 with a BOM, UTF-16BE
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(data) >= 2 {
+			if data[0] == 0xff && data[1] == 0xfe {
+				order = binary.LittleEndian
+			}
+			data = data[2:]
+		}
+		var units []uint16
+		for i := 0; i+1 < len(data); i += 2 {
+			u := order.Uint16(data[i:])
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		return string(utf16.Decode(units))
+	case 3: // UTF-8
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			data = data[:i]
+		}
+		return string(data)
+	default: // ISO-8859-1
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			data = data[:i]
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+}
+
+// deunsynchronise removes the zero bytes [unsynchronise] inserted after 0xFF.
+func deunsynchronise(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		out = append(out, data[i])
+		if data[i] == 0xff && i+1 < len(data) && data[i+1] == 0 {
+			i++
+		}
+	}
+	return out
+}
+
+func isID3v2Header(header []byte) bool {
+	return len(header) >= 10 && bytes.Equal(header[:3], []byte("ID3")) &&
+		header[3] != 0xff && header[4] != 0xff &&
+		(header[6]|header[7]|header[8]|header[9])&0x80 == 0
+}
+
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0])<<21 | uint32(b[1])<<14 | uint32(b[2])<<7 | uint32(b[3])
+}
+
+func putSyncsafe(b []byte, v uint32) {
+	b[0] = byte(v>>21) & 0x7f
+	b[1] = byte(v>>14) & 0x7f
+	b[2] = byte(v>>7) & 0x7f
+	b[3] = byte(v) & 0x7f
+}
+
+// id3v2TagSize returns the size of the ID3v2 tag at the start of r including its header and footer, or 0 if there is none.
+func id3v2TagSize(r io.ReaderAt) (int64, error) {
+	var header [10]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !isID3v2Header(header[:]) {
+		return 0, nil
+	}
+
+	size := 10 + int64(syncsafe(header[6:10]))
+	if header[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+	return size, nil
+}
+
+// addID3v2Footer adds a footer to the ID3v2.4 tag at the start of the file at path, dropping the tag's padding. Files
+// without an ID3v2.4 tag at the start, or with a footer already, are left as is.
+func addID3v2Footer(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 10)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("read header: %w", err)
+	}
+	if !isID3v2Header(header) || header[3] != 4 || header[5]&0x10 != 0 {
+		return nil
+	}
+
+	size := syncsafe(header[6:10])
+	body := make([]byte, size)
+	if _, err := f.ReadAt(body, 10); err != nil {
+		return fmt.Errorf("read tag: %w", err)
+	}
+	// the spec forbids padding with a footer, but TagLib subtracts the footer size from the tag size when parsing, so
+	// the frames end with a frame for it to cut short and skip instead of the last real one
+	body = append(body[:id3v2FramesEnd(body, header[5])], id3v2FooterGuard...)
+
+	header[5] |= 0x10
+	putSyncsafe(header[6:10], uint32(len(body)))
+	footer := append([]byte("3DI"), header[3:]...)
+
+	var tag bytes.Buffer
+	tag.Write(header)
+	tag.Write(body)
+	tag.Write(footer)
+
+	return rewriteRange(f, 0, 10+int64(size), tag.Bytes())
+}
+
+// id3v2FooterGuard is an empty PRIV frame of 11 bytes, one more than the footer, which ends the frames of a tag with a
+// footer so TagLib's misreading of the tag size only loses it.
+var id3v2FooterGuard = []byte{'P', 'R', 'I', 'V', 0, 0, 0, 1, 0, 0, 0}
+
+// unsynchroniseID3v2 applies unsynchronisation to every frame of the ID3v2.4 tag at the start of the file at path,
+// so that the tag contains no false MPEG sync signals. The tag is written in place if it still fits in its padding.
+// Files without an ID3v2.4 tag at the start, or with unsynchronisation already, are left as is.
+func unsynchroniseID3v2(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 10)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("read header: %w", err)
+	}
+	if !isID3v2Header(header) || header[3] != 4 || header[5]&0x80 != 0 || header[5]&0x10 != 0 {
+		return nil
+	}
+
+	size := syncsafe(header[6:10])
+	body := make([]byte, size)
+	if _, err := f.ReadAt(body, 10); err != nil {
+		return fmt.Errorf("read tag: %w", err)
+	}
+
+	var framesStart int
+	if header[5]&0x40 != 0 && len(body) >= 4 {
+		framesStart = min(int(syncsafe(body[0:4])), len(body))
+	}
+	framesEnd := id3v2FramesEnd(body, header[5])
+
+	var frames bytes.Buffer
+	frames.Write(body[:framesStart])
+	for offset := framesStart; offset < framesEnd; {
+		frameSize := int(syncsafe(body[offset+4 : offset+8]))
+		frameHeader := bytes.Clone(body[offset : offset+10])
+		frameData := unsynchronise(body[offset+10 : offset+10+frameSize])
+
+		frameHeader[9] |= 0x02 // format flags, unsynchronisation
+		putSyncsafe(frameHeader[4:8], uint32(len(frameData)))
+		frames.Write(frameHeader)
+		frames.Write(frameData)
+
+		offset += 10 + frameSize
+	}
+
+	header[5] |= 0x80
+
+	padding := len(body) - framesEnd
+	if grown := frames.Len() - framesEnd; grown <= padding {
+		frames.Write(make([]byte, padding-grown))
+		if _, err := f.WriteAt(append(header, frames.Bytes()...), 0); err != nil {
+			return fmt.Errorf("write tag: %w", err)
+		}
+		return nil
+	}
+
+	putSyncsafe(header[6:10], uint32(frames.Len()))
+	return rewriteRange(f, 0, 10+int64(size), append(header, frames.Bytes()...))
+}
+
+// unsynchronise inserts a zero byte after every 0xFF which is followed by a byte that could be mistaken for an MPEG
+// sync signal or a previous unsynchronisation, and after a trailing 0xFF.
+func unsynchronise(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i, b := range data {
+		out = append(out, b)
+		if b != 0xff {
+			continue
+		}
+		if i+1 == len(data) || data[i+1] >= 0xe0 || data[i+1] == 0x00 {
+			out = append(out, 0x00)
+		}
+	}
+	return out
+}
+
+// id3v2FramesEnd returns the offset in the ID3v2.4 tag body where the frames end and the padding starts.
+func id3v2FramesEnd(body []byte, flags byte) int {
+	var offset int
+	if flags&0x40 != 0 && len(body) >= 4 {
+		offset = int(syncsafe(body[0:4])) // includes its own size field
+	}
+	for offset+10 <= len(body) && body[offset] != 0 {
+		size := int(syncsafe(body[offset+4 : offset+8]))
+		if offset+10+size > len(body) {
+			break
+		}
+		offset += 10 + size
+	}
+	return min(offset, len(body))
+}
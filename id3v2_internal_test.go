@@ -0,0 +1,42 @@
+package taglib
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetID3v2PaddingExtendedHeader(t *testing.T) {
+	t.Parallel()
+
+	// an ID3v2.3 tag with an extended header giving 32 bytes of padding
+	frame := append([]byte("TIT2\x00\x00\x00\x06\x00\x00"), "\x00Title"...)
+	body := append([]byte{0, 0, 0, 6, 0, 0, 0, 0, 0, 32}, frame...)
+	body = append(body, make([]byte, 32)...)
+	tag := []byte{'I', 'D', '3', 3, 0, 0x40, 0, 0, 0, 0}
+	putSyncsafe(tag[6:10], uint32(len(body)))
+	tag = append(tag, body...)
+
+	path := filepath.Join(t.TempDir(), "eg.mp3")
+	if err := os.WriteFile(path, append(tag, "audio"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setID3v2Padding(path, 100); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.BigEndian.Uint32(b[16:20]); got != 100 {
+		t.Errorf("extended header padding size %d, want 100", got)
+	}
+	if got := id3v2Padding(b[:len(b)-len("audio")]); got != 100 {
+		t.Errorf("padding %d, want 100", got)
+	}
+	if got := string(b[len(b)-len("audio"):]); got != "audio" {
+		t.Errorf("audio %q moved", got)
+	}
+}
@@ -0,0 +1,297 @@
+package taglib_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestReadID3v2Header(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	header, err := taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, header, nil)
+
+	path = tmpf(t, egMP3, "eg.mp3")
+	err = taglib.WriteTags(path, map[string][]string{"TITLE": {"title"}}, 0)
+	nilErr(t, err)
+
+	header, err = taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, header.MajorVersion, 4)
+	eq(t, header.Footer, false)
+	eq(t, header.ExtendedHeader, false)
+	eq(t, header.CRC, false)
+
+	// 2.3 with an extended header carrying a CRC
+	tag := []byte("ID3\x03\x00\x40\x00\x00\x00\x0a\x00\x00\x00\x06\x80\x00\x00\x00\x00\x00\x12\x34\x56\x78")
+	path = tmpf(t, tag, "eg.mp3")
+	header, err = taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, *header, taglib.ID3v2Header{MajorVersion: 3, Size: 10, ExtendedHeader: true, CRC: true})
+}
+
+func TestWriteID3v2Footer(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	tags := map[string][]string{"TITLE": {"title"}, "ARTIST": {"artist"}}
+	err := taglib.WriteTags(path, tags, taglib.Clear|taglib.ID3v2Footer)
+	nilErr(t, err)
+
+	header, err := taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, header.Footer, true)
+
+	b, err := os.ReadFile(path)
+	nilErr(t, err)
+	footer := b[10+header.Size : 10+header.Size+10]
+	eq(t, string(footer[:3]), "3DI")
+	eq(t, bytes.Equal(footer[3:], b[3:10]), true)
+
+	// no padding, with an empty frame last for TagLib to skip
+	padding, err := taglib.ReadPadding(path)
+	nilErr(t, err)
+	eq(t, padding, 0)
+	frames, err := taglib.ReadID3v2Frames(path)
+	nilErr(t, err)
+	eq(t, frames[len(frames)-1].ID, "PRIV")
+
+	got, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.Length > 0, true)
+}
+
+func TestWriteID3v2Unsynchronisation(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	err := taglib.WriteImage(path, coverJPG)
+	nilErr(t, err)
+
+	tags := map[string][]string{"TITLE": {"title"}}
+	err = taglib.WriteTags(path, tags, taglib.Clear|taglib.ID3v2Unsynchronisation)
+	nilErr(t, err)
+
+	header, err := taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, header.Unsynchronisation, true)
+
+	// no false sync signals left in the tag
+	b, err := os.ReadFile(path)
+	nilErr(t, err)
+	tag := b[10 : 10+header.Size]
+	for i := range len(tag) - 1 {
+		if tag[i] == 0xff && tag[i+1] >= 0xe0 {
+			t.Fatalf("sync signal at %d", i)
+		}
+	}
+
+	got, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+
+	image, err := taglib.ReadImage(path)
+	nilErr(t, err)
+	eq(t, bytes.Equal(image, coverJPG), true)
+
+	// without the option the next save drops it again
+	err = taglib.WriteTags(path, tags, 0)
+	nilErr(t, err)
+
+	header, err = taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, header.Unsynchronisation, false)
+}
+
+func TestID3v2Comments(t *testing.T) {
+	t.Parallel()
+
+	comments := []taglib.ID3v2LanguageText{
+		{Language: "eng", Text: "Comment"},
+		{Language: "deu", Description: "Notes", Text: "Grüße"},
+		{Description: "日本", Text: "語"},
+	}
+	for _, path := range []string{
+		tmpf(t, egMP3, "eg.mp3"), tmpf(t, egWAV, "eg.wav"), tmpf(t, egAIFF, "eg.aiff"), tmpf(t, egDSF, "eg.dsf"),
+		tmpf(t, egTTA, "eg.tta"),
+	} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			before, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+
+			nilErr(t, taglib.WriteID3v2Comments(path, comments))
+			got, err := taglib.ReadID3v2Comments(path)
+			nilErr(t, err)
+			eq(t, len(got), 3)
+			eq(t, got[0], comments[0])
+			eq(t, got[1], comments[1])
+			eq(t, got[2], taglib.ID3v2LanguageText{Language: "XXX", Description: "日本", Text: "語"})
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			eq(t, tags[taglib.Comment][0], "Comment")
+
+			after, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			eq(t, after.Length, before.Length)
+			eq(t, after.SampleFrames, before.SampleFrames)
+		})
+	}
+
+	// an ID3v2.3 tag is kept, with UTF-16 for text outside ISO-8859-1
+	audio := egMP3[10+(int(egMP3[6])<<21|int(egMP3[7])<<14|int(egMP3[8])<<7|int(egMP3[9])):]
+	path := tmpf(t, append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), audio...), "eg.mp3")
+	nilErr(t, taglib.WriteID3v2Comments(path, comments))
+	got, err := taglib.ReadID3v2Comments(path)
+	nilErr(t, err)
+	eq(t, len(got), 3)
+	eq(t, got[2].Description, "日本")
+	header, err := taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, header.MajorVersion, 3)
+
+	// the footer is kept, and the frames read back by TagLib
+	path = tmpf(t, egMP3, "eg.mp3")
+	nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, taglib.Clear|taglib.ID3v2Footer))
+	nilErr(t, taglib.WriteID3v2Comments(path, comments[:1]))
+	header, err = taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, header.Footer, true)
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}, taglib.Comment: {"Comment"}})
+
+	// the Vorbis comment of FLAC files holds their comments
+	err = taglib.WriteID3v2Comments(tmpf(t, egFLAC, "eg.flac"), comments)
+	eq(t, errors.Is(err, errors.ErrUnsupported), true)
+}
+
+func TestID3v2CommentKeys(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	err := taglib.WriteTags(path, map[string][]string{
+		"COMMENT:iTunNORM": {"a", "b"},
+		taglib.Comment:     {"plain1", "plain2"},
+	}, taglib.Clear)
+	nilErr(t, err)
+
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Comment: {"plain1", "plain2"}, "COMMENT:ITUNNORM": {"a", "b"}})
+	got, err := taglib.ReadID3v2Comments(path)
+	nilErr(t, err)
+	eq(t, len(got), 4)
+	eq(t, got[2], taglib.ID3v2LanguageText{Language: "XXX", Description: "iTunNORM", Text: "a"})
+
+	// the case and language of existing frames are kept, and other comments left alone
+	nilErr(t, taglib.WriteID3v2Comments(path, []taglib.ID3v2LanguageText{
+		{Language: "eng", Description: "iTunNORM", Text: "a"},
+		{Language: "deu", Text: "plain"},
+	}))
+	nilErr(t, taglib.WriteTags(path, map[string][]string{"COMMENT:ITUNNORM": {"z"}}, 0))
+	got, err = taglib.ReadID3v2Comments(path)
+	nilErr(t, err)
+	eq(t, slices.Equal(got, []taglib.ID3v2LanguageText{
+		{Language: "deu", Text: "plain"},
+		{Language: "eng", Description: "iTunNORM", Text: "z"},
+	}), true)
+
+	nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, 0))
+	got, err = taglib.ReadID3v2Comments(path)
+	nilErr(t, err)
+	eq(t, len(got), 2)
+	eq(t, got[0], taglib.ID3v2LanguageText{Language: "deu", Text: "plain"})
+
+	nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, taglib.Clear))
+	got, err = taglib.ReadID3v2Comments(path)
+	nilErr(t, err)
+	eq(t, len(got), 0)
+}
+
+func TestID3v2Lyrics(t *testing.T) {
+	t.Parallel()
+
+	lyrics := []taglib.ID3v2LanguageText{{Language: "eng", Text: "Line 1\nLine 2"}, {Language: "fra", Text: "Ligne"}}
+
+	// a new tag for a file without one
+	audio := egMP3[10+(int(egMP3[6])<<21|int(egMP3[7])<<14|int(egMP3[8])<<7|int(egMP3[9])):]
+	paths := []string{tmpf(t, audio, "eg.mp3"), tmpf(t, egMP3, "eg.mp3"), tmpf(t, egAIFF, "eg.aiff")}
+	for _, path := range paths {
+		nilErr(t, taglib.WriteID3v2Lyrics(path, lyrics))
+		got, err := taglib.ReadID3v2Lyrics(path)
+		nilErr(t, err)
+		eq(t, slices.Equal(got, lyrics), true)
+
+		tags, err := taglib.ReadTags(path)
+		nilErr(t, err)
+		eq(t, slices.Equal(tags[taglib.Lyrics], []string{"Line 1\nLine 2", "Ligne"}), true)
+
+		nilErr(t, taglib.WriteID3v2Lyrics(path, nil))
+		got, err = taglib.ReadID3v2Lyrics(path)
+		nilErr(t, err)
+		eq(t, len(got), 0)
+	}
+
+	header, err := taglib.ReadID3v2Header(paths[0])
+	nilErr(t, err)
+	eq(t, header.MajorVersion, 4)
+	properties, err := taglib.ReadProperties(paths[0])
+	nilErr(t, err)
+	eq(t, properties.Length > 0, true)
+}
+
+func TestID3v2Credits(t *testing.T) {
+	t.Parallel()
+
+	people := []taglib.ID3v2Credit{{Role: "producer", Person: "Producer"}, {Role: "mix", Person: "Mixer"}}
+	musicians := []taglib.ID3v2Credit{{Role: "violin", Person: "Violinist"}}
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	nilErr(t, taglib.WriteID3v2InvolvedPeople(path, people))
+	nilErr(t, taglib.WriteID3v2MusicianCredits(path, musicians))
+
+	got, err := taglib.ReadID3v2InvolvedPeople(path)
+	nilErr(t, err)
+	eq(t, slices.Equal(got, people), true)
+	got, err = taglib.ReadID3v2MusicianCredits(path)
+	nilErr(t, err)
+	eq(t, slices.Equal(got, musicians), true)
+
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, tags["PRODUCER"][0], "Producer")
+	eq(t, tags["MIXER"][0], "Mixer")
+	eq(t, tags["PERFORMER:VIOLIN"][0], "Violinist")
+
+	nilErr(t, taglib.WriteID3v2InvolvedPeople(path, nil))
+	got, err = taglib.ReadID3v2InvolvedPeople(path)
+	nilErr(t, err)
+	eq(t, len(got), 0)
+
+	// ID3v2.3 stores involved people as IPLS, and has no musician credits
+	audio := egMP3[10+(int(egMP3[6])<<21|int(egMP3[7])<<14|int(egMP3[8])<<7|int(egMP3[9])):]
+	path = tmpf(t, append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), audio...), "eg.mp3")
+	nilErr(t, taglib.WriteID3v2InvolvedPeople(path, people))
+	got, err = taglib.ReadID3v2InvolvedPeople(path)
+	nilErr(t, err)
+	eq(t, slices.Equal(got, people), true)
+	frames, err := taglib.ReadID3v2Frames(path)
+	nilErr(t, err)
+	eq(t, frames[0].ID, "IPLS")
+
+	err = taglib.WriteID3v2MusicianCredits(path, musicians)
+	eq(t, errors.Is(err, errors.ErrUnsupported), true)
+}
@@ -0,0 +1,338 @@
+package taglib
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// id3v2DefaultPadding is the padding TagLib leaves in a new or grown ID3v2 tag.
+const id3v2DefaultPadding = 1024
+
+// id3v2Location is where the ID3v2 tag of a file is, or where a new one goes.
+type id3v2Location struct {
+	// start and end are the bounds of the tag, equal if there is none
+	start, end int64
+	// form holds the tag of WAV and AIFF files in chunk, which has no ID if there is none
+	form  *iffForm
+	chunk iffChunk
+	// dsf is set for DSF files, whose header points to the tag at the end of the file
+	dsf bool
+}
+
+// locateID3v2Tag finds the ID3v2 tag at the start of f, in the ID3 chunk of WAV and AIFF files, or at the end of DSF
+// files. New tags go at the start of MPEG and TrueAudio files, which are recognised by their first frame or by the
+// extension of path. Other formats return an error wrapping [errors.ErrUnsupported].
+func locateID3v2Tag(f fileReader, path string) (id3v2Location, error) {
+	size, err := id3v2TagSize(f)
+	if err != nil {
+		return id3v2Location{}, fmt.Errorf("read id3v2 size: %w", err)
+	}
+	if size > 0 {
+		return id3v2Location{start: 0, end: size}, nil
+	}
+
+	var magic [28]byte
+	if _, err := f.ReadAt(magic[:], 0); err != nil && !errors.Is(err, io.EOF) {
+		return id3v2Location{}, fmt.Errorf("read magic: %w", err)
+	}
+	switch string(magic[:4]) {
+	case "DSD ":
+		info, err := f.Stat()
+		if err != nil {
+			return id3v2Location{}, fmt.Errorf("stat: %w", err)
+		}
+		// the "DSD " chunk has the size of the file, then the offset of the tag, 0 if there is none
+		offset := int64(binary.LittleEndian.Uint64(magic[20:]))
+		if offset == 0 {
+			offset = info.Size()
+		}
+		if offset < 28 || offset > info.Size() {
+			return id3v2Location{}, fmt.Errorf("dsf tag offset: %w", ErrInvalidFile)
+		}
+		return id3v2Location{start: offset, end: info.Size(), dsf: true}, nil
+	case "RIFF", "RF64", "BW64", "FORM":
+		form, err := readIFFForm(f)
+		if err != nil {
+			break
+		}
+		for _, c := range form.chunks {
+			if c.id == "ID3 " || c.id == "id3 " {
+				return id3v2Location{start: c.offset + 8, end: c.offset + 8 + c.size, form: form, chunk: c}, nil
+			}
+		}
+		return id3v2Location{start: form.end, end: form.end, form: form}, nil
+	case "TTA1":
+		return id3v2Location{}, nil
+	}
+	if isMPEGHeader(magic[:]) {
+		return id3v2Location{}, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3", ".mp2", ".tta":
+		return id3v2Location{}, nil
+	}
+	return id3v2Location{}, fmt.Errorf("id3v2 tag in this format: %w", errors.ErrUnsupported)
+}
+
+// editID3v2Tag passes the version and frames of the ID3v2 tag of the file at path to edit, and saves the frames it
+// returns in place of the tag, as found by [locateID3v2Tag]. Files without a tag get an ID3v2.4 tag. The tag is saved
+// without unsynchronisation or an extended header, in place if it still fits. ID3v2.2 tags, which TagLib upgrades when
+// saving, return an error wrapping [errors.ErrUnsupported].
+func editID3v2Tag(path string, edit func(major byte, frames []ID3v2Frame) ([]ID3v2Frame, error)) error {
+	return editID3v2TagVersion(path, 4, edit)
+}
+
+// editID3v2TagVersion is like [editID3v2Tag], but files without a tag get one of the given major version.
+func editID3v2TagVersion(path string, newMajor byte, edit func(major byte, frames []ID3v2Frame) ([]ID3v2Frame, error)) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	loc, err := locateID3v2Tag(f, path)
+	if err != nil {
+		return err
+	}
+
+	major := newMajor
+	var footer bool
+	var frames []ID3v2Frame
+	if loc.end > loc.start {
+		tag, err := io.ReadAll(io.NewSectionReader(f, loc.start, loc.end-loc.start))
+		if err != nil {
+			return fmt.Errorf("read id3v2 tag: %w", err)
+		}
+		if int64(len(tag)) < loc.end-loc.start {
+			return fmt.Errorf("read id3v2 tag: %w", io.ErrUnexpectedEOF)
+		}
+		if !isID3v2Header(tag) {
+			return fmt.Errorf("id3v2 header: %w", ErrInvalidFile)
+		}
+		major = tag[3]
+		if major < 3 {
+			return fmt.Errorf("id3v2.%d tag: %w", major, errors.ErrUnsupported)
+		}
+		footer = major == 4 && tag[5]&0x10 != 0
+		for _, frame := range id3v2Frames(tag) {
+			if isID3v2FooterGuard(frame) {
+				continue
+			}
+			if major == 4 && tag[5]&0x80 != 0 {
+				// unsynchronisation of the whole tag applies to every frame
+				frame.Flags |= 0x02
+			}
+			frames = append(frames, frame)
+		}
+	}
+
+	frames, err = edit(major, frames)
+	if err != nil {
+		return err
+	}
+
+	tag := renderID3v2Tag(major, frames, footer, 0)
+	if !footer {
+		padding := id3v2DefaultPadding
+		if size := int(loc.end - loc.start); size >= len(tag) {
+			padding = size - len(tag)
+		}
+		tag = renderID3v2Tag(major, frames, footer, padding)
+	}
+
+	switch {
+	case loc.form != nil && loc.chunk.id == "":
+		return loc.form.rewrite(f, loc.start, loc.end, loc.form.encode("ID3 ", tag))
+	case loc.form != nil:
+		return loc.form.rewrite(f, loc.chunk.offset, loc.chunk.end, loc.form.encode(loc.chunk.id, tag))
+	case loc.dsf:
+		if err := rewriteRange(f, loc.start, loc.end, tag); err != nil {
+			return err
+		}
+		var header [16]byte
+		binary.LittleEndian.PutUint64(header[:], uint64(loc.start)+uint64(len(tag)))
+		binary.LittleEndian.PutUint64(header[8:], uint64(loc.start))
+		if _, err := f.WriteAt(header[:], 12); err != nil {
+			return fmt.Errorf("write dsf header: %w", err)
+		}
+		return nil
+	}
+	return rewriteRange(f, loc.start, loc.end, tag)
+}
+
+// renderID3v2Tag renders frames as an ID3v2.3 or ID3v2.4 tag followed by padding, or by a footer instead if footer is
+// set, in which case the frames end with [id3v2FooterGuard].
+func renderID3v2Tag(major byte, frames []ID3v2Frame, footer bool, padding int) []byte {
+	tag := []byte{'I', 'D', '3', major, 0, 0, 0, 0, 0, 0}
+	for _, frame := range frames {
+		tag = append(tag, frame.ID...)
+		if major == 4 {
+			tag = append(tag, 0, 0, 0, 0)
+			putSyncsafe(tag[len(tag)-4:], uint32(len(frame.Data)))
+		} else {
+			tag = binary.BigEndian.AppendUint32(tag, uint32(len(frame.Data)))
+		}
+		tag = binary.BigEndian.AppendUint16(tag, frame.Flags)
+		tag = append(tag, frame.Data...)
+	}
+	if footer {
+		tag[5] |= 0x10
+		tag = append(tag, id3v2FooterGuard...)
+	} else {
+		tag = append(tag, make([]byte, padding)...)
+	}
+	putSyncsafe(tag[6:10], uint32(len(tag)-10))
+	if footer {
+		tag = append(tag, append([]byte("3DI"), tag[3:10]...)...)
+	}
+	return tag
+}
+
+func isID3v2FooterGuard(frame ID3v2Frame) bool {
+	return frame.ID == "PRIV" && frame.Flags == 0 && len(frame.Data) == 1 && frame.Data[0] == 0
+}
+
+// id3v2FrameID returns the ID3v2.3 or ID3v2.4 ID of a frame stored in a tag of version major.
+func id3v2FrameID(major byte, id string) string {
+	if major == 2 {
+		return id3v22Frames[id]
+	}
+	return id
+}
+
+// id3v2Encoding returns the text encoding TagLib writes values with in a tag of version major, UTF-8 for ID3v2.4, and
+// ISO-8859-1 for ID3v2.3, or UTF-16 with a BOM for text outside it.
+func id3v2Encoding(major byte, values ...string) byte {
+	if major >= 4 {
+		return 3
+	}
+	for _, v := range values {
+		for _, r := range v {
+			if r > 0xff {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// appendID3v2Text appends s to b in the given encoding, followed by a terminator if terminate is set.
+func appendID3v2Text(b []byte, encoding byte, s string, terminate bool) []byte {
+	switch encoding {
+	case 1:
+		text := encodeUTF16BOM(s)
+		if !terminate {
+			text = text[:len(text)-2]
+		}
+		return append(b, text...)
+	case 3:
+		b = append(b, s...)
+	default:
+		for _, r := range s {
+			b = append(b, byte(r))
+		}
+	}
+	if terminate {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// renderID3v2Texts renders values as the content of a text frame in a tag of version major.
+func renderID3v2Texts(major byte, values []string) []byte {
+	encoding := id3v2Encoding(major, values...)
+	data := []byte{encoding}
+	for i, v := range values {
+		data = appendID3v2Text(data, encoding, v, i < len(values)-1)
+	}
+	return data
+}
+
+// renderID3v2LanguageText renders t as the content of a COMM or USLT frame in a tag of version major.
+func renderID3v2LanguageText(major byte, t ID3v2LanguageText) []byte {
+	encoding := id3v2Encoding(major, t.Description, t.Text)
+	data := append([]byte{encoding}, cmp.Or(t.Language, "XXX")...)
+	data = appendID3v2Text(data, encoding, t.Description, true)
+	return appendID3v2Text(data, encoding, t.Text, false)
+}
+
+// id3v2CommentDescription returns the COMM frame description of a COMMENT or COMMENT:DESCRIPTION key.
+func id3v2CommentDescription(key string) (string, bool) {
+	if strings.EqualFold(key, Comment) {
+		return "", true
+	}
+	if len(key) > len(Comment)+1 && strings.EqualFold(key[:len(Comment)+1], Comment+":") {
+		return key[len(Comment)+1:], true
+	}
+	return "", false
+}
+
+// id3v2CommentsAfter returns the COMM frames a tag with comments has after tags are written, and whether they need
+// saving. TagLib keeps one value of one frame per description, so every comment key is written by Go instead, one
+// frame per value, reusing the description case and language of the first existing frame with that description.
+// Comments whose description isn't written are kept unless clear is set.
+func id3v2CommentsAfter(comments []ID3v2LanguageText, tags map[string][]string, clear bool) ([]ID3v2LanguageText, bool) {
+	written := map[string][]string{}
+	for k, vs := range tags {
+		if description, ok := id3v2CommentDescription(k); ok {
+			written[strings.ToUpper(description)] = append(written[strings.ToUpper(description)], vs...)
+		}
+	}
+	if len(comments) == 0 && len(written) == 0 {
+		return nil, false
+	}
+
+	var after []ID3v2LanguageText
+	for _, c := range comments {
+		if _, ok := written[strings.ToUpper(c.Description)]; !ok && !clear {
+			after = append(after, c)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		description, ok := id3v2CommentDescription(k)
+		if !ok {
+			continue
+		}
+		template := ID3v2LanguageText{Description: description}
+		if i := slices.IndexFunc(comments, func(c ID3v2LanguageText) bool {
+			return strings.EqualFold(c.Description, description)
+		}); i >= 0 {
+			template.Language, template.Description = comments[i].Language, comments[i].Description
+		}
+		for _, v := range tags[k] {
+			template.Text = v
+			after = append(after, template)
+		}
+	}
+	return after, true
+}
+
+// restoreID3v2Comments replaces the COMM frames of the ID3v2 tag of the file at path with comments once TagLib has
+// saved tags, and removes the TXXX frames TagLib writes comment keys with more than one value to.
+func restoreID3v2Comments(path string, comments []ID3v2LanguageText, tags map[string][]string) error {
+	return editID3v2Tag(path, func(major byte, frames []ID3v2Frame) ([]ID3v2Frame, error) {
+		frames = slices.DeleteFunc(frames, func(f ID3v2Frame) bool {
+			if f.ID == "COMM" {
+				return true
+			}
+			for k := range tags {
+				if _, ok := id3v2CommentDescription(k); ok && id3v2FrameHasName(major, f, "TXXX:"+k) {
+					return true
+				}
+			}
+			return false
+		})
+		for _, c := range comments {
+			frames = append(frames, ID3v2Frame{ID: "COMM", Data: renderID3v2LanguageText(major, c)})
+		}
+		return frames, nil
+	})
+}
@@ -0,0 +1,81 @@
+package taglib
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// Image is an embedded image with its data and metadata.
+type Image struct {
+	ImageDesc
+	// Data is the image data
+	Data []byte
+}
+
+// ReadImages reads every embedded image from a file at the given path, in the order of [Properties.Images], with the
+// dimensions and SHA-1 of each computed from its data. It returns an empty slice if there are no images.
+func ReadImages(path string) (_ []Image, err error) {
+	defer wrapErr(&err, "read images", path)
+	pictures, err := readPictures(path)
+	if err != nil {
+		return nil, err
+	}
+	images := make([]Image, 0, len(pictures))
+	for _, p := range pictures {
+		sum := sha1.Sum(p.Data)
+		width, height := imageSize(p.Data)
+		images = append(images, Image{
+			ImageDesc: ImageDesc{
+				Type:        string(p.Type),
+				Description: p.Description,
+				MIMEType:    p.MIMEType,
+				Width:       width,
+				Height:      height,
+				SHA1:        hex.EncodeToString(sum[:]),
+			},
+			Data: p.Data,
+		})
+	}
+	return images, nil
+}
+
+// imageSize reads the dimensions of PNG, JPEG, GIF, and WebP images from their headers. It returns 0 for other
+// formats.
+func imageSize(b []byte) (width, height uint) {
+	be16 := func(i int) uint { return uint(binary.BigEndian.Uint16(b[i:])) }
+	le16 := func(i int) uint { return uint(binary.LittleEndian.Uint16(b[i:])) }
+	le24 := func(i int) uint { return uint(b[i]) | uint(b[i+1])<<8 | uint(b[i+2])<<16 }
+
+	switch {
+	case len(b) >= 24 && bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) && string(b[12:16]) == "IHDR":
+		return uint(binary.BigEndian.Uint32(b[16:])), uint(binary.BigEndian.Uint32(b[20:]))
+	case len(b) >= 10 && (bytes.HasPrefix(b, []byte("GIF87a")) || bytes.HasPrefix(b, []byte("GIF89a"))):
+		return le16(6), le16(8)
+	case len(b) >= 30 && string(b[:4]) == "RIFF" && string(b[8:12]) == "WEBP":
+		switch string(b[12:16]) {
+		case "VP8 ":
+			return le16(26) & 0x3fff, le16(28) & 0x3fff
+		case "VP8L":
+			bits := le16(21) | le16(23)<<16
+			return bits&0x3fff + 1, bits>>14&0x3fff + 1
+		case "VP8X":
+			return le24(24) + 1, le24(27) + 1
+		}
+	case len(b) >= 4 && b[0] == 0xff && b[1] == 0xd8:
+		// walk the markers to the first start of frame
+		for i := 2; i+9 < len(b) && b[i] == 0xff; {
+			marker := b[i+1]
+			if marker == 0xff {
+				i++
+				continue
+			}
+			if marker >= 0xc0 && marker <= 0xcf && marker != 0xc4 && marker != 0xc8 && marker != 0xcc {
+				return be16(i + 7), be16(i + 5)
+			}
+			i += 2 + int(be16(i+2))
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,58 @@
+package taglib_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestReadImages(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	images, err := taglib.ReadImages(path)
+	nilErr(t, err)
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, len(images), len(properties.Images))
+	eq(t, len(images), 2)
+	for i, img := range images {
+		desc := properties.Images[i]
+		eq(t, img.Type, desc.Type)
+		eq(t, img.Description, desc.Description)
+		eq(t, img.MIMEType, desc.MIMEType)
+		data, err := taglib.ReadImageOptions(path, i)
+		nilErr(t, err)
+		eq(t, bytes.Equal(img.Data, data), true)
+		sum := sha1.Sum(data)
+		eq(t, img.SHA1, hex.EncodeToString(sum[:]))
+	}
+	eq(t, images[0].PictureType(), taglib.PictureFrontCover)
+	eq(t, images[0].Width, 700)
+	eq(t, images[0].Height, 700)
+	eq(t, images[1].PictureType(), taglib.PictureLeadArtist)
+	eq(t, images[1].MIMEType, "image/jpeg")
+	eq(t, images[1].Width, 640)
+	eq(t, images[1].Height, 640)
+
+	images, err = taglib.ReadImages(tmpf(t, egMP3, "eg.mp3"))
+	nilErr(t, err)
+	eq(t, len(images), 0)
+}
+
+func TestReadImagesError(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "missing.flac")
+	_, err := taglib.ReadImages(path)
+	var tagErr *taglib.Error
+	eq(t, errors.As(err, &tagErr), true)
+	eq(t, tagErr.Op, "read images")
+	eq(t, tagErr.Path, path)
+}
@@ -0,0 +1,124 @@
+package taglib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// initSnapshot is the linear memory of a module after _initialize has run its static constructors, kept as the
+// ranges which differ from a freshly instantiated module. wazero can't clone an instance, so each module is still
+// instantiated, but restoring the snapshot skips running the constructors, which is about a quarter of the cost of
+// creating a module. It relies on the constructors leaving the only global, the stack pointer, as they found it, and
+// making no calls to the host which later calls depend on, since WASI preopens are read on first use. Neither can be
+// seen from outside the module, but the memory they leave is checked against a module initialized the usual way when
+// the snapshot is taken.
+type initSnapshot struct {
+	// pages is the size of the memory in pages
+	pages uint32
+	// ranges are the bytes which differ from the data segments
+	ranges []initRange
+}
+
+type initRange struct {
+	offset uint32
+	data   []byte
+}
+
+// initRangeGap is the number of equal bytes which still join two changed ranges, saving a write for each.
+const initRangeGap = 64
+
+// takeInitSnapshot instantiates a module without running its start functions, runs _initialize, and keeps the memory
+// it changed. It returns nil for binaries without an _initialize export.
+func takeInitSnapshot(ctx context.Context, runtime wazero.Runtime, compiled wazero.CompiledModule) (*initSnapshot, error) {
+	if _, ok := compiled.ExportedFunctions()["_initialize"]; !ok {
+		return nil, nil
+	}
+	mod, err := runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions())
+	if err != nil {
+		return nil, fmt.Errorf("instantiate: %w", err)
+	}
+	defer mod.Close(ctx)
+
+	before, ok := mod.Memory().Read(0, mod.Memory().Size())
+	if !ok {
+		return nil, fmt.Errorf("read memory")
+	}
+	before = bytes.Clone(before)
+	if _, err := mod.ExportedFunction("_initialize").Call(ctx); err != nil {
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	after, ok := mod.Memory().Read(0, mod.Memory().Size())
+	if !ok {
+		return nil, fmt.Errorf("read memory")
+	}
+
+	snapshot := &initSnapshot{pages: uint32(len(after) / 65536), ranges: diffMemory(before, after)}
+
+	// constructors which read the clock, random numbers, or the environment would leave different memory each time,
+	// so check the snapshot against a module initialized the usual way, and run _initialize for each module if not
+	check, err := runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, fmt.Errorf("instantiate: %w", err)
+	}
+	defer check.Close(ctx)
+	want, ok := check.Memory().Read(0, check.Memory().Size())
+	if !ok {
+		return nil, fmt.Errorf("read memory")
+	}
+	if !bytes.Equal(snapshot.apply(before), want) {
+		return nil, nil
+	}
+	return snapshot, nil
+}
+
+// diffMemory returns the ranges of after which differ from before, joining changes fewer than initRangeGap bytes
+// apart. Memory past the end of before differs.
+func diffMemory(before, after []byte) []initRange {
+	var ranges []initRange
+	for i := 0; i < len(after); i++ {
+		if i < len(before) && after[i] == before[i] {
+			continue
+		}
+		end := i + 1
+		for equal := 0; end < len(after) && equal < initRangeGap; end++ {
+			if end < len(before) && after[end] == before[end] {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+		ranges = append(ranges, initRange{offset: uint32(i), data: bytes.Clone(after[i:end])})
+		i = end - 1
+	}
+	return ranges
+}
+
+// apply returns a copy of memory with the snapshot written over it, grown to its size.
+func (s *initSnapshot) apply(memory []byte) []byte {
+	out := make([]byte, max(len(memory), int(s.pages)*65536))
+	copy(out, memory)
+	for _, r := range s.ranges {
+		copy(out[r.offset:], r.data)
+	}
+	return out
+}
+
+// restore puts the memory of mod, a module instantiated without its start functions, in its initialized state.
+func (s *initSnapshot) restore(mod api.Module) error {
+	memory := mod.Memory()
+	if pages := memory.Size() / 65536; pages < s.pages {
+		if _, ok := memory.Grow(s.pages - pages); !ok {
+			return errMemoryLimit
+		}
+	}
+	for _, r := range s.ranges {
+		if !memory.Write(r.offset, r.data) {
+			return fmt.Errorf("write memory at %d", r.offset)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package taglib
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiffMemory(t *testing.T) {
+	t.Parallel()
+
+	for _, changed := range [][]int{
+		{0},
+		{10, 10 + 1 + initRangeGap}, // the byte right after a full gap of equal bytes
+		{10, 10 + initRangeGap},
+		{10, 10 + 2 + initRangeGap},
+		{299},
+	} {
+		before := make([]byte, 300)
+		after := bytes.Clone(before)
+		for _, i := range changed {
+			after[i] = 1
+		}
+
+		snapshot := initSnapshot{ranges: diffMemory(before, after)}
+		if got := snapshot.apply(before); !bytes.Equal(got, after) {
+			t.Errorf("changes at %v: restored memory differs", changed)
+		}
+	}
+
+	// memory which grew is kept whole
+	before := make([]byte, 8)
+	after := append(make([]byte, 8), 0, 0, 0)
+	ranges := diffMemory(before, after)
+	if len(ranges) != 1 || ranges[0].offset != 8 || len(ranges[0].data) != 3 {
+		t.Errorf("grown memory: got %v", ranges)
+	}
+}
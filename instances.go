@@ -0,0 +1,48 @@
+package taglib
+
+import "sync"
+
+// instances counts the modules in use, each of which holds its own linear memory for the length of a call.
+var instances = struct {
+	sync.Mutex
+	cond        *sync.Cond
+	active, max int
+}{}
+
+func init() { instances.cond = sync.NewCond(&instances.Mutex) }
+
+// SetMaxInstances bounds the number of files read or written at once to n, which bounds the memory used by the
+// package since each call instantiates its own module. Calls over the limit wait for a module to be closed. A limit
+// of 0, the default, means no limit.
+//
+// Modules are not pooled between calls, since each mounts the directory of the file it works on, so there are no idle
+// instances to tune. [WriteTagsBatch] keeps one module for the files of each directory it writes.
+func SetMaxInstances(n int) {
+	instances.Lock()
+	instances.max = max(n, 0)
+	instances.Unlock()
+	instances.cond.Broadcast()
+}
+
+// Instances returns the number of modules in use, one for each file being read or written.
+func Instances() int {
+	instances.Lock()
+	defer instances.Unlock()
+	return instances.active
+}
+
+func acquireInstance() {
+	instances.Lock()
+	for instances.max > 0 && instances.active >= instances.max {
+		instances.cond.Wait()
+	}
+	instances.active++
+	instances.Unlock()
+}
+
+func releaseInstance() {
+	instances.Lock()
+	instances.active--
+	instances.Unlock()
+	instances.cond.Signal()
+}
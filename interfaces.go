@@ -0,0 +1,49 @@
+package taglib
+
+// TagReader reads the tags of a file, like [ReadTags]. The interfaces of this package let applications swap the
+// files on disk for a fake in their tests, such as the one of package [go.senan.xyz/taglib/taglibtest].
+type TagReader interface {
+	ReadTags(path string) (map[string][]string, error)
+}
+
+// TagWriter writes the tags of a file, like [WriteTags].
+type TagWriter interface {
+	WriteTags(path string, tags map[string][]string, opts WriteOption) error
+}
+
+// PropertiesReader reads the audio properties of a file, like [ReadProperties].
+type PropertiesReader interface {
+	ReadProperties(path string) (Properties, error)
+}
+
+// ImageReader reads the first embedded image of a file, like [ReadImage].
+type ImageReader interface {
+	ReadImage(path string) ([]byte, error)
+}
+
+// ImageWriter writes the first embedded image of a file, like [WriteImage].
+type ImageWriter interface {
+	WriteImage(path string, image []byte) error
+}
+
+// Tagger is all of the interfaces of this package.
+type Tagger interface {
+	TagReader
+	TagWriter
+	PropertiesReader
+	ImageReader
+	ImageWriter
+}
+
+// Files is the [Tagger] of files on disk, calling the functions of this package.
+type Files struct{}
+
+var _ Tagger = Files{}
+
+func (Files) ReadTags(path string) (map[string][]string, error) { return ReadTags(path) }
+func (Files) WriteTags(path string, tags map[string][]string, opts WriteOption) error {
+	return WriteTags(path, tags, opts)
+}
+func (Files) ReadProperties(path string) (Properties, error) { return ReadProperties(path) }
+func (Files) ReadImage(path string) ([]byte, error)          { return ReadImage(path) }
+func (Files) WriteImage(path string, image []byte) error     { return WriteImage(path, image) }
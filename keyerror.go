@@ -0,0 +1,135 @@
+package taglib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// KeyError records a key of a write which couldn't be saved, while the other keys were. Writes with [WriteTags] report
+// each such key with its own KeyError, joined with [errors.Join] inside the [Error], so errors.As finds the first, and
+// the Unwrap() []error method of the joined error lists them all. Each unwraps to [ErrSavingFile].
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (e *KeyError) Error() string { return fmt.Sprintf("key %q: %v", e.Key, e.Err) }
+func (e *KeyError) Unwrap() error { return e.Err }
+
+// keyRule is the set of keys a format's tag can hold, which TagLib otherwise drops without an error.
+type keyRule uint8
+
+const (
+	keyRuleAny   keyRule = iota
+	keyRuleXiph          // Vorbis comments: ASCII 0x20 to 0x7D, except "="
+	keyRuleAPEv2         // APEv2: 2 to 255 of ASCII 0x20 to 0x7E
+)
+
+// readKeyRule finds the rule for the main tag of the file at path with [keyRuleOf].
+func readKeyRule(path string) keyRule {
+	f, err := os.Open(path)
+	if err != nil {
+		return keyRuleAny
+	}
+	defer f.Close()
+	return keyRuleOf(f)
+}
+
+// keyRuleOf finds the rule for the main tag of a file from its magic, after any leading ID3v2 tag.
+func keyRuleOf(f io.ReaderAt) keyRule {
+	start, err := id3v2TagSize(f)
+	if err != nil {
+		return keyRuleAny
+	}
+	var magic [4]byte
+	if _, err := f.ReadAt(magic[:], start); err != nil && err != io.EOF {
+		return keyRuleAny
+	}
+	switch string(magic[:]) {
+	case "fLaC", "OggS":
+		return keyRuleXiph
+	case "MAC ", "wvpk", "MPCK":
+		return keyRuleAPEv2
+	}
+	if string(magic[:3]) == "MP+" {
+		return keyRuleAPEv2
+	}
+	return keyRuleAny
+}
+
+// checkKey returns why key can't be written under the rule, or "" if it can.
+func (r keyRule) checkKey(key string) string {
+	switch {
+	case key == "":
+		return "empty key"
+	case strings.ContainsAny(key, "\t\v"):
+		return "key has a tab"
+	}
+	switch r {
+	case keyRuleXiph:
+		for _, c := range []byte(key) {
+			if c < 0x20 || c > 0x7d || c == '=' {
+				return "key isn't valid in vorbis comments"
+			}
+		}
+	case keyRuleAPEv2:
+		if len(key) < 2 || len(key) > 255 {
+			return "key isn't 2 to 255 bytes long for APEv2"
+		}
+		for _, c := range []byte(key) {
+			if c < 0x20 || c > 0x7e {
+				return "key isn't valid in APEv2"
+			}
+		}
+		switch key {
+		case "ID3", "TAG", "OGGS", "MP+":
+			return "key is reserved in APEv2"
+		}
+	}
+	return ""
+}
+
+// validTags folds the keys of tags unless they are case sensitive, and splits them with splitInvalidKeys. Every write
+// normalises its tags here, whether the file is on disk or in an [FS].
+func validTags(rule keyRule, tags map[string][]string, cfg writeConfig) (map[string][]string, []error) {
+	if !cfg.caseSensitive {
+		tags = foldKeys(tags)
+	}
+	return splitInvalidKeys(rule, tags)
+}
+
+// splitInvalidKeys returns the tags which a file with the key rule can hold, and a [KeyError] for each which it can't,
+// in the order of keys.
+func splitInvalidKeys(rule keyRule, tags map[string][]string) (map[string][]string, []error) {
+	var errs []error
+	valid := make(map[string][]string, len(tags))
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		reason := rule.checkKey(k)
+		if reason == "" {
+			for _, v := range tags[k] {
+				if strings.Contains(v, "\v") {
+					reason = "value has a vertical tab"
+				}
+			}
+		}
+		if reason != "" {
+			errs = append(errs, &KeyError{Key: k, Err: fmt.Errorf("%s: %w", reason, ErrSavingFile)})
+			continue
+		}
+		valid[k] = tags[k]
+	}
+	return valid, errs
+}
+
+// joinKeyErrors joins the errors of invalid keys with err from writing the others, leaving err as is if there are none.
+func joinKeyErrors(keyErrs []error, err error) error {
+	if len(keyErrs) == 0 {
+		return err
+	}
+	return errors.Join(append(keyErrs, err)...)
+}
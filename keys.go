@@ -0,0 +1,37 @@
+package taglib
+
+import "strings"
+
+// Key is a normalized tag key, such as [Title] or [Artist], as read by [ReadTags]. The key constants are untyped, so
+// they can be used as a Key in the maps of [ReadTagsKeyed] and [WriteTagsKeyed] and as a string in the maps of
+// [ReadTags] and [WriteTags]. Other strings have to be converted, with [NormalizeKey] for keys from user input.
+type Key string
+
+// NormalizeKey returns s as a Key, upper cased like the keys read by [ReadTags].
+func NormalizeKey(s string) Key {
+	return Key(strings.ToUpper(s))
+}
+
+// ReadTagsKeyed reads all metadata tags from an audio file at the given path, like [ReadTags], keyed by [Key].
+func ReadTagsKeyed(path string) (_ map[Key][]string, err error) {
+	defer wrapErr(&err, "read tags", path)
+	tags, err := readTags(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	keyed := make(map[Key][]string, len(tags))
+	for k, vs := range tags {
+		keyed[Key(k)] = vs
+	}
+	return keyed, nil
+}
+
+// WriteTagsKeyed writes the metadata key-values pairs to path, like [WriteTags], keyed by [Key].
+func WriteTagsKeyed(path string, tags map[Key][]string, opts WriteOption) (err error) {
+	defer wrapErr(&err, "write tags", path)
+	plain := make(map[string][]string, len(tags))
+	for k, vs := range tags {
+		plain[string(k)] = vs
+	}
+	return writeTags(path, plain, nil, newWriteConfig(opts))
+}
@@ -0,0 +1,29 @@
+package taglib_test
+
+import (
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestKeyed(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	err := taglib.WriteTagsKeyed(path, map[taglib.Key][]string{
+		taglib.Title:                  {"Title"},
+		taglib.NormalizeKey("custom"): {"Custom"},
+	}, taglib.Clear)
+	nilErr(t, err)
+
+	keyed, err := taglib.ReadTagsKeyed(path)
+	nilErr(t, err)
+	eq(t, len(keyed), 2)
+	eq(t, keyed[taglib.Title][0], "Title")
+	eq(t, keyed["CUSTOM"][0], "Custom")
+
+	// the constants still key plain maps
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, tags[taglib.Title][0], "Title")
+}
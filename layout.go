@@ -0,0 +1,299 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+)
+
+// TagRegion is where a block of metadata lives in a file.
+type TagRegion struct {
+	// Kind names the block: "ID3v2", "ID3v1", "APEv2", or "Lyrics3v2" for tags, a FLAC metadata block type such as
+	// "FLAC VORBIS_COMMENT" or "FLAC PADDING", or the path of an MP4 atom, "moov" or "moov.udta.meta.ilst"
+	Kind string
+	// Offset is the position of the block in the file, at the start of its header
+	Offset int64
+	// Length is the size of the block, including its headers, footers, and any padding inside the tag
+	Length int64
+}
+
+// ReadTagRegions reports the offset and length of each metadata block in the file at path, ordered by offset. It
+// covers an ID3v2 tag at the start of any file, the metadata blocks of FLAC files, the "moov" and "ilst" atoms of MP4
+// files, and the ID3v1, APEv2, and Lyrics3v2 tags at the end of FLAC, MPEG, and other files without a container.
+func ReadTagRegions(path string) (_ []TagRegion, err error) {
+	defer wrapErr(&err, "read tag regions", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+	size := info.Size()
+
+	start, err := id3v2TagSize(f)
+	if err != nil {
+		return nil, fmt.Errorf("read id3v2 header: %w", err)
+	}
+
+	var regions []TagRegion
+	if start > 0 {
+		regions = append(regions, TagRegion{Kind: "ID3v2", Offset: 0, Length: start})
+	}
+
+	var magic [16]byte
+	if _, err := f.ReadAt(magic[:], start); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read magic: %w", err)
+	}
+
+	trailing := true
+	switch {
+	case string(magic[:4]) == "fLaC":
+		blocks, err := readFLACBlocks(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range blocks {
+			regions = append(regions, TagRegion{Kind: "FLAC " + flacBlockName(block.typ), Offset: block.offset, Length: 4 + int64(block.length)})
+		}
+	case string(magic[4:8]) == "ftyp":
+		regions = append(regions, mp4TagRegions(f, size)...)
+		trailing = false
+	case slices.Contains([]string{"OggS", "RIFF", "FORM", "FRM8", "DSD "}, string(magic[:4])),
+		bytes.Equal(magic[:], asfHeaderGUID):
+		trailing = false // containers with their own metadata
+	}
+
+	if trailing {
+		tags, err := trailingTags(f, size)
+		if err != nil {
+			return nil, fmt.Errorf("read trailing tags: %w", err)
+		}
+		regions = append(regions, tags...)
+	}
+
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Offset < regions[j].Offset })
+	return regions, nil
+}
+
+// mp4TagRegions finds the "moov" atom of an MP4 file, and the "ilst" atom with the iTunes metadata inside it.
+func mp4TagRegions(r io.ReaderAt, end int64) []TagRegion {
+	var regions []TagRegion
+	for _, moov := range mp4Atoms(r, 0, end) {
+		if moov.typ != "moov" {
+			continue
+		}
+		regions = append(regions, TagRegion{Kind: "moov", Offset: moov.offset, Length: moov.size})
+		for _, udta := range mp4Atoms(r, moov.offset+moov.headerSize, moov.offset+moov.size) {
+			if udta.typ != "udta" {
+				continue
+			}
+			for _, meta := range mp4Atoms(r, udta.offset+udta.headerSize, udta.offset+udta.size) {
+				if meta.typ != "meta" {
+					continue
+				}
+				// meta is a full atom, with a version and flags before its children
+				for _, ilst := range mp4Atoms(r, meta.offset+meta.headerSize+4, meta.offset+meta.size) {
+					if ilst.typ == "ilst" {
+						regions = append(regions, TagRegion{Kind: "moov.udta.meta.ilst", Offset: ilst.offset, Length: ilst.size})
+					}
+				}
+			}
+		}
+	}
+	return regions
+}
+
+func flacBlockName(typ byte) string {
+	switch typ {
+	case flacBlockStreamInfo:
+		return "STREAMINFO"
+	case flacBlockPadding:
+		return "PADDING"
+	case flacBlockApplication:
+		return "APPLICATION"
+	case flacBlockSeekTable:
+		return "SEEKTABLE"
+	case flacBlockComment:
+		return "VORBIS_COMMENT"
+	case flacBlockCueSheet:
+		return "CUESHEET"
+	case flacBlockPicture:
+		return "PICTURE"
+	}
+	return fmt.Sprintf("RESERVED_%d", typ)
+}
+
+// ReadPadding reports the bytes of padding in the metadata area of the file at path, which a rewrite with less
+// padding would reclaim. It counts the padding after the frames of an ID3v2 tag at the start of any file, the PADDING
+// blocks of FLAC files including their headers, and the "free" and "skip" atoms of MP4 files at the top level and
+// around the metadata in "moov".
+func ReadPadding(path string) (_ int64, err error) {
+	defer wrapErr(&err, "read padding", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat: %w", err)
+	}
+
+	start, err := id3v2TagSize(f)
+	if err != nil {
+		return 0, fmt.Errorf("read id3v2 header: %w", err)
+	}
+
+	var padding int64
+	if start > 0 {
+		tag := make([]byte, start)
+		if _, err := f.ReadAt(tag, 0); err != nil {
+			return 0, fmt.Errorf("read id3v2 tag: %w", err)
+		}
+		padding += id3v2Padding(tag)
+	}
+
+	var magic [8]byte
+	if _, err := f.ReadAt(magic[:], start); err != nil && err != io.EOF {
+		return 0, fmt.Errorf("read magic: %w", err)
+	}
+
+	switch {
+	case string(magic[:4]) == "fLaC":
+		blocks, err := readFLACBlocks(f)
+		if err != nil {
+			return 0, err
+		}
+		for _, block := range blocks {
+			if block.typ == flacBlockPadding {
+				padding += 4 + int64(block.length)
+			}
+		}
+	case string(magic[4:]) == "ftyp":
+		padding += mp4Padding(f, 0, info.Size(), "moov", "udta", "meta")
+	}
+	return padding, nil
+}
+
+// mp4Padding sums the sizes of the "free" and "skip" atoms between offset and end, and in the atoms of path below.
+func mp4Padding(r io.ReaderAt, offset, end int64, path ...string) int64 {
+	var padding int64
+	for _, atom := range mp4Atoms(r, offset, end) {
+		switch {
+		case atom.typ == "free" || atom.typ == "skip":
+			padding += atom.size
+		case len(path) > 0 && atom.typ == path[0]:
+			children := atom.offset + atom.headerSize
+			if atom.typ == "meta" {
+				children += 4 // version and flags
+			}
+			padding += mp4Padding(r, children, atom.offset+atom.size, path[1:]...)
+		}
+	}
+	return padding
+}
+
+// setPadding resizes the padding counted by [ReadPadding] in an ID3v2 tag at the start of the file at path and in its
+// FLAC metadata blocks to n bytes, rewriting the file if the size changes. FLAC padding of less than a 4 byte block
+// header is rounded up. Tags with a footer are left as is, since they can't have padding.
+func setPadding(path string, n int64) error {
+	if err := setID3v2Padding(path, n); err != nil {
+		return fmt.Errorf("id3v2: %w", err)
+	}
+	if err := setFLACPadding(path, n); err != nil {
+		return fmt.Errorf("flac: %w", err)
+	}
+	return nil
+}
+
+func setID3v2Padding(path string, n int64) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	size, err := id3v2TagSize(f)
+	if err != nil || size == 0 {
+		return err
+	}
+	tag := make([]byte, size)
+	if _, err := f.ReadAt(tag, 0); err != nil {
+		return fmt.Errorf("read tag: %w", err)
+	}
+	if tag[5]&0x10 != 0 {
+		return nil
+	}
+	padding := id3v2Padding(tag)
+	if padding == n {
+		return nil
+	}
+
+	// padding is never unsynchronised, so it is the same size at the end of the raw tag
+	tag = append(tag[:size-padding], make([]byte, n)...)
+	if len(tag)-10 > 1<<28-1 {
+		return fmt.Errorf("tag of %d bytes too large: %w", len(tag), ErrSavingFile)
+	}
+	putSyncsafe(tag[6:10], uint32(len(tag)-10))
+	if tag[3] == 3 && tag[5]&0x40 != 0 && len(tag) >= 20 {
+		// the ID3v2.3 extended header has its size and flags, then the size of the padding
+		binary.BigEndian.PutUint32(tag[16:20], uint32(n))
+	}
+	return rewriteRange(f, 0, size, tag)
+}
+
+func setFLACPadding(path string, n int64) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	blocks, err := readFLACBlocks(f)
+	if errors.Is(err, ErrInvalidFile) {
+		return nil // not a FLAC file
+	}
+	if err != nil {
+		return err
+	}
+
+	var paddings []flacBlock
+	var datas []flacBlockData
+	for _, block := range blocks {
+		if block.typ == flacBlockPadding {
+			paddings = append(paddings, block)
+			continue
+		}
+		data, err := block.read(f)
+		if err != nil {
+			return fmt.Errorf("read block: %w", err)
+		}
+		datas = append(datas, flacBlockData{typ: block.typ, data: data})
+	}
+
+	last := blocks[len(blocks)-1]
+	if n > 0 {
+		length := max(n-4, 0)
+		if length > flacMaxBlockLength {
+			return fmt.Errorf("padding of %d bytes too large: %w", n, ErrSavingFile)
+		}
+		if len(paddings) == 1 && paddings[0] == last && int64(last.length) == length {
+			return nil
+		}
+		datas = append(datas, flacBlockData{typ: flacBlockPadding, data: make([]byte, length)})
+	} else if len(paddings) == 0 {
+		return nil
+	}
+
+	return rewriteRange(f, blocks[0].offset, last.offset+4+int64(last.length), encodeFLACBlocks(datas))
+}
@@ -0,0 +1,133 @@
+package taglib_test
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestReadTagRegions(t *testing.T) {
+	t.Parallel()
+
+	kinds := map[string][]string{
+		"eg.flac": {"FLAC STREAMINFO", "FLAC VORBIS_COMMENT"},
+		"eg.mp3":  {"ID3v2"},
+		"eg.m4a":  {"moov", "moov.udta.meta.ilst"},
+		"eg.mpc":  {"APEv2"},
+		"eg.wv":   {"APEv2"},
+		"eg.ape":  {"APEv2"},
+	}
+
+	for _, path := range append(testPaths(t), nichePaths(t)...) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, 0)
+			nilErr(t, err)
+
+			regions, err := taglib.ReadTagRegions(path)
+			nilErr(t, err)
+
+			info, err := os.Stat(path)
+			nilErr(t, err)
+
+			got := map[string]bool{}
+			for i, r := range regions {
+				got[r.Kind] = true
+				eq(t, r.Offset >= 0 && r.Length > 0 && r.Offset+r.Length <= info.Size(), true)
+				if i > 0 {
+					eq(t, r.Offset >= regions[i-1].Offset, true)
+				}
+			}
+			for _, kind := range kinds[filepath.Base(path)] {
+				eq(t, got[kind], true)
+			}
+		})
+	}
+}
+
+func TestReadTagRegionsTrailing(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	err := taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, 0)
+	nilErr(t, err)
+
+	// append an empty ID3v1 tag
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	nilErr(t, err)
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	_, err = f.Write(tag)
+	nilErr(t, err)
+	nilErr(t, f.Close())
+
+	info, err := os.Stat(path)
+	nilErr(t, err)
+
+	regions, err := taglib.ReadTagRegions(path)
+	nilErr(t, err)
+	last := regions[len(regions)-1]
+	eq(t, last, taglib.TagRegion{Kind: "ID3v1", Offset: info.Size() - 128, Length: 128})
+}
+
+func TestReadPadding(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range append(testPaths(t), nichePaths(t)...) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, 0)
+			nilErr(t, err)
+
+			padding, err := taglib.ReadPadding(path)
+			nilErr(t, err)
+
+			switch filepath.Base(path) {
+			case "eg.flac":
+				regions, err := taglib.ReadTagRegions(path)
+				nilErr(t, err)
+				var want int64
+				for _, r := range regions {
+					if r.Kind == "FLAC PADDING" {
+						want += r.Length
+					}
+				}
+				eq(t, padding, want)
+				eq(t, padding > 0, true)
+			case "eg.mp3", "eg.m4a":
+				eq(t, padding > 0, true)
+			case "eg.ape", "eg.wv", "eg.mpc":
+				eq(t, padding, int64(0))
+			}
+		})
+	}
+}
+
+func TestWriteCompact(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range append(testPaths(t), nichePaths(t)...) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			before := sha256.New()
+			nilErr(t, taglib.HashAudio(path, before))
+
+			err := taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, taglib.Compact)
+			nilErr(t, err)
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			eq(t, len(tags[taglib.Title]) > 0 && tags[taglib.Title][0] == "Title", true)
+
+			after := sha256.New()
+			nilErr(t, taglib.HashAudio(path, after))
+			eq(t, string(before.Sum(nil)), string(after.Sum(nil)))
+
+			if filepath.Base(path) != "eg.m4a" {
+				padding, err := taglib.ReadPadding(path)
+				nilErr(t, err)
+				eq(t, padding, int64(0))
+			}
+		})
+	}
+}
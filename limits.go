@@ -0,0 +1,323 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// ErrLimitExceeded is returned when a file or the memory needed to read or write it exceeds a limit set with
+// [SetLimits]. The error is a [*LimitError] reporting which limit.
+var ErrLimitExceeded = fmt.Errorf("limit exceeded")
+
+// LimitError is the error of a file which exceeds a limit set with [SetLimits]. It matches [ErrLimitExceeded].
+type LimitError struct {
+	// Limit is the name of the field of [Limits] which was exceeded, such as "MaxTagSize"
+	Limit string
+	// Kind names what exceeded it, such as "ID3v2 tag" or "picture"
+	Kind string
+	// Size is the size or count which exceeded the limit of Max, 0 for MaxMemory
+	Size, Max int64
+}
+
+func (e *LimitError) Error() string {
+	if e.Size == 0 {
+		return fmt.Sprintf("%s over %s: %v", e.Kind, e.Limit, ErrLimitExceeded)
+	}
+	return fmt.Sprintf("%s of %d over %s of %d: %v", e.Kind, e.Size, e.Limit, e.Max, ErrLimitExceeded)
+}
+
+func (e *LimitError) Unwrap() error { return ErrLimitExceeded }
+
+// Limits bounds the resources a file may use, so a corrupt or malicious file can't exhaust the memory of a server
+// which reads user provided files. A zero field means no limit.
+type Limits struct {
+	// MaxMemory bounds the linear memory of the module for each file in bytes. Calls which need more fail, even if
+	// TagLib would otherwise skip the data it couldn't allocate. A write which fails part way may leave the file
+	// partly written, which [WithAtomicRename] avoids
+	MaxMemory uint64
+	// MaxTagSize bounds the size of each tag, as reported by [ReadTagRegions]. FLAC padding and the MP4 "moov" atom,
+	// which holds the sample tables, aren't counted
+	MaxTagSize int64
+	// MaxPictureSize bounds the size of each picture in ID3v2 APIC frames, FLAC PICTURE blocks, and MP4 "covr" atoms,
+	// and of the images written with [WriteImageOptions] and [WritePictures]
+	MaxPictureSize int64
+	// MaxFrames bounds the number of frames in an ID3v2 tag
+	MaxFrames int
+	// MaxStringLength bounds the size in bytes of each text value, in ID3v2 text, URL, comment, and lyrics frames,
+	// FLAC and Ogg Vorbis comments, and MP4 items other than "covr"
+	MaxStringLength int64
+}
+
+var limits atomic.Pointer[Limits]
+
+// SetLimits sets the limits checked by every later read and write, which fail with [ErrLimitExceeded] when a file
+// exceeds them. The sizes of tags, pictures, and frames are checked before TagLib reads the file. The zero Limits, the
+// default, means no limits.
+func SetLimits(l Limits) {
+	limits.Store(&l)
+}
+
+func currentLimits() Limits {
+	if l := limits.Load(); l != nil {
+		return *l
+	}
+	return Limits{}
+}
+
+// checkLimits checks the file at path against the tag, picture, and frame limits. Files which can't be read are left
+// for TagLib to report.
+func checkLimits(path string, l Limits) error {
+	if l.MaxTagSize == 0 && l.MaxPictureSize == 0 && l.MaxFrames == 0 && l.MaxStringLength == 0 {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if l.MaxTagSize > 0 {
+		regions, _ := ReadTagRegions(path)
+		for _, r := range regions {
+			if r.Kind == "FLAC PADDING" || r.Kind == "moov" {
+				continue
+			}
+			if r.Length > l.MaxTagSize {
+				return &LimitError{Limit: "MaxTagSize", Kind: r.Kind + " tag", Size: r.Length, Max: l.MaxTagSize}
+			}
+		}
+	}
+
+	if err := checkID3v2Frames(f, l); err != nil {
+		return err
+	}
+
+	if l.MaxPictureSize == 0 && l.MaxStringLength == 0 {
+		return nil
+	}
+	if blocks, err := readFLACBlocks(f); err == nil {
+		for _, block := range blocks {
+			switch block.typ {
+			case flacBlockPicture:
+				if err := checkPictureSize(l, int64(block.length)); err != nil {
+					return err
+				}
+			case flacBlockComment:
+				if l.MaxStringLength == 0 {
+					continue
+				}
+				comments := io.NewSectionReader(f, block.offset+4, int64(block.length))
+				if err := checkStringLength(l, longestVorbisComment(comments)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if packets := oggHeaderPackets(f, 2); len(packets) >= 2 && l.MaxStringLength > 0 {
+		comments := packets[1]
+		switch {
+		case bytes.HasPrefix(comments, []byte("\x03vorbis")):
+			comments = comments[7:]
+		case bytes.HasPrefix(comments, []byte("OpusTags")):
+			comments = comments[8:]
+		default:
+			comments = nil
+		}
+		if err := checkStringLength(l, longestVorbisComment(io.NewSectionReader(bytes.NewReader(comments), 0, int64(len(comments))))); err != nil {
+			return err
+		}
+	}
+	info, err := f.Stat()
+	if err != nil || !hasAt(f, 4, "ftyp") {
+		return nil
+	}
+	for _, r := range mp4TagRegions(f, info.Size()) {
+		if !strings.HasSuffix(r.Kind, ".ilst") {
+			continue
+		}
+		for _, item := range mp4Atoms(f, r.Offset+8, r.Offset+r.Length) {
+			size := item.size - item.headerSize
+			if item.typ == "covr" {
+				err = checkPictureSize(l, size)
+			} else {
+				err = checkStringLength(l, size)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkID3v2Frames checks the frames of the ID3v2 tag of f against the frame, picture, and string limits. Only the
+// frame headers are read, so a tag claiming more than the file holds costs no more than the frames it has. Tags
+// unsynchronised as a whole, whose frame headers can't be found without decoding, are read up to the end of the file.
+func checkID3v2Frames(f *os.File, l Limits) error {
+	loc, err := locateID3v2Tag(f, "")
+	if err != nil || loc.end == loc.start {
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	var header [10]byte
+	if _, err := f.ReadAt(header[:], loc.start); err != nil || !isID3v2Header(header[:]) {
+		return nil
+	}
+	major, flags := header[3], header[5]
+
+	count := 0
+	check := func(id string, size int64) error {
+		count++
+		if l.MaxFrames > 0 && count > l.MaxFrames {
+			return &LimitError{Limit: "MaxFrames", Kind: "ID3v2 frame count", Size: int64(count), Max: int64(l.MaxFrames)}
+		}
+		switch {
+		case id == "APIC" || id == "PIC":
+			return checkPictureSize(l, size)
+		case id3v2TextFrame(id):
+			return checkStringLength(l, size)
+		}
+		return nil
+	}
+
+	if major < 4 && flags&0x80 != 0 {
+		tag, _, err := readID3v2Tag(f)
+		if err != nil {
+			return nil
+		}
+		for _, frame := range id3v2Frames(tag) {
+			if err := check(frame.ID, int64(len(frame.Data))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	pos, end := loc.start+10, loc.end
+	if major >= 4 && flags&0x10 != 0 {
+		end -= 10 // footer
+	}
+	end = min(end, info.Size())
+	if major > 2 && flags&0x40 != 0 {
+		// the ID3v2.4 extended header size includes itself, the ID3v2.3 one doesn't
+		var size [4]byte
+		if _, err := f.ReadAt(size[:], pos); err != nil {
+			return nil
+		}
+		if major == 4 {
+			pos += int64(syncsafe(size[:]))
+		} else {
+			pos += 4 + int64(binary.BigEndian.Uint32(size[:]))
+		}
+	}
+	frameHeader := make([]byte, id3v2FrameHeaderSize(major))
+	for pos+int64(len(frameHeader)) <= end {
+		if _, err := f.ReadAt(frameHeader, pos); err != nil || frameHeader[0] == 0 {
+			break
+		}
+		id, size, _ := id3v2FrameHeader(major, frameHeader)
+		pos += int64(len(frameHeader))
+		if int64(size) > end-pos {
+			break
+		}
+		if err := check(id, int64(size)); err != nil {
+			return err
+		}
+		pos += int64(size)
+	}
+	return nil
+}
+
+func checkPictureSize(l Limits, size int64) error {
+	if l.MaxPictureSize > 0 && size > l.MaxPictureSize {
+		return &LimitError{Limit: "MaxPictureSize", Kind: "picture", Size: size, Max: l.MaxPictureSize}
+	}
+	return nil
+}
+
+func checkStringLength(l Limits, size int64) error {
+	if l.MaxStringLength > 0 && size > l.MaxStringLength {
+		return &LimitError{Limit: "MaxStringLength", Kind: "string", Size: size, Max: l.MaxStringLength}
+	}
+	return nil
+}
+
+// id3v2TextFrame reports whether frames with the ID hold text: text, URL, comment, and lyrics frames.
+func id3v2TextFrame(id string) bool {
+	switch id {
+	case "COMM", "USLT", "COM", "ULT":
+		return true
+	}
+	return strings.HasPrefix(id, "T") || strings.HasPrefix(id, "W")
+}
+
+// longestVorbisComment returns the size of the longest comment of a Vorbis comment block, up to where it is cut off.
+// Only the sizes are read, so a comment claiming more than the block holds costs nothing.
+func longestVorbisComment(r *io.SectionReader) int64 {
+	var size [4]byte
+	if _, err := r.ReadAt(size[:], 0); err != nil {
+		return 0
+	}
+	vendor := int64(binary.LittleEndian.Uint32(size[:]))
+	longest := vendor
+	pos := 4 + vendor
+	if _, err := r.ReadAt(size[:], pos); err != nil {
+		return longest
+	}
+	count := binary.LittleEndian.Uint32(size[:])
+	pos += 4
+	for range count {
+		if _, err := r.ReadAt(size[:], pos); err != nil {
+			break
+		}
+		n := int64(binary.LittleEndian.Uint32(size[:]))
+		longest = max(longest, n)
+		pos += 4 + n
+	}
+	return longest
+}
+
+// limitedMemory backs the linear memory of a module, failing to grow past max. TagLib's allocations fail when the
+// memory can't grow, which exceeded records so the call can report [ErrLimitExceeded].
+type limitedMemory struct {
+	buf      []byte
+	max      uint64
+	exceeded bool
+}
+
+func newMemoryAllocator(mem *limitedMemory) experimental.MemoryAllocator {
+	return experimental.MemoryAllocatorFunc(func(_, limit uint64) experimental.LinearMemory {
+		mem.max = min(mem.max, limit)
+		return mem
+	})
+}
+
+func (m *limitedMemory) Reallocate(size uint64) []byte {
+	// the initial memory of the module is always allocated, since instantiation can't fail gracefully
+	if size > m.max && m.buf != nil {
+		m.exceeded = true
+		return nil
+	}
+	if size <= uint64(cap(m.buf)) {
+		m.buf = m.buf[:size]
+		return m.buf
+	}
+	buf := make([]byte, size, max(size, min(uint64(cap(m.buf))*2, m.max)))
+	copy(buf, m.buf)
+	m.buf = buf
+	return buf
+}
+
+func (m *limitedMemory) Free() {
+	m.buf = nil
+}
@@ -0,0 +1,55 @@
+package taglib
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckLimitsClaimedSize(t *testing.T) {
+	// not parallel, the allocations are measured for the whole program
+
+	mp3, err := os.ReadFile("testdata/eg.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an ID3v2 tag claiming 256 MiB
+	copy(mp3[6:10], []byte{0x7f, 0x7f, 0x7f, 0x7f})
+
+	flac, err := os.ReadFile("testdata/eg.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a last VORBIS_COMMENT block claiming 16 MiB, cut off after its vendor string
+	i := bytes.Index(flac, []byte("Lavf61.1.100"))
+	flac = flac[:i+len("Lavf61.1.100")]
+	flac[i-8] |= 0x80
+	copy(flac[i-7:i-4], []byte{0xff, 0xff, 0xff})
+
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		limits Limits
+	}{
+		{"eg.mp3", mp3, Limits{MaxFrames: 100}},
+		{"eg.mp3", mp3, Limits{MaxStringLength: 1 << 10}},
+		{"eg.flac", flac, Limits{MaxStringLength: 1 << 10}},
+	} {
+		path := filepath.Join(t.TempDir(), tc.name)
+		if err := os.WriteFile(path, tc.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if err := checkLimits(path, tc.limits); err != nil {
+			t.Fatal(err)
+		}
+		runtime.ReadMemStats(&after)
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+			t.Fatalf("%s: allocated %d bytes for a file of %d", tc.name, alloc, len(tc.data))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package taglib_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestLimits(t *testing.T) {
+	// not parallel, the limits are global
+	t.Cleanup(func() { taglib.SetLimits(taglib.Limits{}) })
+
+	flac := tmpf(t, egFLAC, "eg.flac")
+	mp3 := tmpf(t, egMP3, "eg.mp3")
+	nilErr(t, taglib.WriteTags(mp3, map[string][]string{taglib.Title: {"title"}, taglib.Artist: {"artist"}}, 0))
+	long := map[string][]string{taglib.Title: {strings.Repeat("long title ", 10)}}
+	ogg := tmpf(t, egOgg, "eg.ogg")
+	nilErr(t, taglib.WriteTags(ogg, long, 0))
+	m4a := tmpf(t, egM4a, "eg.m4a")
+	nilErr(t, taglib.WriteTags(m4a, long, 0))
+
+	for _, tc := range []struct {
+		name   string
+		limits taglib.Limits
+		path   string
+		limit  string
+	}{
+		{"tag size", taglib.Limits{MaxTagSize: 16}, flac, "MaxTagSize"},
+		{"picture size", taglib.Limits{MaxPictureSize: 16}, flac, "MaxPictureSize"},
+		{"frames", taglib.Limits{MaxFrames: 1}, mp3, "MaxFrames"},
+		{"memory", taglib.Limits{MaxMemory: 1 << 20}, flac, "MaxMemory"},
+		{"flac string", taglib.Limits{MaxStringLength: 4}, flac, "MaxStringLength"},
+		{"id3v2 string", taglib.Limits{MaxStringLength: 4}, mp3, "MaxStringLength"},
+		{"ogg string", taglib.Limits{MaxStringLength: 64}, ogg, "MaxStringLength"},
+		{"mp4 string", taglib.Limits{MaxStringLength: 64}, m4a, "MaxStringLength"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			taglib.SetLimits(tc.limits)
+			_, err := taglib.ReadTags(tc.path)
+			if !errors.Is(err, taglib.ErrLimitExceeded) {
+				t.Fatalf("expected limit error, got %v", err)
+			}
+			var limitErr *taglib.LimitError
+			eq(t, errors.As(err, &limitErr), true)
+			eq(t, limitErr.Limit, tc.limit)
+
+			taglib.SetLimits(taglib.Limits{MaxTagSize: 1 << 20, MaxPictureSize: 1 << 20, MaxFrames: 16, MaxMemory: 64 << 20, MaxStringLength: 1 << 10})
+			_, err = taglib.ReadTags(tc.path)
+			nilErr(t, err)
+		})
+	}
+
+	// images larger than the memory limit fail while being copied into the module
+	taglib.SetLimits(taglib.Limits{MaxMemory: 8 << 20})
+	err := taglib.WriteImage(tmpf(t, egFLAC, "eg.flac"), bytes.Repeat([]byte{1}, 10<<20))
+	if !errors.Is(err, taglib.ErrLimitExceeded) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+
+	taglib.SetLimits(taglib.Limits{MaxPictureSize: 16})
+	err = taglib.WriteImage(tmpf(t, egFLAC, "eg.flac"), coverJPG)
+	if !errors.Is(err, taglib.ErrLimitExceeded) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package taglib
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// listenerFactory is the factory set with [SetFunctionListenerFactory], nil if there is none.
+var listenerFactory experimental.FunctionListenerFactory
+
+// SetFunctionListenerFactory sets a wazero function listener factory, which is notified of every call to a function
+// in the Wasm module. It can trace the calls TagLib makes while parsing a pathological file, for example with the
+// factory from wazero's experimental/logging package, or sample them into a profile. Listeners are compiled into the
+// module, which makes every call slower, so this is meant for debugging.
+//
+// The factory must be set before the first read or write compiles the module, and it fails after.
+func SetFunctionListenerFactory(factory experimental.FunctionListenerFactory) error {
+	runtimeState.Lock()
+	defer runtimeState.Unlock()
+	if runtimeState.rc.Load() != nil {
+		return fmt.Errorf("set function listener factory: module already compiled")
+	}
+	listenerFactory = factory
+	return nil
+}
@@ -0,0 +1,241 @@
+package taglib
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// SyncedLyrics is lyrics with a time for each line, as stored in ID3v2 SYLT frames and LRC files.
+type SyncedLyrics struct {
+	// Language is the ISO-639-2 code of the lyrics, such as "eng". "XXX" marks an unknown language
+	Language string
+	// Description is a short description of the lyrics, often empty
+	Description string
+	// Lines are the lines of the lyrics in time order
+	Lines []SyncedLine
+}
+
+// SyncedLine is a line of [SyncedLyrics].
+type SyncedLine struct {
+	// Time is the time from the start of the audio when the line is sung
+	Time time.Duration
+	// Text is the text of the line
+	Text string
+}
+
+// ReadID3v2SyncedLyrics reads all SYLT frames with millisecond timestamps from the ID3v2 tag of a file at the given
+// path, in tag order. Frames timed in MPEG frames, compressed, or encrypted are skipped. Formats without an ID3v2 tag
+// return no lyrics.
+func ReadID3v2SyncedLyrics(path string) (_ []SyncedLyrics, err error) {
+	defer wrapErr(&err, "read id3v2 synced lyrics", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	tag, _, err := readID3v2Tag(f)
+	if err != nil {
+		return nil, err
+	}
+	major, body, ok := id3v2Body(tag)
+	if !ok {
+		return nil, nil
+	}
+
+	var lyrics []SyncedLyrics
+	headerSize := id3v2FrameHeaderSize(major)
+	for len(body) >= headerSize && body[0] != 0 {
+		id, size, format := id3v2FrameHeader(major, body)
+		if size > len(body)-headerSize {
+			break
+		}
+		data, ok := id3v2FrameContent(major, format, body[headerSize:headerSize+size])
+		body = body[headerSize+size:]
+		if !ok || (id != "SYLT" && id != "SLT") {
+			continue
+		}
+		l, err := ParseSYLT(data)
+		if err != nil {
+			continue
+		}
+		lyrics = append(lyrics, l)
+	}
+	return lyrics, nil
+}
+
+// ParseSYLT decodes the content of an ID3v2 SYLT frame, such as the Data of an [ID3v2Frame]. Only timestamps in
+// milliseconds are supported.
+func ParseSYLT(data []byte) (SyncedLyrics, error) {
+	if len(data) < 6 {
+		return SyncedLyrics{}, fmt.Errorf("sylt frame too short: %w", ErrInvalidFile)
+	}
+	encoding := data[0]
+	if data[4] != 2 {
+		return SyncedLyrics{}, fmt.Errorf("sylt timestamp format %d: %w", data[4], errors.ErrUnsupported)
+	}
+	lyrics := SyncedLyrics{Language: string(data[1:4])}
+
+	var rest []byte
+	lyrics.Description, rest = splitID3v2Text(encoding, data[6:])
+	for len(rest) > 0 {
+		var text string
+		text, rest = splitID3v2Text(encoding, rest)
+		if len(rest) < 4 {
+			break
+		}
+		ms := binary.BigEndian.Uint32(rest)
+		rest = rest[4:]
+		lyrics.Lines = append(lyrics.Lines, SyncedLine{Time: time.Duration(ms) * time.Millisecond, Text: text})
+	}
+	return lyrics, nil
+}
+
+// SYLT encodes the lyrics as the content of an ID3v2 SYLT frame of lyrics with millisecond timestamps. The text is
+// UTF-16, which both ID3v2.3 and ID3v2.4 support. An empty Language is written as "XXX".
+func (s SyncedLyrics) SYLT() []byte {
+	language := s.Language
+	if len(language) != 3 {
+		language = "XXX"
+	}
+	var b bytes.Buffer
+	b.WriteByte(1) // UTF-16 with a BOM
+	b.WriteString(language)
+	b.WriteByte(2) // milliseconds
+	b.WriteByte(1) // lyrics
+	b.Write(encodeUTF16BOM(s.Description))
+	for _, line := range s.Lines {
+		b.Write(encodeUTF16BOM(line.Text))
+		b.Write(binary.BigEndian.AppendUint32(nil, uint32(line.Time.Milliseconds())))
+	}
+	return b.Bytes()
+}
+
+// ParseLRC parses lyrics in the LRC format, where each line starts with one or more "[mm:ss.xx]" time tags. Lines are
+// sorted by time, and the "[offset:ms]" tag is applied to them. Other ID tags, such as "[ar:Artist]", and lines without
+// a time tag are ignored.
+func ParseLRC(text string) (SyncedLyrics, error) {
+	var lyrics SyncedLyrics
+	var offset time.Duration
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		var times []time.Duration
+		for strings.HasPrefix(line, "[") {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				break
+			}
+			tag := line[1:end]
+			line = line[end+1:]
+
+			if name, value, ok := strings.Cut(tag, ":"); ok && !isDigits(name) {
+				if name == "offset" {
+					ms, err := strconv.Atoi(strings.TrimSpace(value))
+					if err != nil {
+						return SyncedLyrics{}, fmt.Errorf("line %d: parse offset %q: %w", n, value, err)
+					}
+					offset = time.Duration(ms) * time.Millisecond
+				}
+				continue
+			}
+			t, err := parseLRCTime(tag)
+			if err != nil {
+				return SyncedLyrics{}, fmt.Errorf("line %d: %w", n, err)
+			}
+			times = append(times, t)
+		}
+		for _, t := range times {
+			lyrics.Lines = append(lyrics.Lines, SyncedLine{Time: t, Text: strings.TrimSpace(line)})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return SyncedLyrics{}, err
+	}
+
+	// a positive offset shows the lyrics sooner
+	for i := range lyrics.Lines {
+		lyrics.Lines[i].Time = max(lyrics.Lines[i].Time-offset, 0)
+	}
+	slices.SortStableFunc(lyrics.Lines, func(a, b SyncedLine) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	return lyrics, nil
+}
+
+// LRC formats the lyrics in the LRC format, with a "[mm:ss.xx]" time tag for each line.
+func (s SyncedLyrics) LRC() string {
+	var b strings.Builder
+	for _, line := range s.Lines {
+		cs := line.Time.Milliseconds() / 10
+		fmt.Fprintf(&b, "[%02d:%02d.%02d]%s\n", cs/6000, cs/100%60, cs%100, line.Text)
+	}
+	return b.String()
+}
+
+// parseLRCTime parses a time tag of minutes, seconds, and an optional fraction of a second, separated by a "." or ":".
+func parseLRCTime(tag string) (time.Duration, error) {
+	minutes, rest, ok := strings.Cut(tag, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time tag %q", tag)
+	}
+	seconds, fraction, _ := strings.Cut(strings.Replace(rest, ":", ".", 1), ".")
+	if !isDigits(minutes) || !isDigits(seconds) || (fraction != "" && !isDigits(fraction)) || len(fraction) > 3 {
+		return 0, fmt.Errorf("invalid time tag %q", tag)
+	}
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	t := time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if fraction != "" {
+		f, _ := strconv.Atoi(fraction + strings.Repeat("0", 3-len(fraction)))
+		t += time.Duration(f) * time.Millisecond
+	}
+	return t, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitID3v2Text decodes a terminated string with the given encoding byte from the start of data, returning the rest.
+func splitID3v2Text(encoding byte, data []byte) (string, []byte) {
+	if encoding == 1 || encoding == 2 { // UTF-16, terminated by a zero code unit
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return decodeID3v2Text(encoding, data[:i]), data[i+2:]
+			}
+		}
+		return decodeID3v2Text(encoding, data), nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return decodeID3v2Text(encoding, data[:i]), data[i+1:]
+	}
+	return decodeID3v2Text(encoding, data), nil
+}
+
+// encodeUTF16BOM encodes s as terminated little endian UTF-16 with a BOM.
+func encodeUTF16BOM(s string) []byte {
+	b := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return append(b, 0, 0)
+}
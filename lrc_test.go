@@ -0,0 +1,65 @@
+package taglib_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestParseLRC(t *testing.T) {
+	t.Parallel()
+
+	lyrics, err := taglib.ParseLRC("[ar:Artist]\n[offset:+500]\n\n[00:12.34]first\n[01:02.5][00:20.00] chorus \n[00:15:01]second\nuntimed\n")
+	nilErr(t, err)
+	eq(t, slices.Equal(lyrics.Lines, []taglib.SyncedLine{
+		{Time: 11840 * time.Millisecond, Text: "first"},
+		{Time: 14510 * time.Millisecond, Text: "second"},
+		{Time: 19500 * time.Millisecond, Text: "chorus"},
+		{Time: 62 * time.Second, Text: "chorus"},
+	}), true)
+
+	eq(t, lyrics.LRC(), "[00:11.84]first\n[00:14.51]second\n[00:19.50]chorus\n[01:02.00]chorus\n")
+
+	_, err = taglib.ParseLRC("[00:1x.00]bad")
+	eq(t, err != nil, true)
+}
+
+func TestSYLT(t *testing.T) {
+	t.Parallel()
+
+	lyrics := taglib.SyncedLyrics{
+		Language:    "eng",
+		Description: "desc",
+		Lines: []taglib.SyncedLine{
+			{Time: 0, Text: "first"},
+			{Time: 1500 * time.Millisecond, Text: "second 🎵"},
+		},
+	}
+
+	got, err := taglib.ParseSYLT(lyrics.SYLT())
+	nilErr(t, err)
+	eq(t, got.Language, lyrics.Language)
+	eq(t, got.Description, lyrics.Description)
+	eq(t, slices.Equal(got.Lines, lyrics.Lines), true)
+
+	// an ID3v2.3 tag with a SYLT frame
+	frame := lyrics.SYLT()
+	tag := []byte("ID3\x03\x00\x00\x00\x00\x00\x00")
+	tag[9] = byte(10 + len(frame))
+	tag = append(tag, "SYLT\x00\x00\x00\x00\x00\x00"...)
+	tag[17] = byte(len(frame))
+	tag = append(tag, frame...)
+
+	path := tmpf(t, tag, "eg.mp3")
+	read, err := taglib.ReadID3v2SyncedLyrics(path)
+	nilErr(t, err)
+	eq(t, len(read), 1)
+	eq(t, read[0].Language, "eng")
+	eq(t, slices.Equal(read[0].Lines, lyrics.Lines), true)
+
+	read, err = taglib.ReadID3v2SyncedLyrics(tmpf(t, egFLAC, "eg.flac"))
+	nilErr(t, err)
+	eq(t, len(read), 0)
+}
@@ -0,0 +1,496 @@
+package taglib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// KeyMapping maps a tag key to the exact native names it is stored under, instead of TagLib's own [property mapping].
+//
+// ID3v2 is a frame ID like "TIT1", or "TXXX:Description" for a user text frame. MP4 is an atom name like "©grp", or
+// "----:mean:name" for a freeform atom. TXXX descriptions and freeform names are matched case insensitively when
+// reading and replacing. Integer and boolean atoms like "cnID" or "cpil" are read as decimal text and written from
+// the first value. An empty name leaves that format to TagLib.
+//
+// [property mapping]: https://taglib.org/api/p_propertymapping.html
+type KeyMapping struct {
+	Key   string
+	ID3v2 string
+	MP4   string
+}
+
+// Mapping is a set of [KeyMapping] used by [ReadTagsMapping] and [WriteTagsMapping].
+type Mapping []KeyMapping
+
+// MappingPicard uses the TXXX descriptions and MP4 freeform atoms written by MusicBrainz Picard, so identifiers
+// round-trip between Picard and this package without being duplicated under a differently cased name.
+var MappingPicard = Mapping{
+	{Key: MusicBrainzAlbumID, ID3v2: "TXXX:MusicBrainz Album Id", MP4: "----:com.apple.iTunes:MusicBrainz Album Id"},
+	{Key: MusicBrainzAlbumArtistID, ID3v2: "TXXX:MusicBrainz Album Artist Id", MP4: "----:com.apple.iTunes:MusicBrainz Album Artist Id"},
+	{Key: MusicBrainzArtistID, ID3v2: "TXXX:MusicBrainz Artist Id", MP4: "----:com.apple.iTunes:MusicBrainz Artist Id"},
+	{Key: MusicBrainzReleaseGroupID, ID3v2: "TXXX:MusicBrainz Release Group Id", MP4: "----:com.apple.iTunes:MusicBrainz Release Group Id"},
+	{Key: MusicBrainzReleaseTrackID, ID3v2: "TXXX:MusicBrainz Release Track Id", MP4: "----:com.apple.iTunes:MusicBrainz Release Track Id"},
+	// ID3v2 stores the recording ID in a UFID frame, which TagLib already maps
+	{Key: MusicBrainzTrackID, MP4: "----:com.apple.iTunes:MusicBrainz Track Id"},
+	{Key: MusicBrainzWorkID, ID3v2: "TXXX:MusicBrainz Work Id", MP4: "----:com.apple.iTunes:MusicBrainz Work Id"},
+	{Key: "MUSICBRAINZ_DISCID", ID3v2: "TXXX:MusicBrainz Disc Id", MP4: "----:com.apple.iTunes:MusicBrainz Disc Id"},
+	{Key: "MUSICBRAINZ_ORIGINALALBUMID", ID3v2: "TXXX:MusicBrainz Original Album Id", MP4: "----:com.apple.iTunes:MusicBrainz Original Album Id"},
+	{Key: "MUSICBRAINZ_ORIGINALARTISTID", ID3v2: "TXXX:MusicBrainz Original Artist Id", MP4: "----:com.apple.iTunes:MusicBrainz Original Artist Id"},
+	{Key: ReleaseCountry, ID3v2: "TXXX:MusicBrainz Album Release Country", MP4: "----:com.apple.iTunes:MusicBrainz Album Release Country"},
+	{Key: ReleaseStatus, ID3v2: "TXXX:MusicBrainz Album Status", MP4: "----:com.apple.iTunes:MusicBrainz Album Status"},
+	{Key: ReleaseType, ID3v2: "TXXX:MusicBrainz Album Type", MP4: "----:com.apple.iTunes:MusicBrainz Album Type"},
+	{Key: AcoustIDID, ID3v2: "TXXX:Acoustid Id", MP4: "----:com.apple.iTunes:Acoustid Id"},
+	{Key: AcoustIDFingerprint, ID3v2: "TXXX:Acoustid Fingerprint", MP4: "----:com.apple.iTunes:Acoustid Fingerprint"},
+	{Key: MusicIPPUID, ID3v2: "TXXX:MusicIP PUID", MP4: "----:com.apple.iTunes:MusicIP PUID"},
+	{Key: ASIN, ID3v2: "TXXX:ASIN", MP4: "----:com.apple.iTunes:ASIN"},
+	{Key: Artists, ID3v2: "TXXX:ARTISTS", MP4: "----:com.apple.iTunes:ARTISTS"},
+	{Key: Barcode, ID3v2: "TXXX:BARCODE", MP4: "----:com.apple.iTunes:BARCODE"},
+	{Key: CatalogNumber, ID3v2: "TXXX:CATALOGNUMBER", MP4: "----:com.apple.iTunes:CATALOGNUMBER"},
+	{Key: Script, ID3v2: "TXXX:SCRIPT", MP4: "----:com.apple.iTunes:SCRIPT"},
+}
+
+// These keys are read from and written to the iTunes atoms of MP4 files, which TagLib doesn't map itself.
+const (
+	ITunesAdvisory   = "ITUNESADVISORY"   // rtng
+	ITunesArtistID   = "ITUNESARTISTID"   // atID
+	ITunesCatalogID  = "ITUNESCATALOGID"  // cnID
+	ITunesComposerID = "ITUNESCOMPOSERID" // cmID
+	ITunesCountryID  = "ITUNESCOUNTRYID"  // sfID, the store front
+	ITunesGenreID    = "ITUNESGENREID"    // geID
+	ITunesMediaKind  = "ITUNESMEDIAKIND"  // stik
+	ITunesPlaylistID = "ITUNESPLAYLISTID" // plID, the album's ID
+)
+
+// Values of [ITunesMediaKind].
+const (
+	MediaKindMusic      = "1"
+	MediaKindAudiobook  = "2"
+	MediaKindMusicVideo = "6"
+	MediaKindMovie      = "9"
+	MediaKindTVShow     = "10"
+	MediaKindBooklet    = "11"
+	MediaKindRingtone   = "14"
+	MediaKindPodcast    = "21"
+)
+
+// Values of [ITunesAdvisory].
+const (
+	AdvisoryNone     = "0"
+	AdvisoryExplicit = "1"
+	AdvisoryClean    = "2"
+)
+
+// MappingITunes uses the atoms and frames written by iTunes and Apple Music. With ID3v2, grouping is written to GRP1
+// and work to TIT1 as iTunes does since 12.5. It includes the iTunes atoms read and written for every MP4 file.
+var MappingITunes = append(Mapping{
+	{Key: Composer, MP4: "\u00a9wrt"},
+	{Key: Grouping, ID3v2: "GRP1", MP4: "\u00a9grp"},
+	{Key: Work, ID3v2: "TIT1", MP4: "\u00a9wrk"},
+	{Key: AlbumSort, ID3v2: "TSOA", MP4: "soal"},
+	{Key: AlbumArtistSort, ID3v2: "TSO2", MP4: "soaa"},
+	{Key: ArtistSort, ID3v2: "TSOP", MP4: "soar"},
+	{Key: ComposerSort, ID3v2: "TSOC", MP4: "soco"},
+	{Key: TitleSort, ID3v2: "TSOT", MP4: "sonm"},
+	{Key: Compilation, ID3v2: "TCMP", MP4: "cpil"},
+}, mappingMP4ITunes...)
+
+// mappingMP4ITunes maps the iTunes atoms which TagLib doesn't read to their keys, for every MP4 file.
+var mappingMP4ITunes = Mapping{
+	{Key: ITunesAdvisory, MP4: "rtng"},
+	{Key: ITunesArtistID, MP4: "atID"},
+	{Key: ITunesCatalogID, MP4: "cnID"},
+	{Key: ITunesComposerID, MP4: "cmID"},
+	{Key: ITunesCountryID, MP4: "sfID"},
+	{Key: ITunesGenreID, MP4: "geID"},
+	{Key: ITunesMediaKind, MP4: "stik"},
+	{Key: ITunesPlaylistID, MP4: "plID"},
+}
+
+// find returns the key mapped to the native name, if any.
+func (m Mapping) find(name string) (string, bool) {
+	for _, km := range m {
+		for _, n := range []string{km.ID3v2, km.MP4} {
+			if n != "" && nativeNameEqual(n, name) {
+				return strings.ToUpper(km.Key), true
+			}
+		}
+	}
+	return "", false
+}
+
+func nativeNameEqual(a, b string) bool {
+	if strings.HasPrefix(a, "TXXX:") || strings.HasPrefix(a, "----:") {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+var (
+	registeredMu       sync.RWMutex
+	registeredMappings Mapping
+)
+
+// RegisterMapping adds m to the mappings used by every read and write of a file on disk, including [ReadTags] and
+// [WriteTags], so applications can give their own keys a native name
+//
+//	taglib.RegisterMapping(taglib.Mapping{
+//		{Key: "ENERGY", ID3v2: "TXXX:EnergyLevel", MP4: "----:com.apple.iTunes:EnergyLevel"},
+//	})
+//
+// For the same key, later registrations take precedence, and a mapping passed to [ReadTagsMapping] or
+// [WriteTagsMapping] takes precedence over registered ones. It is safe to call concurrently with reads and writes.
+func RegisterMapping(m Mapping) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registeredMappings = registeredMappings.merge(m)
+}
+
+func registeredMapping() Mapping {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	return registeredMappings
+}
+
+// merge returns a copy of m with the entries of other added, replacing those of m with the same key.
+func (m Mapping) merge(other Mapping) Mapping {
+	var out Mapping
+	for _, km := range m {
+		if !slices.ContainsFunc(other, func(o KeyMapping) bool { return strings.EqualFold(o.Key, km.Key) }) {
+			out = append(out, km)
+		}
+	}
+	return append(out, other...)
+}
+
+// ReadTagsMapping is like [ReadTags], but reads the keys in m from their native names. Native names not in m are read
+// with TagLib's mapping as usual.
+func ReadTagsMapping(path string, m Mapping) (_ map[string][]string, err error) {
+	defer wrapErr(&err, "read tags", path)
+	return readTags(path, m)
+}
+
+// WriteTagsMapping is like [WriteTags], but writes the keys in m to their native names, replacing any existing frame
+// or atom with a differently cased name.
+func WriteTagsMapping(path string, tags map[string][]string, m Mapping, opts WriteOption) (err error) {
+	defer wrapErr(&err, "write tags", path)
+	return writeTags(path, tags, m, newWriteConfig(opts))
+}
+
+// nativeMapping returns the mapping used to read and write a file, of m over the registered mappings over
+// [mappingMP4ITunes].
+func nativeMapping(m Mapping) Mapping {
+	return mappingMP4ITunes.merge(registeredMapping()).merge(m)
+}
+
+// nativeFormat is where a file keeps the tags which have native names.
+type nativeFormat int
+
+const (
+	nativeNone nativeFormat = iota
+	nativeID3v2
+	nativeMP4
+)
+
+// name returns the native name km has in the format, empty if it has none.
+func (format nativeFormat) name(km KeyMapping) string {
+	switch format {
+	case nativeID3v2:
+		return km.ID3v2
+	case nativeMP4:
+		return km.MP4
+	}
+	return ""
+}
+
+// nativeFormatOf reports whether TagLib keeps the tags of f in ID3v2 frames or in MP4 items. An ID3v2 tag at the start
+// of a file only counts for MPEG and TrueAudio files, since TagLib ignores the ones before FLAC and APE files.
+func nativeFormatOf(f fileReader, path string) (nativeFormat, error) {
+	var magic [8]byte
+	if _, err := f.ReadAt(magic[:], 0); err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("read magic: %w", err)
+	}
+	if string(magic[4:]) == "ftyp" {
+		return nativeMP4, nil
+	}
+
+	loc, err := locateID3v2Tag(f, path)
+	if errors.Is(err, errors.ErrUnsupported) || errors.Is(err, ErrInvalidFile) {
+		return nativeNone, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if loc.form != nil || loc.dsf {
+		return nativeID3v2, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3", ".mp2", ".tta":
+		return nativeID3v2, nil
+	}
+	var next [4]byte
+	if _, err := f.ReadAt(next[:], loc.end); err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("read audio: %w", err)
+	}
+	if string(next[:]) == "TTA1" || isMPEGHeader(next[:]) {
+		return nativeID3v2, nil
+	}
+	return nativeNone, nil
+}
+
+// nativeTag is a frame or item under its native name, with the key TagLib reads it as, empty if it doesn't.
+type nativeTag struct {
+	name, key string
+	values    []string
+}
+
+// readNativeTags replaces the values TagLib read into tags with the ones under the native names in m, or in the
+// mappings of [nativeMapping], so a file holding the same value under two names doesn't report it twice.
+func readNativeTags(path string, tags map[string][]string, m Mapping) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+	return readNativeTagsFile(f, path, tags, m)
+}
+
+// readNativeTagsFile is [readNativeTags] for an open file f, with path only used for the extension.
+func readNativeTagsFile(f fileReader, path string, tags map[string][]string, m Mapping) error {
+	format, err := nativeFormatOf(f, path)
+	if err != nil {
+		return err
+	}
+	m = nativeMapping(m)
+	if !slices.ContainsFunc(m, func(km KeyMapping) bool { return format.name(km) != "" }) {
+		return nil
+	}
+
+	var natives []nativeTag
+	switch format {
+	case nativeID3v2:
+		tag, _, err := readID3v2Tag(f)
+		if err != nil {
+			return fmt.Errorf("read id3v2: %w", err)
+		}
+		natives = id3v2NativeTags(tag)
+	case nativeMP4:
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("stat: %w", err)
+		}
+		items, err := readMP4Items(f, info.Size())
+		if err != nil {
+			return fmt.Errorf("read mp4 items: %w", err)
+		}
+		for _, item := range items {
+			natives = append(natives, nativeTag{name: item.name, key: mp4PropertyKey(item.name), values: item.values()})
+		}
+	}
+
+	var mapped = map[string][]string{}
+	for _, n := range natives {
+		key, ok := m.find(n.name)
+		if !ok {
+			continue
+		}
+		if n.key != "" {
+			delete(tags, n.key)
+		}
+		mapped[key] = append(mapped[key], n.values...)
+	}
+	for k, vs := range mapped {
+		if len(vs) > 0 {
+			tags[k] = vs
+		}
+	}
+	return nil
+}
+
+// id3v2NativeTags lists the text and user text frames of an ID3v2 tag. Compressed and encrypted frames are skipped.
+func id3v2NativeTags(tag []byte) []nativeTag {
+	if len(tag) < 10 {
+		return nil
+	}
+	var natives []nativeTag
+	for _, frame := range id3v2Frames(tag) {
+		if n, ok := id3v2NativeTag(tag[3], frame); ok {
+			natives = append(natives, n)
+		}
+	}
+	return natives
+}
+
+// id3v2NativeTag reads a text or user text frame of a tag of version major.
+func id3v2NativeTag(major byte, frame ID3v2Frame) (nativeTag, bool) {
+	id := id3v2FrameID(major, frame.ID)
+	if !strings.HasPrefix(id, "T") {
+		return nativeTag{}, false
+	}
+	data, ok := id3v2FrameContent(major, byte(frame.Flags), frame.Data)
+	if !ok || len(data) == 0 {
+		return nativeTag{}, false
+	}
+	values := splitID3v2Texts(data[0], data[1:])
+	if id != "TXXX" {
+		return nativeTag{name: id, key: salvageID3v2Keys[id], values: values}, true
+	}
+	if len(values) == 0 {
+		return nativeTag{}, false
+	}
+	return nativeTag{name: "TXXX:" + values[0], key: id3v2UserTextKey(values[0]), values: values[1:]}, true
+}
+
+// id3v2UserTextKey returns the key TagLib reads a TXXX frame with the given description as.
+func id3v2UserTextKey(description string) string {
+	if key, ok := MappingPicard.find("TXXX:" + description); ok {
+		return key
+	}
+	return strings.ToUpper(description)
+}
+
+// splitNativeTags returns the tags of tags which have a native name in the format of the file at path, and the
+// others for TagLib to write. Keys with a native name are left in the others without values, so TagLib removes them
+// from where it maps them. For MP4 files, "true" and "yes" are written as 1 to the boolean atoms, and "false" and
+// "no" as 0, since TagLib reads their values as integers.
+func splitNativeTags(path string, tags map[string][]string, m Mapping) (nativeFormat, []nativeTag, map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	format, err := nativeFormatOf(f, path)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	m = nativeMapping(m)
+
+	var natives []nativeTag
+	var rest = make(map[string][]string, len(tags))
+	for k, vs := range tags {
+		if format == nativeMP4 && isMP4FlagKey(k) {
+			vs = mp4FlagValues(vs)
+		}
+		i := slices.IndexFunc(m, func(km KeyMapping) bool { return strings.EqualFold(km.Key, k) && format.name(km) != "" })
+		if i >= 0 {
+			natives = append(natives, nativeTag{name: format.name(m[i]), key: k, values: vs})
+			rest[k] = nil
+			continue
+		}
+		rest[k] = vs
+	}
+	return format, natives, rest, nil
+}
+
+func isMP4FlagKey(key string) bool {
+	switch strings.ToUpper(key) {
+	case Compilation, GaplessPlayback, Podcast, ShowWorkMovement:
+		return true
+	}
+	return false
+}
+
+func mp4FlagValues(values []string) []string {
+	var out []string
+	for _, v := range values {
+		switch strings.ToUpper(v) {
+		case "TRUE", "YES":
+			v = "1"
+		case "FALSE", "NO":
+			v = "0"
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// writeNativeTags saves natives under their names in the ID3v2 tag or MP4 items of the file at path, after TagLib has
+// saved written. With clear, every native name of m is removed, including those of the iTunes atoms TagLib doesn't
+// clear itself, unless TagLib wrote it for a key of written. New ID3v2 tags are created with the given major version.
+func writeNativeTags(path string, format nativeFormat, natives []nativeTag, written map[string][]string, m Mapping, clear bool, major byte) error {
+	var cleared []string
+	if clear {
+		for _, km := range nativeMapping(m) {
+			if name := format.name(km); name != "" {
+				cleared = append(cleared, name)
+			}
+		}
+	}
+	if len(cleared) == 0 && len(natives) == 0 {
+		return nil
+	}
+	var keys = map[string]bool{}
+	for k, vs := range written {
+		if len(vs) > 0 {
+			keys[strings.ToUpper(k)] = true
+		}
+	}
+	// remove reports whether the native name and TagLib key of a frame or item mean it should be removed
+	remove := func(matches func(name string) bool, key string) bool {
+		if slices.ContainsFunc(natives, func(n nativeTag) bool { return matches(n.name) }) {
+			return true
+		}
+		if keys[key] {
+			return false
+		}
+		return slices.ContainsFunc(cleared, matches)
+	}
+
+	switch format {
+	case nativeID3v2:
+		return editID3v2TagVersion(path, major, func(major byte, frames []ID3v2Frame) ([]ID3v2Frame, error) {
+			frames = slices.DeleteFunc(frames, func(frame ID3v2Frame) bool {
+				n, _ := id3v2NativeTag(major, frame)
+				return remove(func(name string) bool { return id3v2FrameHasName(major, frame, name) }, n.key)
+			})
+			for _, n := range natives {
+				if len(n.values) == 0 {
+					continue
+				}
+				if description, ok := strings.CutPrefix(n.name, "TXXX:"); ok {
+					frames = append(frames, ID3v2Frame{ID: "TXXX", Data: renderID3v2Texts(major, append([]string{description}, n.values...))})
+				} else {
+					frames = append(frames, ID3v2Frame{ID: n.name, Data: renderID3v2Texts(major, n.values)})
+				}
+			}
+			return frames, nil
+		})
+	case nativeMP4:
+		return editMP4Items(path, func(items []mp4Item) []mp4Item {
+			items = slices.DeleteFunc(items, func(item mp4Item) bool {
+				return remove(func(name string) bool { return nativeNameEqual(name, item.name) }, mp4PropertyKey(item.name))
+			})
+			for _, n := range natives {
+				if len(n.values) > 0 {
+					items = append(items, mp4Item{name: n.name, atom: renderMP4Item(n.name, n.values)})
+				}
+			}
+			return items
+		})
+	}
+	return nil
+}
+
+// id3v2FrameHasName reports whether frame is stored under the native name, matching the descriptions of TXXX frames
+// case insensitively.
+func id3v2FrameHasName(major byte, frame ID3v2Frame, name string) bool {
+	description, ok := strings.CutPrefix(name, "TXXX:")
+	if !ok {
+		return frame.ID == name
+	}
+	if frame.ID != "TXXX" {
+		return false
+	}
+	data, ok := id3v2FrameContent(major, byte(frame.Flags), frame.Data)
+	if !ok || len(data) == 0 {
+		return false
+	}
+	got, _ := splitID3v2Text(data[0], data[1:])
+	return strings.EqualFold(got, description)
+}
@@ -0,0 +1,166 @@
+package taglib_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestRegisterMapping(t *testing.T) {
+	t.Parallel()
+
+	// a key no other test writes, as the mapping applies to every read and write
+	const key = "TESTENERGY"
+	taglib.RegisterMapping(taglib.Mapping{
+		{Key: key, ID3v2: "TXXX:EnergyLevel", MP4: "----:com.apple.iTunes:EnergyLevel"},
+	})
+
+	for _, path := range []string{tmpf(t, egMP3, "eg.mp3"), tmpf(t, egM4a, "eg.m4a"), tmpf(t, egWAV, "eg.wav")} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			// TagLib writes the frame or atom with its own upper case name, which is read as the mapped key
+			nilErr(t, taglib.WriteTags(path, map[string][]string{"ENERGYLEVEL": {"1"}}, taglib.Clear))
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{key: {"1"}})
+
+			// and replaced instead of duplicated
+			nilErr(t, taglib.WriteTags(path, map[string][]string{key: {"2", "3"}, taglib.Title: {"Title"}}, 0))
+			tags, err = taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{key: {"2", "3"}, taglib.Title: {"Title"}})
+
+			nilErr(t, taglib.WriteTags(path, map[string][]string{key: nil}, 0))
+			tags, err = taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}})
+
+			nilErr(t, taglib.WriteTags(path, map[string][]string{key: {"4"}}, taglib.Clear))
+			tags, err = taglib.ReadTagKeys(path, key)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{key: {"4"}})
+
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			eq(t, properties.Length > 0, true)
+		})
+	}
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	nilErr(t, taglib.WriteTags(path, map[string][]string{key: {"5"}}, taglib.Clear))
+	frames, err := taglib.ReadID3v2Frames(path)
+	nilErr(t, err)
+	eq(t, slices.ContainsFunc(frames, func(f taglib.ID3v2Frame) bool {
+		return f.ID == "TXXX" && string(f.Data) == "\x03EnergyLevel\x005"
+	}), true)
+}
+
+func TestMappingGrowsMP4(t *testing.T) {
+	t.Parallel()
+
+	// the file with its moov atom moved before the audio, as when optimised for streaming
+	moovFirst := func() []byte {
+		moov := bytes.Index(egM4a, []byte("moov")) - 4
+		mdat := bytes.Index(egM4a, []byte("mdat")) - 4
+		b := slices.Concat(egM4a[:mdat], egM4a[moov:], egM4a[mdat:moov])
+		stco := bytes.Index(b, []byte("stco")) + 12
+		binary.BigEndian.PutUint32(b[stco:], binary.BigEndian.Uint32(b[stco:])+uint32(len(egM4a)-moov))
+		return b
+	}
+
+	for _, path := range []string{tmpf(t, egM4a, "eg.m4a"), tmpf(t, moovFirst(), "moov-first.m4a")} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			// more than the padding of the file, so the moov atom grows
+			long := strings.Repeat("x", 4000)
+			nilErr(t, taglib.WriteTagsMapping(path, map[string][]string{"LONG": {long}}, taglib.Mapping{
+				{Key: "LONG", MP4: "----:com.apple.iTunes:Long"},
+			}, 0))
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			eq(t, tags["LONG"][0], long)
+
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			eq(t, properties.Length > 0, true)
+
+			// the chunk offset still points at the audio in the mdat atom
+			b, err := os.ReadFile(path)
+			nilErr(t, err)
+			stco := bytes.Index(b, []byte("stco")) + 12
+			eq(t, int(binary.BigEndian.Uint32(b[stco:])), bytes.Index(b, []byte("mdat"))+4)
+		})
+	}
+}
+
+func TestMappingPicard(t *testing.T) {
+	t.Parallel()
+
+	// names cased differently from Picard's, as other taggers write them
+	other := taglib.Mapping{
+		{Key: taglib.MusicBrainzAlbumID, ID3v2: "TXXX:MUSICBRAINZ ALBUM ID", MP4: "----:com.apple.iTunes:MUSICBRAINZ ALBUM ID"},
+	}
+	for _, path := range []string{tmpf(t, egMP3, "eg.mp3"), tmpf(t, egM4a, "eg.m4a")} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			nilErr(t, taglib.WriteTagsMapping(path, map[string][]string{taglib.MusicBrainzAlbumID: {"old"}}, other, taglib.Clear))
+
+			tags := map[string][]string{taglib.MusicBrainzAlbumID: {"album-id"}, taglib.Barcode: {"123"}}
+			nilErr(t, taglib.WriteTagsMapping(path, tags, taglib.MappingPicard, 0))
+
+			got, err := taglib.ReadTagsMapping(path, taglib.MappingPicard)
+			nilErr(t, err)
+			tagEq(t, got, tags)
+
+			b, err := os.ReadFile(path)
+			nilErr(t, err)
+			eq(t, bytes.Contains(b, []byte("MusicBrainz Album Id")), true)
+			eq(t, bytes.Contains(b, []byte("MUSICBRAINZ ALBUM ID")), false)
+		})
+	}
+}
+
+func TestMappingITunes(t *testing.T) {
+	t.Parallel()
+
+	tags := map[string][]string{
+		taglib.Grouping:    {"Grouping"},
+		taglib.Work:        {"Work"},
+		taglib.AlbumSort:   {"Album, The"},
+		taglib.Compilation: {"1"},
+	}
+
+	path := tmpf(t, egM4a, "eg.m4a")
+	nilErr(t, taglib.WriteTagsMapping(path, tags, taglib.MappingITunes, taglib.Clear))
+	got, err := taglib.ReadTagsMapping(path, taglib.MappingITunes)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+	mp4DataEq(t, path, "\xa9grp", 1, []byte("Grouping"))
+	mp4DataEq(t, path, "\xa9wrk", 1, []byte("Work"))
+	mp4DataEq(t, path, "soal", 1, []byte("Album, The"))
+	mp4DataEq(t, path, "cpil", 21, []byte{1})
+
+	// grouping and work go to GRP1 and TIT1, as iTunes writes them since 12.5
+	path = tmpf(t, egMP3, "eg.mp3")
+	nilErr(t, taglib.WriteTagsMapping(path, tags, taglib.MappingITunes, taglib.Clear))
+	got, err = taglib.ReadTagsMapping(path, taglib.MappingITunes)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+
+	frames, err := taglib.ReadID3v2Frames(path)
+	nilErr(t, err)
+	for _, exp := range []taglib.ID3v2Frame{
+		{ID: "GRP1", Data: []byte("\x03Grouping")},
+		{ID: "TIT1", Data: []byte("\x03Work")},
+		{ID: "TSOA", Data: []byte("\x03Album, The")},
+		{ID: "TCMP", Data: []byte("\x031")},
+	} {
+		eq(t, slices.ContainsFunc(frames, func(f taglib.ID3v2Frame) bool {
+			return f.ID == exp.ID && bytes.Equal(f.Data, exp.Data)
+		}), true)
+	}
+}
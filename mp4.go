@@ -0,0 +1,428 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// mp4Item is an item atom of the iTunes metadata of an MP4 file, named by its type, or "----:mean:name" for a
+// freeform item.
+type mp4Item struct {
+	name string
+	atom []byte
+}
+
+// mp4ValueType is how the data of an item which doesn't hold text is stored, as in TagLib's item factory.
+type mp4ValueType int
+
+const (
+	mp4Text mp4ValueType = iota
+	mp4Bool
+	mp4Int
+	mp4UInt
+	mp4LongLong
+	mp4Byte
+)
+
+// mp4ValueTypes are the items which hold integers, written from the first value.
+var mp4ValueTypes = map[string]mp4ValueType{
+	"cpil": mp4Bool, "pgap": mp4Bool, "pcst": mp4Bool, "shwm": mp4Bool, "hdvd": mp4Bool,
+	"tmpo": mp4Int, "©mvi": mp4Int, "©mvc": mp4Int,
+	"tvsn": mp4UInt, "tves": mp4UInt, "cnID": mp4UInt, "sfID": mp4UInt, "atID": mp4UInt, "geID": mp4UInt, "cmID": mp4UInt,
+	"plID": mp4LongLong,
+	"stik": mp4Byte, "rtng": mp4Byte, "akID": mp4Byte,
+}
+
+// mp4PropertyKeys maps the items TagLib reads as tags to their keys. Freeform items in the "com.apple.iTunes"
+// namespace are read as their upper case name, or as the keys of [MappingPicard].
+var mp4PropertyKeys = map[string]string{
+	"©nam": Title, "©ART": Artist, "aART": AlbumArtist, "©alb": Album, "©cmt": Comment,
+	"©gen": Genre, "©day": Date, "©wrt": Composer, "©lyr": Lyrics, "©too": EncodedBy,
+	"©grp": Grouping, "©wrk": Work, "©mvn": MovementName, "©mvi": MovementNumber,
+	"©mvc": MovementCount, "trkn": TrackNumber, "disk": DiscNumber, "cpil": Compilation, "tmpo": BPM,
+	"cprt": Copyright, "soal": AlbumSort, "soaa": AlbumArtistSort, "soar": ArtistSort, "sonm": TitleSort,
+	"soco": ComposerSort, "sosn": ShowSort, "shwm": ShowWorkMovement, "pgap": GaplessPlayback, "pcst": Podcast,
+	"catg": PodcastCategory, "desc": PodcastDesc, "egid": PodcastID, "purl": PodcastURL, "tves": TVEpisode,
+	"tven": TVEpisodeID, "tvnn": TVNetwork, "tvsn": TVSeason, "tvsh": TVShow,
+}
+
+// mp4PropertyKey returns the key TagLib reads the item with the given name as, empty if it doesn't.
+func mp4PropertyKey(name string) string {
+	if mean, rest, ok := strings.Cut(strings.TrimPrefix(name, "----:"), ":"); ok && strings.HasPrefix(name, "----:") {
+		if mean != "com.apple.iTunes" {
+			return ""
+		}
+		if key, ok := MappingPicard.find(name); ok {
+			return key
+		}
+		return strings.ToUpper(rest)
+	}
+	return mp4PropertyKeys[name]
+}
+
+// readMP4Items lists the items of the first "ilst" atom of an MP4 file, nil if it has none.
+func readMP4Items(r io.ReaderAt, end int64) ([]mp4Item, error) {
+	for _, region := range mp4TagRegions(r, end) {
+		if region.Kind != "moov.udta.meta.ilst" {
+			continue
+		}
+		ilst := make([]byte, region.Length)
+		if _, err := r.ReadAt(ilst, region.Offset); err != nil {
+			return nil, fmt.Errorf("read ilst: %w", err)
+		}
+		return mp4ItemsOf(ilst), nil
+	}
+	return nil, nil
+}
+
+// mp4ItemsOf splits the items of an "ilst" atom.
+func mp4ItemsOf(ilst []byte) []mp4Item {
+	var items []mp4Item
+	for _, atom := range mp4Children(ilst, 0) {
+		b := ilst[atom.offset : atom.offset+atom.size]
+		items = append(items, mp4Item{name: mp4ItemName(b), atom: b})
+	}
+	return items
+}
+
+// mp4Children lists the children of the atom in b, which start skip bytes after its header.
+func mp4Children(b []byte, skip int64) []mp4Atom {
+	headerSize := mp4HeaderSize(b)
+	if int64(len(b)) < headerSize+skip {
+		return nil
+	}
+	return mp4Atoms(bytes.NewReader(b), headerSize+skip, int64(len(b)))
+}
+
+func mp4HeaderSize(atom []byte) int64 {
+	if len(atom) >= 4 && binary.BigEndian.Uint32(atom) == 1 {
+		return 16
+	}
+	return 8
+}
+
+// mp4ItemName returns the type of an item atom, or "----:mean:name" for a freeform item.
+func mp4ItemName(atom []byte) string {
+	if len(atom) < 8 {
+		return ""
+	}
+	if typ := string(atom[4:8]); typ != "----" {
+		return latin1String(typ)
+	}
+	var mean, name string
+	for _, child := range mp4Children(atom, 0) {
+		// mean and name are full atoms, with a version and flags before the string
+		if child.size < child.headerSize+4 {
+			continue
+		}
+		text := string(atom[child.offset+child.headerSize+4 : child.offset+child.size])
+		switch child.typ {
+		case "mean":
+			mean = text
+		case "name":
+			name = text
+		}
+	}
+	return "----:" + mean + ":" + name
+}
+
+// values returns the values of the item as text, as decimals for integers, and "number/total" for the track and disc
+// number. Cover art and other binary data have none.
+func (item mp4Item) values() []string {
+	var values []string
+	for _, child := range mp4Children(item.atom, 0) {
+		// the data atom has a version, 3 bytes of type, and 4 bytes of locale before the payload
+		if child.typ != "data" || child.size < child.headerSize+8 {
+			continue
+		}
+		header := item.atom[child.offset+child.headerSize:]
+		typ := binary.BigEndian.Uint32(header) & 0xffffff
+		data := item.atom[child.offset+child.headerSize+8 : child.offset+child.size]
+
+		switch valueType := mp4ValueTypes[item.name]; {
+		case item.name == "trkn" || item.name == "disk":
+			if len(data) >= 6 {
+				values = append(values, fmt.Sprintf("%d/%d", binary.BigEndian.Uint16(data[2:]), binary.BigEndian.Uint16(data[4:])))
+			}
+		case valueType == mp4Bool:
+			if len(data) > 0 {
+				values = append(values, strconv.Itoa(min(int(data[0]), 1)))
+			}
+		case valueType != mp4Text:
+			if v, ok := mp4Integer(data, valueType != mp4UInt && valueType != mp4Byte); ok {
+				values = append(values, v)
+			}
+		case typ == 0 || typ == 1: // implicit, or UTF-8
+			values = append(values, string(data))
+		}
+	}
+	return values
+}
+
+// mp4Integer decodes a big endian integer of 1, 2, 4, or 8 bytes.
+func mp4Integer(data []byte, signed bool) (string, bool) {
+	var v uint64
+	switch len(data) {
+	case 1:
+		v = uint64(data[0])
+		if signed {
+			return strconv.Itoa(int(int8(data[0]))), true
+		}
+	case 2:
+		v = uint64(binary.BigEndian.Uint16(data))
+		if signed {
+			return strconv.Itoa(int(int16(v))), true
+		}
+	case 4:
+		v = uint64(binary.BigEndian.Uint32(data))
+		if signed {
+			return strconv.Itoa(int(int32(v))), true
+		}
+	case 8:
+		v = binary.BigEndian.Uint64(data)
+		if signed {
+			return strconv.FormatInt(int64(v), 10), true
+		}
+	default:
+		return "", false
+	}
+	return strconv.FormatUint(v, 10), true
+}
+
+// renderMP4Item renders the item atom for name with a data atom for each value, or with the first value for items
+// which hold integers, like TagLib. Values which aren't a decimal integer are written as 0.
+func renderMP4Item(name string, values []string) []byte {
+	var data []byte
+	if valueType := mp4ValueTypes[name]; valueType != mp4Text {
+		v, _ := strconv.ParseInt(strings.TrimSpace(values[0]), 10, 64)
+		var payload []byte
+		switch valueType {
+		case mp4Bool:
+			payload = []byte{0}
+			if v != 0 {
+				payload[0] = 1
+			}
+		case mp4Int:
+			payload = binary.BigEndian.AppendUint16(nil, uint16(v))
+		case mp4UInt:
+			payload = binary.BigEndian.AppendUint32(nil, uint32(v))
+		case mp4LongLong:
+			payload = binary.BigEndian.AppendUint64(nil, uint64(v))
+		case mp4Byte:
+			payload = []byte{byte(v)}
+		}
+		data = renderMP4Data(21, payload) // signed integer
+	} else {
+		for _, v := range values {
+			data = append(data, renderMP4Data(1, []byte(v))...) // UTF-8
+		}
+	}
+
+	mean, rest, freeform := strings.Cut(strings.TrimPrefix(name, "----:"), ":")
+	if !strings.HasPrefix(name, "----:") || !freeform {
+		return renderMP4Atom(latin1Bytes(name), data)
+	}
+	children := renderMP4Atom("mean", append([]byte{0, 0, 0, 0}, mean...))
+	children = append(children, renderMP4Atom("name", append([]byte{0, 0, 0, 0}, rest...))...)
+	return renderMP4Atom("----", append(children, data...))
+}
+
+// latin1String decodes the ISO-8859-1 type of an atom, such as "\xa9nam", to the UTF-8 name "©nam".
+func latin1String(s string) string {
+	runes := make([]rune, len(s))
+	for i := range len(s) {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// latin1Bytes encodes the UTF-8 name of an atom as its ISO-8859-1 type.
+func latin1Bytes(s string) string {
+	var b []byte
+	for _, r := range s {
+		b = append(b, byte(r))
+	}
+	return string(b)
+}
+
+func renderMP4Data(typ uint32, payload []byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, typ)
+	data = append(data, 0, 0, 0, 0) // locale
+	return renderMP4Atom("data", append(data, payload...))
+}
+
+func renderMP4Atom(typ string, data []byte) []byte {
+	atom := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	atom = append(atom, typ...)
+	return append(atom, data...)
+}
+
+// editMP4Items passes the items of the MP4 file at path to edit, and saves the items it returns in the "ilst" atom,
+// creating it and its "udta" and "meta" parents if missing. A "free" atom following the "ilst" atom is used as
+// padding, as TagLib does, and when the "moov" atom still changes size, the chunk offsets of its tracks are moved
+// with the audio after it. Fragmented files, whose fragments have offsets of their own, return an error wrapping
+// [errors.ErrUnsupported] in that case.
+func editMP4Items(path string, edit func(items []mp4Item) []mp4Item) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	top := mp4Atoms(f, 0, info.Size())
+	moovIndex := -1
+	for i, atom := range top {
+		if atom.typ == "moov" {
+			moovIndex = i
+			break
+		}
+	}
+	if moovIndex < 0 {
+		return fmt.Errorf("no moov atom: %w", ErrInvalidFile)
+	}
+	moovAtom := top[moovIndex]
+	moov := make([]byte, moovAtom.size)
+	if _, err := f.ReadAt(moov, moovAtom.offset); err != nil {
+		return fmt.Errorf("read moov: %w", err)
+	}
+
+	newMoov := setMP4Child(moov, 0, "udta", func(udta []byte) []byte {
+		if udta == nil {
+			udta = renderMP4Atom("udta", nil)
+		}
+		return setMP4Child(udta, 0, "meta", func(meta []byte) []byte {
+			if meta == nil {
+				// a full atom, with a handler for iTunes metadata
+				hdlr := renderMP4Atom("hdlr", append(make([]byte, 8), "mdirappl\x00\x00\x00\x00\x00\x00\x00\x00\x00"...))
+				meta = renderMP4Atom("meta", append([]byte{0, 0, 0, 0}, hdlr...))
+			}
+			return setMP4Ilst(meta, edit)
+		})
+	})
+
+	delta := int64(len(newMoov)) - moovAtom.size
+	if delta != 0 {
+		for _, atom := range top {
+			if atom.typ == "moof" {
+				return fmt.Errorf("resize moov of fragmented file: %w", errors.ErrUnsupported)
+			}
+		}
+		if err := shiftMP4ChunkOffsets(newMoov, moovAtom.offset+moovAtom.size, delta); err != nil {
+			return err
+		}
+	}
+	return rewriteRange(f, moovAtom.offset, moovAtom.offset+moovAtom.size, newMoov)
+}
+
+// setMP4Ilst returns the "meta" atom with its "ilst" atom holding the items returned by edit. The "free" atom after
+// the "ilst" atom, if any, grows or shrinks to keep the size of the "meta" atom.
+func setMP4Ilst(meta []byte, edit func(items []mp4Item) []mp4Item) []byte {
+	children := mp4Children(meta, 4)
+	index := -1
+	for i, child := range children {
+		if child.typ == "ilst" {
+			index = i
+			break
+		}
+	}
+
+	var items []mp4Item
+	start, end := int64(len(meta)), int64(len(meta))
+	if index >= 0 {
+		start, end = children[index].offset, children[index].offset+children[index].size
+		items = mp4ItemsOf(meta[start:end])
+	}
+	var ilst []byte
+	for _, item := range edit(items) {
+		ilst = append(ilst, item.atom...)
+	}
+	ilst = renderMP4Atom("ilst", ilst)
+
+	if index >= 0 && index+1 < len(children) && children[index+1].typ == "free" {
+		free := children[index+1]
+		if size := free.size - (int64(len(ilst)) - (end - start)); size >= 8 && size <= math.MaxUint32 {
+			ilst = append(ilst, renderMP4Atom("free", make([]byte, size-8))...)
+			end = free.offset + free.size
+		}
+	}
+	return resizeMP4Atom(concat(meta[:start], ilst, meta[end:]))
+}
+
+// setMP4Child returns the atom with its first child of type typ, starting skip bytes after its header, replaced by
+// the result of set, which is passed nil if there is none and its result appended.
+func setMP4Child(atom []byte, skip int64, typ string, set func(child []byte) []byte) []byte {
+	for _, child := range mp4Children(atom, skip) {
+		if child.typ == typ {
+			replaced := set(atom[child.offset : child.offset+child.size])
+			return resizeMP4Atom(concat(atom[:child.offset], replaced, atom[child.offset+child.size:]))
+		}
+	}
+	return resizeMP4Atom(concat(atom, set(nil)))
+}
+
+// resizeMP4Atom sets the size in the header of atom to its length.
+func resizeMP4Atom(atom []byte) []byte {
+	if mp4HeaderSize(atom) == 16 {
+		binary.BigEndian.PutUint64(atom[8:], uint64(len(atom)))
+	} else {
+		binary.BigEndian.PutUint32(atom, uint32(len(atom)))
+	}
+	return atom
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
+
+// shiftMP4ChunkOffsets adds delta to the offsets in the "stco" and "co64" atoms of moov which point at or after end,
+// where the data moves to when the "moov" atom before it changes size.
+func shiftMP4ChunkOffsets(moov []byte, end int64, delta int64) error {
+	r := bytes.NewReader(moov)
+	for _, path := range [][]string{{"trak", "mdia", "minf", "stbl", "stco"}, {"trak", "mdia", "minf", "stbl", "co64"}} {
+		for _, atom := range mp4AtomsAt(r, mp4HeaderSize(moov), int64(len(moov)), path...) {
+			// a full atom with the number of entries after the version and flags
+			table := moov[atom.offset+atom.headerSize : atom.offset+atom.size]
+			if len(table) < 8 {
+				continue
+			}
+			entrySize := 4
+			if atom.typ == "co64" {
+				entrySize = 8
+			}
+			count := int(binary.BigEndian.Uint32(table[4:]))
+			for i := 0; i < count && 8+(i+1)*entrySize <= len(table); i++ {
+				entry := table[8+i*entrySize:]
+				if entrySize == 8 {
+					if offset := int64(binary.BigEndian.Uint64(entry)); offset >= end {
+						binary.BigEndian.PutUint64(entry, uint64(offset+delta))
+					}
+					continue
+				}
+				offset := int64(binary.BigEndian.Uint32(entry))
+				if offset < end {
+					continue
+				}
+				if offset+delta > math.MaxUint32 {
+					return fmt.Errorf("chunk offset past 4 GB: %w", errors.ErrUnsupported)
+				}
+				binary.BigEndian.PutUint32(entry, uint32(offset+delta))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package taglib_test
+
+import (
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestMP4TVAtoms(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egM4a, "eg.m4a")
+	tags := map[string][]string{
+		taglib.TVShow:      {"Show"},
+		taglib.ShowSort:    {"Show, The"},
+		taglib.TVSeason:    {"2"},
+		taglib.TVEpisode:   {"5"},
+		taglib.TVEpisodeID: {"S02E05"},
+		taglib.TVNetwork:   {"Network"},
+	}
+	nilErr(t, taglib.WriteTags(path, tags, taglib.Clear))
+
+	got, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+
+	// season and episode are integers for iTunes, the rest UTF-8
+	mp4DataEq(t, path, "tvsh", 1, []byte("Show"))
+	mp4DataEq(t, path, "sosn", 1, []byte("Show, The"))
+	mp4DataEq(t, path, "tvsn", 21, []byte{0, 0, 0, 2})
+	mp4DataEq(t, path, "tves", 21, []byte{0, 0, 0, 5})
+	mp4DataEq(t, path, "tven", 1, []byte("S02E05"))
+	mp4DataEq(t, path, "tvnn", 1, []byte("Network"))
+}
+
+// mp4DataEq checks the type and payload of the data atom in the item atom with the given name
+func mp4DataEq(t testing.TB, path string, name string, typ uint32, data []byte) {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	nilErr(t, err)
+
+	// an item atom holds a "data" atom with a 4 byte type, 4 bytes of locale, then the payload
+	for i := 4; i+20 <= len(b); i++ {
+		if string(b[i:i+4]) != name || string(b[i+8:i+12]) != "data" {
+			continue
+		}
+		dataSize := int(binary.BigEndian.Uint32(b[i+4:]))
+		eq(t, binary.BigEndian.Uint32(b[i+12:]), typ)
+		eq(t, string(b[i+20:i+4+dataSize]), string(data))
+		return
+	}
+	t.Fatalf("no %q item atom", name)
+}
+
+func TestMP4PodcastAtoms(t *testing.T) {
+	t.Parallel()
+
+	tags := map[string][]string{
+		taglib.Podcast:         {"1"},
+		taglib.PodcastURL:      {"https://example.com/feed.xml"},
+		taglib.PodcastID:       {"episode-guid"},
+		taglib.PodcastCategory: {"Technology"},
+		taglib.PodcastDesc:     {"Description"},
+	}
+
+	path := tmpf(t, egM4a, "eg.m4a")
+	nilErr(t, taglib.WriteTags(path, tags, taglib.Clear))
+
+	got, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+
+	// the feed URL and GUID are implicit data as iTunes writes them
+	mp4DataEq(t, path, "pcst", 21, []byte{1})
+	mp4DataEq(t, path, "purl", 0, []byte("https://example.com/feed.xml"))
+	mp4DataEq(t, path, "egid", 0, []byte("episode-guid"))
+	mp4DataEq(t, path, "catg", 1, []byte("Technology"))
+	mp4DataEq(t, path, "desc", 1, []byte("Description"))
+
+	// the same keys reach WFED, TGID, TCAT, and TDES frames in ID3v2
+	path = tmpf(t, egMP3, "eg.mp3")
+	delete(tags, taglib.Podcast)
+	nilErr(t, taglib.WriteTags(path, tags, taglib.Clear))
+
+	got, err = taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+}
+
+func TestMP4PodcastFlag(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egM4a, "eg.m4a")
+	for _, v := range []string{"1", "0"} {
+		nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Podcast: {v}}, 0))
+
+		got, err := taglib.ReadTags(path)
+		nilErr(t, err)
+		eq(t, strings.Join(got[taglib.Podcast], ""), v)
+
+		mp4DataEq(t, path, "pcst", 21, []byte{v[0] - '0'})
+	}
+
+	// an empty value removes the atom
+	nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Podcast: nil}, 0))
+
+	got, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, len(got[taglib.Podcast]), 0)
+	eq(t, got[taglib.Artist][0], "example artist")
+}
+
+func TestMP4GaplessPlayback(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egM4a, "eg.m4a")
+	for _, v := range []string{"1", "0"} {
+		tags := map[string][]string{taglib.GaplessPlayback: {v}}
+		nilErr(t, taglib.WriteTags(path, tags, 0))
+
+		got, err := taglib.ReadTags(path)
+		nilErr(t, err)
+		eq(t, strings.Join(got[taglib.GaplessPlayback], ""), v)
+
+		mp4DataEq(t, path, "pgap", 21, []byte{v[0] - '0'})
+	}
+
+	// other spellings of true and false, which TagLib would read as 0
+	for v, exp := range map[string]string{"true": "1", "Yes": "1", "false": "0", "NO": "0"} {
+		nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.GaplessPlayback: {v}}, 0))
+
+		got, err := taglib.ReadTags(path)
+		nilErr(t, err)
+		eq(t, strings.Join(got[taglib.GaplessPlayback], ""), exp)
+	}
+
+	nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.GaplessPlayback: nil}, 0))
+	got, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, len(got[taglib.GaplessPlayback]), 0)
+}
+
+func TestMP4ITunesAtoms(t *testing.T) {
+	t.Parallel()
+
+	tags := map[string][]string{
+		taglib.ITunesMediaKind:  {taglib.MediaKindAudiobook},
+		taglib.ITunesAdvisory:   {taglib.AdvisoryExplicit},
+		taglib.ITunesCatalogID:  {"1234567"},
+		taglib.ITunesCountryID:  {"143441"},
+		taglib.ITunesPlaylistID: {"1099511627776"},
+	}
+
+	path := tmpf(t, egM4a, "eg.m4a")
+	nilErr(t, taglib.WriteTags(path, tags, taglib.Clear))
+
+	got, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+
+	mp4DataEq(t, path, "stik", 21, []byte{2})
+	mp4DataEq(t, path, "rtng", 21, []byte{1})
+	mp4DataEq(t, path, "cnID", 21, []byte{0, 0x12, 0xd6, 0x87})
+	mp4DataEq(t, path, "plID", 21, []byte{0, 0, 1, 0, 0, 0, 0, 0})
+
+	// not mapped by TagLib, so cleared here
+	nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, taglib.Clear))
+	got, err = taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, map[string][]string{taglib.Title: {"Title"}})
+}
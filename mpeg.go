@@ -0,0 +1,309 @@
+package taglib
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// MPEGProperties contains properties specific to MPEG audio files, such as MP3s.
+type MPEGProperties struct {
+	// Version is the MPEG version, "1", "2", or "2.5"
+	Version string
+	// Layer is the MPEG layer, 1, 2, or 3
+	Layer uint
+	// ChannelMode is the channel mode of the first audio frame, "stereo", "joint stereo", "dual channel", or "mono"
+	ChannelMode string
+	// Copyrighted reports whether the copyright bit of the first audio frame is set
+	Copyrighted bool
+	// Original reports whether the first audio frame marks the stream as an original rather than a copy
+	Original bool
+	// Emphasis is the de-emphasis to apply on playback, "none", "50/15 ms", "CCITT J.17", or "reserved"
+	Emphasis string
+	// Protected reports whether frames are protected by a CRC-16 after the header
+	Protected bool
+	// VBR reports whether the bitrate varies between frames, as reported by a Xing or VBRI header. Files without one
+	// are assumed to be CBR unless read with [ReadMPEGLength]
+	VBR bool
+	// Header is the header in the first frame with the frame count, "Xing", "Info" (the Xing header LAME writes for CBR
+	// files), or "VBRI", empty if there is none
+	Header string
+	// HeaderFrames is the number of audio frames according to the header, 0 if unknown
+	HeaderFrames uint
+	// HeaderTrusted reports whether the header has a frame count, and a byte count within 1% of the size of the audio
+	// data. TagLib computes the length of files without a trusted header from the bitrate of the first frame, which
+	// is wrong for VBR files, so [ReadMPEGLength] should be used instead
+	HeaderTrusted bool
+}
+
+// MPEGLength is the result of walking the frames of an MPEG audio file.
+type MPEGLength struct {
+	// Length is the duration of the audio frames, not counting a Xing or VBRI header frame
+	Length time.Duration
+	// Frames is the number of audio frames
+	Frames uint
+	// VBR reports whether any audio frame has a different bitrate to the first
+	VBR bool
+}
+
+// ReadMPEGLength computes the exact length of an MPEG audio file at the given path by reading the header of every
+// frame, for files where [MPEGProperties.HeaderTrusted] is false. This reads the whole file.
+func ReadMPEGLength(path string) (_ MPEGLength, err error) {
+	defer wrapErr(&err, "read mpeg length", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return MPEGLength{}, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	start, end, err := mpegAudioRange(f)
+	if err != nil {
+		return MPEGLength{}, err
+	}
+	var first [4]byte
+	if _, err := f.ReadAt(first[:], start); err != nil || !isMPEGHeader(first[:]) {
+		return MPEGLength{}, fmt.Errorf("find first frame: %w", ErrInvalidFile)
+	}
+	firstHeader := parseMPEGHeader(first[:])
+	if mpegVBRHeader(f, start).name != "" {
+		start += int64(firstHeader.frameSize) // holds no audio
+	}
+
+	var length MPEGLength
+	var samples uint64
+	var bitrate int
+	br := bufio.NewReaderSize(io.NewSectionReader(f, start, end-start), 1<<16)
+	for {
+		b, err := br.Peek(4)
+		if err != nil {
+			break
+		}
+		// frames must match the version, layer, and sample rate of the first, which avoids false syncs in the audio
+		if !isMPEGHeader(b) || b[1]&^0x01 != first[1]&^0x01 || b[2]&0x0c != first[2]&0x0c {
+			if _, err := br.Discard(1); err != nil {
+				break
+			}
+			continue
+		}
+		h := parseMPEGHeader(b)
+		if length.Frames == 0 {
+			bitrate = h.bitrate
+		} else if h.bitrate != bitrate {
+			length.VBR = true
+		}
+		length.Frames++
+		samples += uint64(h.samples)
+		if _, err := br.Discard(h.frameSize); err != nil {
+			break
+		}
+	}
+	if firstHeader.sampleRate > 0 {
+		length.Length = time.Duration(samples * uint64(time.Second) / uint64(firstHeader.sampleRate))
+	}
+	return length, nil
+}
+
+// readMPEGProperties reads the MPEG properties TagLib doesn't expose, or nil if the file isn't MPEG audio, and the
+// bitrate of the first audio frame in kbit/s.
+func readMPEGProperties(f fileReader) (*MPEGProperties, int, error) {
+	start, end, err := mpegAudioRange(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	var b [4]byte
+	if _, err := f.ReadAt(b[:], start); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	if !isMPEGHeader(b[:]) {
+		return nil, 0, nil
+	}
+
+	h := parseMPEGHeader(b[:])
+	props := &MPEGProperties{Version: h.version, Layer: h.layer}
+
+	vbr := mpegVBRHeader(f, start)
+	props.Header = vbr.name
+	props.VBR = vbr.name == "Xing" || vbr.name == "VBRI"
+	props.HeaderFrames = uint(vbr.frames)
+	if vbr.frames > 0 {
+		size := end - start
+		props.HeaderTrusted = !vbr.hasBytes || abs(int64(vbr.bytes)-size) <= size/100
+	}
+
+	// the flags are read from the first audio frame, since encoders don't always set them in the header frame
+	if vbr.name != "" {
+		var next [4]byte
+		if _, err := f.ReadAt(next[:], start+int64(h.frameSize)); err == nil && isMPEGHeader(next[:]) {
+			b = next
+		}
+	}
+	props.ChannelMode = mpegChannelModes[b[3]>>6]
+	props.Copyrighted = b[3]&0x08 != 0
+	props.Original = b[3]&0x04 != 0
+	props.Emphasis = mpegEmphases[b[3]&0x03]
+	props.Protected = b[1]&0x01 == 0
+	return props, parseMPEGHeader(b[:]).bitrate, nil
+}
+
+// mpegAudioRange finds the start of the first frame of an MPEG audio file, after any ID3v2 tag and zero padding, and
+// the end of the audio before any trailing tags.
+func mpegAudioRange(f fileReader) (int64, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("stat: %w", err)
+	}
+	start, err := id3v2TagSize(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("read id3v2 size: %w", err)
+	}
+	end, err := trailingTagsStart(f, info.Size())
+	if err != nil {
+		return 0, 0, fmt.Errorf("find trailing tags: %w", err)
+	}
+	start, err = skipZeros(f, start, end)
+	if err != nil {
+		return 0, 0, fmt.Errorf("skip padding: %w", err)
+	}
+	return start, end, nil
+}
+
+// mpegHeader is a decoded MPEG audio frame header.
+type mpegHeader struct {
+	version    string
+	layer      uint
+	bitrate    int // kbit/s
+	sampleRate int
+	samples    int // per frame
+	frameSize  int // in bytes, including the header
+}
+
+var (
+	mpegBitrates = [2][3][16]int{
+		{ // MPEG 1, layers 1 to 3
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		},
+		{ // MPEG 2 and 2.5, layers 1 to 3
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		},
+	}
+	mpegSampleRates  = [3]int{44100, 48000, 32000}
+	mpegEmphases     = [4]string{"none", "50/15 ms", "reserved", "CCITT J.17"}
+	mpegChannelModes = [4]string{"stereo", "joint stereo", "dual channel", "mono"}
+)
+
+// parseMPEGHeader decodes a frame header, which must pass [isMPEGHeader].
+func parseMPEGHeader(b []byte) mpegHeader {
+	var h mpegHeader
+	var divisor int
+	switch b[1] >> 3 & 0x03 {
+	case 3:
+		h.version, divisor = "1", 1
+	case 2:
+		h.version, divisor = "2", 2
+	default:
+		h.version, divisor = "2.5", 4
+	}
+	h.layer = uint(4 - b[1]>>1&0x03)
+	h.sampleRate = mpegSampleRates[b[2]>>2&0x03] / divisor
+
+	table := 0
+	if h.version != "1" {
+		table = 1
+	}
+	h.bitrate = mpegBitrates[table][h.layer-1][b[2]>>4]
+
+	padding := int(b[2] >> 1 & 0x01)
+	switch {
+	case h.layer == 1:
+		h.samples = 384
+		h.frameSize = (12*h.bitrate*1000/h.sampleRate + padding) * 4
+	case h.layer == 3 && h.version != "1":
+		h.samples = 576
+		h.frameSize = 72*h.bitrate*1000/h.sampleRate + padding
+	default:
+		h.samples = 1152
+		h.frameSize = 144*h.bitrate*1000/h.sampleRate + padding
+	}
+	return h
+}
+
+// mpegVBR is a Xing, Info, or VBRI header from the first frame of an MPEG audio file.
+type mpegVBR struct {
+	name     string
+	frames   uint32
+	bytes    uint32
+	hasBytes bool
+}
+
+// mpegVBRHeader reads the Xing, Info, or VBRI header of the frame at offset, which follows the side information or
+// is at a fixed offset of 32 bytes after the frame header.
+func mpegVBRHeader(r io.ReaderAt, offset int64) mpegVBR {
+	frame := make([]byte, 4+2+32+16)
+	n, _ := r.ReadAt(frame, offset)
+	frame = frame[:n]
+	if len(frame) < 4 || !isMPEGHeader(frame) {
+		return mpegVBR{}
+	}
+
+	if pos := 4 + 32; len(frame) >= pos+18 && string(frame[pos:pos+4]) == "VBRI" {
+		return mpegVBR{
+			name:     "VBRI",
+			bytes:    binary.BigEndian.Uint32(frame[pos+10:]),
+			frames:   binary.BigEndian.Uint32(frame[pos+14:]),
+			hasBytes: true,
+		}
+	}
+
+	mpeg1 := frame[1]&0x18 == 0x18
+	mono := frame[3]&0xc0 == 0xc0
+	pos := 4
+	if frame[1]&0x01 == 0 {
+		pos += 2 // CRC
+	}
+	switch { // side information
+	case mpeg1 && !mono:
+		pos += 32
+	case mpeg1 || !mono:
+		pos += 17
+	default:
+		pos += 9
+	}
+	if len(frame) < pos+8 {
+		return mpegVBR{}
+	}
+	name := string(frame[pos : pos+4])
+	if name != "Xing" && name != "Info" {
+		return mpegVBR{}
+	}
+
+	vbr := mpegVBR{name: name}
+	flags := binary.BigEndian.Uint32(frame[pos+4:])
+	pos += 8
+	if flags&0x1 != 0 && len(frame) >= pos+4 {
+		vbr.frames = binary.BigEndian.Uint32(frame[pos:])
+		pos += 4
+	}
+	if flags&0x2 != 0 && len(frame) >= pos+4 {
+		vbr.bytes = binary.BigEndian.Uint32(frame[pos:])
+		vbr.hasBytes = true
+	}
+	return vbr
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+package taglib_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestMPEGProperties(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	if properties.MPEG == nil {
+		t.Fatalf("no mpeg properties")
+	}
+	eq(t, *properties.MPEG, taglib.MPEGProperties{
+		Version:       "1",
+		Layer:         3,
+		ChannelMode:   "joint stereo",
+		Original:      true,
+		Emphasis:      "none",
+		Header:        "Info",
+		HeaderFrames:  40,
+		HeaderTrusted: true,
+	})
+	eq(t, properties.NominalBitrate, 128)
+	eq(t, fmt.Sprintf("%.1f", properties.AverageBitrate), "129.6")
+
+	properties, err = taglib.ReadProperties(tmpf(t, egFLAC, "eg.flac"))
+	nilErr(t, err)
+	eq(t, properties.MPEG, nil)
+}
+
+func TestReadMPEGLength(t *testing.T) {
+	t.Parallel()
+
+	length, err := taglib.ReadMPEGLength(tmpf(t, egMP3, "eg.mp3"))
+	nilErr(t, err)
+	eq(t, length.Frames, 40)
+	eq(t, length.VBR, false)
+	eq(t, length.Length.Round(time.Millisecond), 1045*time.Millisecond)
+
+	// without the first frame, which holds the Info header
+	audio := egMP3[10+(int(egMP3[6])<<21|int(egMP3[7])<<14|int(egMP3[8])<<7|int(egMP3[9])):]
+	path := tmpf(t, audio[208:], "eg.mp3")
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.MPEG.Header, "")
+	eq(t, properties.MPEG.HeaderTrusted, false)
+
+	length, err = taglib.ReadMPEGLength(path)
+	nilErr(t, err)
+	eq(t, length.Frames, 40)
+}
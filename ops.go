@@ -0,0 +1,114 @@
+package taglib
+
+import (
+	"fmt"
+	gopath "path"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidOp is returned by [WriteTagsOps] for an operation it can't apply.
+var ErrInvalidOp = fmt.Errorf("invalid op")
+
+// TagOpKind is how a [TagOp] changes the values of a key.
+type TagOpKind uint8
+
+const (
+	// TagReplace replaces the values of the key, like [WriteTags]
+	TagReplace TagOpKind = iota
+	// TagAppend adds values after the existing values of the key
+	TagAppend
+	// TagRemove removes the given values from the key, or the whole key if no values are given
+	TagRemove
+)
+
+// TagOp is an operation on the values of one key, applied by [WriteTagsOps].
+type TagOp struct {
+	Kind   TagOpKind
+	Key    string
+	Values []string
+}
+
+// WriteTagsOps applies ops in order to the tags of the file at path, then writes the keys they changed, leaving the
+// other keys as they are. Unlike [WriteTags], which replaces the values of each key, ops can append to or remove
+// values from some keys and replace others in one write. The write can be configured with opts, except [Clear].
+func WriteTagsOps(path string, ops []TagOp, opts ...WriteOptionFunc) (err error) {
+	defer wrapErr(&err, "write tags", path)
+	cfg := newWriteConfig(0)
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.opts&Clear != 0 {
+		return fmt.Errorf("clear with ops: %w", ErrInvalidOp)
+	}
+
+	current, err := readTags(path, nil)
+	if err != nil {
+		return fmt.Errorf("read tags: %w", err)
+	}
+	tags, err := applyTagOps(current, ops)
+	if err != nil {
+		return err
+	}
+	return writeTags(path, tags, nil, cfg)
+}
+
+// applyTagOps applies ops to the current tags, returning the new values of each key the ops change.
+func applyTagOps(current map[string][]string, ops []TagOp) (map[string][]string, error) {
+	tags := map[string][]string{}
+	values := func(key string) []string {
+		if vs, ok := tags[key]; ok {
+			return vs
+		}
+		return current[key]
+	}
+	for _, op := range ops {
+		key := strings.ToUpper(op.Key)
+		switch op.Kind {
+		case TagReplace:
+			tags[key] = slices.Clone(op.Values)
+		case TagAppend:
+			tags[key] = append(slices.Clone(values(key)), op.Values...)
+		case TagRemove:
+			if len(op.Values) == 0 {
+				tags[key] = nil
+				continue
+			}
+			tags[key] = slices.DeleteFunc(slices.Clone(values(key)), func(v string) bool {
+				return slices.Contains(op.Values, v)
+			})
+		default:
+			return nil, fmt.Errorf("kind %d of %q: %w", op.Kind, op.Key, ErrInvalidOp)
+		}
+	}
+	return tags, nil
+}
+
+// DeleteTagsMatching removes the keys of the file at path which match pattern, such as "MUSICBRAINZ_*" or
+// "REPLAYGAIN_*", and returns the keys removed. Patterns have the syntax of [path.Match] and match keys regardless of
+// case. The file is only written if some keys match.
+func DeleteTagsMatching(path string, pattern string) (_ []string, err error) {
+	defer wrapErr(&err, "delete tags", path)
+	pattern = strings.ToUpper(pattern)
+	if _, err := gopath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("match %q: %w", pattern, err)
+	}
+
+	current, err := readTags(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("read tags: %w", err)
+	}
+	var deleted []string
+	tags := map[string][]string{}
+	for k := range current {
+		if ok, _ := gopath.Match(pattern, k); ok {
+			deleted = append(deleted, k)
+			tags[k] = nil
+		}
+	}
+	if len(deleted) == 0 {
+		return nil, nil
+	}
+	slices.Sort(deleted)
+	return deleted, writeTags(path, tags, nil, newWriteConfig(0))
+}
@@ -0,0 +1,83 @@
+package taglib_test
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestWriteTagsOps(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range testPaths(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := taglib.WriteTags(path, map[string][]string{
+				taglib.Title:  {"Title"},
+				taglib.Artist: {"Artist 1", "Artist 2"},
+				taglib.Genre:  {"Rock", "Pop", "Jazz"},
+				taglib.Album:  {"Album"},
+			}, taglib.Clear)
+			nilErr(t, err)
+
+			err = taglib.WriteTagsOps(path, []taglib.TagOp{
+				{Kind: taglib.TagReplace, Key: taglib.Title, Values: []string{"New"}},
+				{Kind: taglib.TagAppend, Key: taglib.Artist, Values: []string{"Artist 3"}},
+				{Kind: taglib.TagRemove, Key: taglib.Genre, Values: []string{"Pop"}},
+				{Kind: taglib.TagRemove, Key: taglib.Album},
+				{Kind: taglib.TagAppend, Key: taglib.Comment, Values: []string{"Comment"}},
+			})
+			nilErr(t, err)
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{
+				taglib.Title:   {"New"},
+				taglib.Artist:  {"Artist 1", "Artist 2", "Artist 3"},
+				taglib.Genre:   {"Rock", "Jazz"},
+				taglib.Comment: {"Comment"},
+			})
+		})
+	}
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	err := taglib.WriteTagsOps(path, []taglib.TagOp{{Kind: 10, Key: taglib.Title}})
+	eq(t, errors.Is(err, taglib.ErrInvalidOp), true)
+	err = taglib.WriteTagsOps(path, nil, taglib.WithClear())
+	eq(t, errors.Is(err, taglib.ErrInvalidOp), true)
+}
+
+func TestDeleteTagsMatching(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	err := taglib.WriteTags(path, map[string][]string{
+		taglib.Title:               {"Title"},
+		taglib.MusicBrainzAlbumID:  {"album id"},
+		taglib.MusicBrainzTrackID:  {"track id"},
+		taglib.ReplayGainTrackGain: {"-6.00 dB"},
+		taglib.ReplayGainAlbumGain: {"-7.00 dB"},
+	}, taglib.Clear)
+	nilErr(t, err)
+
+	deleted, err := taglib.DeleteTagsMatching(path, "musicbrainz_*")
+	nilErr(t, err)
+	eq(t, slices.Equal(deleted, []string{taglib.MusicBrainzAlbumID, taglib.MusicBrainzTrackID}), true)
+
+	deleted, err = taglib.DeleteTagsMatching(path, "REPLAYGAIN_*_GAIN")
+	nilErr(t, err)
+	eq(t, len(deleted), 2)
+
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}})
+
+	deleted, err = taglib.DeleteTagsMatching(path, "NONE*")
+	nilErr(t, err)
+	eq(t, len(deleted), 0)
+
+	_, err = taglib.DeleteTagsMatching(path, "[")
+	eq(t, err != nil, true)
+}
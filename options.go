@@ -0,0 +1,185 @@
+package taglib
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WriteOptionFunc configures a write with [WriteTagsOptions]. Unlike [WriteOption], options can carry values, such as
+// the amount of padding to leave.
+type WriteOptionFunc func(*writeConfig)
+
+// writeConfig is the combined configuration of a write.
+type writeConfig struct {
+	opts            WriteOption
+	id3v2Version    uint
+	padding         int64 // -1 to keep TagLib's padding
+	atomicRename    bool
+	preserveMtime   bool
+	preserveUnknown bool
+	verify          bool
+	skipUnchanged   bool
+	caseSensitive   bool
+}
+
+func newWriteConfig(opts WriteOption) writeConfig {
+	return writeConfig{opts: opts, padding: -1}
+}
+
+// WriteTagsOptions writes the metadata key-values pairs to path like [WriteTags], configured with opts.
+func WriteTagsOptions(path string, tags map[string][]string, opts ...WriteOptionFunc) (err error) {
+	defer wrapErr(&err, "write tags", path)
+	cfg := newWriteConfig(0)
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return writeTags(path, tags, nil, cfg)
+}
+
+// WithOptions adds the [WriteOption] flags in opts, such as [SkipID3v2] or [ID3v2Footer].
+func WithOptions(opts WriteOption) WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.opts |= opts }
+}
+
+// WithClear removes all existing tags not present in the new map, like [Clear].
+func WithClear() WriteOptionFunc {
+	return WithOptions(Clear)
+}
+
+// WithID3v2Version saves ID3v2 tags as version 3 or 4, the default. The bundled binary only saves ID3v2.4, so version 3
+// fails with [errors.ErrUnsupported] before the file is changed for files with ID3v2 tags, as do other versions for
+// any file. [DroppedFrames] still reports the frames ID3v2.3 can't hold.
+func WithID3v2Version(version uint) WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.id3v2Version = version }
+}
+
+// WithPadding resizes the padding of an ID3v2 tag at the start of the file and the PADDING blocks of FLAC files to n
+// bytes after saving, as reported by [ReadPadding], rewriting the file if the size changes. WithPadding(0) is like
+// [Compact].
+func WithPadding(n int64) WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.padding = max(n, 0) }
+}
+
+// WithAtomicRename writes to a copy of the file in the same directory, then renames it over the original. Readers
+// see either the old or the new file, never one being written, at the cost of copying the whole file.
+func WithAtomicRename() WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.atomicRename = true }
+}
+
+// WithPreserveMtime restores the modification time of the file after writing, so tools which sync or scan by
+// modification time don't see a metadata only change.
+func WithPreserveMtime() WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.preserveMtime = true }
+}
+
+// WithSkipUnchanged reads the tags first, and leaves the file untouched if each key written already has the same
+// values, or none if it is written without values, and with [Clear] if there are no other keys. Idempotent tagging
+// jobs then avoid needless writes, modification time changes, and sync churn. Files are always saved with options
+// which change their layout, [Compact], [ID3v2Footer], [ID3v2Unsynchronisation], [WithPadding], and
+// [WithID3v2Version].
+func WithSkipUnchanged() WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.skipUnchanged = true }
+}
+
+// tagsUnchanged reports whether writing tags with cfg would leave the file at path as it is.
+func tagsUnchanged(path string, tags map[string][]string, m Mapping, cfg writeConfig) (bool, error) {
+	if cfg.opts&(Compact|ID3v2Footer|ID3v2Unsynchronisation) != 0 || cfg.padding >= 0 || cfg.id3v2Version != 0 {
+		return false, nil
+	}
+	current, err := readTags(path, m)
+	if err != nil {
+		return false, err
+	}
+	written := map[string]bool{}
+	for k, vs := range tags {
+		k = strings.ToUpper(k)
+		written[k] = true
+		if !slices.Equal(current[k], vs) {
+			return false, nil
+		}
+	}
+	if cfg.opts&Clear != 0 {
+		for k := range current {
+			if !written[k] {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// WithCaseSensitiveKeys passes keys which differ only in case, such as "Albumartist" and "ALBUMARTIST", to TagLib as
+// they are. By default they are written as one key, with the values of each in the order of the keys, since TagLib
+// would otherwise keep the values of whichever key it saw last.
+func WithCaseSensitiveKeys() WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.caseSensitive = true }
+}
+
+// foldKeys merges the keys of tags which differ only in case into the upper case key, appending their values in the
+// order of the keys. Maps without such keys are returned as they are.
+func foldKeys(tags map[string][]string) map[string][]string {
+	seen := map[string]bool{}
+	fold := false
+	for k := range tags {
+		upper := strings.ToUpper(k)
+		fold = fold || seen[upper]
+		seen[upper] = true
+	}
+	if !fold {
+		return tags
+	}
+
+	folded := map[string][]string{}
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		upper := strings.ToUpper(k)
+		folded[upper] = append(folded[upper], tags[k]...)
+	}
+	return folded
+}
+
+// copyToTemp copies the file at path to a temporary file in dir with the same extension, which TagLib uses to detect
+// the format, and returns its path and the mode of the original. The copy is left writable until it is given the mode.
+func copyToTemp(path, dir string) (string, os.FileMode, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("open: %w", err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return "", 0, fmt.Errorf("stat: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".taglib-*"+filepath.Ext(path))
+	if err != nil {
+		return "", 0, fmt.Errorf("create temp: %w", err)
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, fmt.Errorf("copy: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, fmt.Errorf("close temp: %w", err)
+	}
+	return tmp.Name(), info.Mode(), nil
+}
+
+// isFLACFile reports whether the file at path is a FLAC file, after any leading ID3v2 tag.
+func isFLACFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	_, err = readFLACBlocks(f)
+	return err == nil
+}
@@ -0,0 +1,219 @@
+package taglib_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestWriteTagsOptions(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	err := taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"Title"}}, taglib.WithClear())
+	nilErr(t, err)
+
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}})
+}
+
+func TestWithPadding(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{tmpf(t, egFLAC, "eg.flac"), tmpf(t, egMP3, "eg.mp3")} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			for _, n := range []int64{100, 0, 5000} {
+				err := taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"Title"}}, taglib.WithPadding(n))
+				nilErr(t, err)
+
+				padding, err := taglib.ReadPadding(path)
+				nilErr(t, err)
+				eq(t, padding, n)
+
+				tags, err := taglib.ReadTags(path)
+				nilErr(t, err)
+				eq(t, tags[taglib.Title][0], "Title")
+			}
+		})
+	}
+}
+
+func TestWithPaddingKeepsLinks(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{tmpf(t, egFLAC, "eg.flac"), tmpf(t, egMP3, "eg.mp3")} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			hard := path + ".hard"
+			nilErr(t, os.Link(path, hard))
+			sym := path + ".sym"
+			nilErr(t, os.Symlink(path, sym))
+
+			// shrinking and growing the padding both move the audio
+			for _, n := range []int64{0, 5000} {
+				err := taglib.WriteTagsOptions(sym, map[string][]string{taglib.Title: {"Title"}}, taglib.WithPadding(n))
+				nilErr(t, err)
+
+				info, err := os.Lstat(sym)
+				nilErr(t, err)
+				eq(t, info.Mode()&os.ModeSymlink != 0, true)
+
+				a, err := os.Stat(path)
+				nilErr(t, err)
+				b, err := os.Stat(hard)
+				nilErr(t, err)
+				eq(t, os.SameFile(a, b), true)
+
+				padding, err := taglib.ReadPadding(hard)
+				nilErr(t, err)
+				eq(t, padding, n)
+				properties, err := taglib.ReadProperties(hard)
+				nilErr(t, err)
+				eq(t, properties.Length > 0, true)
+			}
+		})
+	}
+}
+
+func TestWithAtomicRename(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	nilErr(t, os.Chmod(path, 0o640))
+
+	err := taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"Title"}}, taglib.WithAtomicRename())
+	nilErr(t, err)
+
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, tags[taglib.Title][0], "Title")
+
+	info, err := os.Stat(path)
+	nilErr(t, err)
+	eq(t, info.Mode().Perm(), os.FileMode(0o640))
+
+	// no temporary files left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	nilErr(t, err)
+	eq(t, len(entries), 1)
+}
+
+func TestWithPreserveMtime(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	nilErr(t, os.Chtimes(path, mtime, mtime))
+
+	err := taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"Title"}}, taglib.WithPreserveMtime(), taglib.WithPadding(0))
+	nilErr(t, err)
+
+	info, err := os.Stat(path)
+	nilErr(t, err)
+	eq(t, info.ModTime().Equal(mtime), true)
+}
+
+func TestWithID3v2VersionInvalid(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	err := taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"Title"}}, taglib.WithID3v2Version(2))
+	eq(t, errors.Is(err, errors.ErrUnsupported), true)
+}
+
+func TestWithID3v2Version3(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egMP3, "eg.mp3")
+	before, err := os.ReadFile(path)
+	nilErr(t, err)
+
+	// the binary can't save ID3v2.3, so the file is left as is
+	err = taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"Title"}}, taglib.WithID3v2Version(3))
+	eq(t, errors.Is(err, errors.ErrUnsupported), true)
+	after, err := os.ReadFile(path)
+	nilErr(t, err)
+	eq(t, bytes.Equal(before, after), true)
+
+	// FLAC files have no ID3v2 tag to save
+	path = tmpf(t, egFLAC, "eg.flac")
+	nilErr(t, taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"Title"}}, taglib.WithID3v2Version(3)))
+}
+
+func TestWithVerify(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range testPaths(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := taglib.WriteTagsOptions(path, map[string][]string{
+				taglib.Title:  {"Title"},
+				taglib.Artist: {"Artist 1", "Artist 2"},
+				taglib.Album:  nil,
+			}, taglib.WithVerify())
+			nilErr(t, err)
+		})
+	}
+
+	// MP4 track numbers are stored as integers
+	path := tmpf(t, egM4a, "eg.m4a")
+	err := taglib.WriteTagsOptions(path, map[string][]string{taglib.TrackNumber: {"three"}}, taglib.WithVerify())
+	eq(t, errors.Is(err, taglib.ErrVerifyFailed), true)
+}
+
+func TestWithSkipUnchanged(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	tags := map[string][]string{taglib.Title: {"Title"}, taglib.Album: nil}
+	nilErr(t, taglib.WriteTags(path, tags, 0))
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	nilErr(t, os.Chtimes(path, mtime, mtime))
+	unchanged := func() bool {
+		info, err := os.Stat(path)
+		nilErr(t, err)
+		return info.ModTime().Equal(mtime)
+	}
+
+	nilErr(t, taglib.WriteTagsOptions(path, tags, taglib.WithSkipUnchanged()))
+	eq(t, unchanged(), true)
+
+	// the existing artist would be cleared
+	nilErr(t, taglib.WriteTagsOptions(path, tags, taglib.WithClear(), taglib.WithSkipUnchanged()))
+	eq(t, unchanged(), false)
+
+	nilErr(t, os.Chtimes(path, mtime, mtime))
+	nilErr(t, taglib.WriteTagsOptions(path, tags, taglib.WithClear(), taglib.WithSkipUnchanged()))
+	eq(t, unchanged(), true)
+
+	nilErr(t, taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"Other"}}, taglib.WithSkipUnchanged()))
+	eq(t, unchanged(), false)
+}
+
+func TestCaseInsensitiveKeys(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range testPaths(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := taglib.WriteTags(path, map[string][]string{
+				"albumartist": {"Artist 3"},
+				"Albumartist": {"Artist 2"},
+				"ALBUMARTIST": {"Artist 1"},
+				"title":       {"Title"},
+			}, taglib.Clear)
+			nilErr(t, err)
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{
+				taglib.AlbumArtist: {"Artist 1", "Artist 2", "Artist 3"},
+				taglib.Title:       {"Title"},
+			})
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package taglib
+
+import (
+	"sort"
+	"strings"
+)
+
+// PerformerCredit is a performer with the instrument or role they performed, such as "violin" or "soprano vocals".
+type PerformerCredit struct {
+	Name       string
+	Instrument string
+}
+
+// PerformerStyle chooses how [SetPerformers] stores instruments.
+type PerformerStyle uint8
+
+const (
+	// PerformerKeys stores each instrument in its own key, like "PERFORMER:VIOLIN" = "Jane Doe". TagLib maps these keys
+	// to the TMCL frame of ID3v2 tags, so this style carries over to MP3 and the ID3v2 credits.
+	PerformerKeys PerformerStyle = iota
+	// PerformerParentheses stores instruments in the value, like "PERFORMER" = "Jane Doe (violin)", as written by
+	// MusicBrainz Picard to Vorbis comments and APE tags.
+	PerformerParentheses
+)
+
+const performerPrefix = Performer + ":"
+
+// Performers parses the performers in tags read with [ReadTags], from both "PERFORMER:INSTRUMENT" keys and
+// "PERFORMER" values with the instrument in parentheses. Instruments from keys are lower case.
+func Performers(tags map[string][]string) []PerformerCredit {
+	var performers []PerformerCredit
+	for _, v := range tags[Performer] {
+		performers = append(performers, parsePerformer(v))
+	}
+
+	var keys []string
+	for k := range tags {
+		if strings.HasPrefix(strings.ToUpper(k), performerPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		instrument := strings.ToLower(k[len(performerPrefix):])
+		for _, v := range tags[k] {
+			performers = append(performers, PerformerCredit{Name: v, Instrument: instrument})
+		}
+	}
+	return performers
+}
+
+// SetPerformers replaces the performers in tags with performers stored in style, ready for [WriteTags]. Existing
+// performer keys are set to no values, so writing them removes them from the file.
+func SetPerformers(tags map[string][]string, performers []PerformerCredit, style PerformerStyle) {
+	for k := range tags {
+		if strings.EqualFold(k, Performer) || strings.HasPrefix(strings.ToUpper(k), performerPrefix) {
+			tags[k] = nil
+		}
+	}
+	for _, p := range performers {
+		switch {
+		case p.Instrument == "":
+			tags[Performer] = append(tags[Performer], p.Name)
+		case style == PerformerParentheses:
+			tags[Performer] = append(tags[Performer], p.Name+" ("+p.Instrument+")")
+		default:
+			k := performerPrefix + strings.ToUpper(p.Instrument)
+			tags[k] = append(tags[k], p.Name)
+		}
+	}
+}
+
+func parsePerformer(v string) PerformerCredit {
+	if !strings.HasSuffix(v, ")") {
+		return PerformerCredit{Name: v}
+	}
+	i := strings.LastIndex(v, " (")
+	if i < 0 {
+		return PerformerCredit{Name: v}
+	}
+	return PerformerCredit{Name: v[:i], Instrument: v[i+2 : len(v)-1]}
+}
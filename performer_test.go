@@ -0,0 +1,53 @@
+package taglib_test
+
+import (
+	"reflect"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestPerformers(t *testing.T) {
+	t.Parallel()
+
+	performers := []taglib.PerformerCredit{
+		{Name: "Jane Doe", Instrument: "violin"},
+		{Name: "John Doe", Instrument: "viola"},
+		{Name: "Orchestra"},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		data  []byte
+		style taglib.PerformerStyle
+	}{
+		{"eg.flac", egFLAC, taglib.PerformerParentheses},
+		{"eg.flac", egFLAC, taglib.PerformerKeys},
+		{"eg.mp3", egMP3, taglib.PerformerKeys},
+	} {
+		path := tmpf(t, tc.data, tc.name)
+
+		// replaces existing credits in either convention
+		nilErr(t, taglib.WriteTags(path, map[string][]string{
+			taglib.Performer:  {"Old (cello)"},
+			"PERFORMER:PIANO": {"Old"},
+		}, 0))
+
+		tags, err := taglib.ReadTags(path)
+		nilErr(t, err)
+		taglib.SetPerformers(tags, performers, tc.style)
+		nilErr(t, taglib.WriteTags(path, tags, 0))
+
+		tags, err = taglib.ReadTags(path)
+		nilErr(t, err)
+
+		got := taglib.Performers(tags)
+		exp := performers
+		if tc.style == taglib.PerformerKeys {
+			exp = []taglib.PerformerCredit{performers[2], performers[1], performers[0]}
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Fatalf("%s %d: %+v != %+v", tc.name, tc.style, got, exp)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+)
+
+// RF64 and Sony Wave64 are WAV containers with 64 bit sizes, used for recordings over 4 GB, which TagLib doesn't
+// support. RF64 files, and BW64 files which share their layout, are RIFF files whose sizes over 4 GB are moved to a
+// "ds64" chunk. Wave64 files have chunks with 16 byte GUIDs and 64 bit sizes, aligned to 8 bytes.
+var (
+	w64RIFFGUID = []byte{'r', 'i', 'f', 'f', 0x2e, 0x91, 0xcf, 0x11, 0xa5, 0xd6, 0x28, 0xdb, 0x04, 0xc1, 0x00, 0x00}
+	w64WAVEGUID = []byte{'w', 'a', 'v', 'e', 0xf3, 0xac, 0xd3, 0x11, 0x8c, 0xd1, 0x00, 0xc0, 0x4f, 0x8e, 0xdb, 0x8a}
+)
+
+// riffInfoKeys are the keys TagLib reads the fields of a RIFF INFO chunk as.
+var riffInfoKeys = map[string]string{
+	"IART": Artist,
+	"IBPM": BPM,
+	"IBSU": ArtistWebpage,
+	"ICMT": Comment,
+	"ICNT": ReleaseCountry,
+	"ICOP": Copyright,
+	"ICRD": Date,
+	"IDIT": EncodingTime,
+	"IEDT": Remixer,
+	"IENG": Arranger,
+	"IGNR": Genre,
+	"ILNG": Language,
+	"IMED": Media,
+	"IMUS": Composer,
+	"INAM": Title,
+	"IPRD": Album,
+	"IPRT": TrackNumber,
+	"IPUB": Label,
+	"ISFT": Encoding,
+	"ISRC": ISRC,
+	"ISTR": Performer,
+	"ITCH": EncodedBy,
+	"IWRI": Lyricist,
+}
+
+// wideWAV is the chunks of an RF64 or Wave64 file. Chunks the file doesn't have are empty.
+type wideWAV struct {
+	format, data, info, id3 region
+	// sampleFrames is the sample count of the "ds64" chunk of RF64 files, or 0 if unknown
+	sampleFrames uint64
+}
+
+// isWideWAV reports whether a file starting with magic is an RF64 or Wave64 file.
+func isWideWAV(magic []byte) bool {
+	return bytes.HasPrefix(magic, []byte("RF64")) || bytes.HasPrefix(magic, []byte("BW64")) ||
+		bytes.HasPrefix(magic, w64RIFFGUID)
+}
+
+// wideWAVChunks finds the chunks of an RF64 or Wave64 file, or returns nil for other files.
+func wideWAVChunks(r io.ReaderAt, end int64) *wideWAV {
+	var magic [16]byte
+	if _, err := r.ReadAt(magic[:], 0); err != nil {
+		return nil
+	}
+	if !isWideWAV(magic[:]) {
+		return nil
+	}
+
+	var w wideWAV
+	add := func(id string, payload region) {
+		switch id {
+		case "fmt ":
+			w.format = payload
+		case "data":
+			w.data = payload
+		case "id3 ", "ID3 ":
+			w.id3 = payload
+		case "LIST", "list":
+			if payload.end-payload.start >= 4 && hasAt(r, payload.start, "INFO") {
+				w.info = region{payload.start + 4, payload.end}
+			}
+		}
+	}
+
+	if bytes.Equal(magic[:], w64RIFFGUID) {
+		var header [24]byte
+		for offset := int64(16 + 8 + 16); offset+24 <= end; {
+			if _, err := r.ReadAt(header[:], offset); err != nil {
+				break
+			}
+			size := int64(binary.LittleEndian.Uint64(header[16:]))
+			if size < 24 || size > end-offset {
+				size = end - offset
+			}
+			// chunks of the WAV format have GUIDs made of their RIFF ID and one of two suffixes
+			id := string(header[:4])
+			if bytes.Equal(header[4:16], w64WAVEGUID[4:]) || bytes.Equal(header[4:16], w64RIFFGUID[4:]) {
+				add(id, region{offset + 24, offset + size})
+			}
+			offset += (size + 7) &^ 7
+		}
+		return &w
+	}
+
+	var dataSize int64 = -1
+	var header [8]byte
+	for offset := int64(12); offset+8 <= end; {
+		if _, err := r.ReadAt(header[:], offset); err != nil {
+			break
+		}
+		id := string(header[:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:]))
+		payload := offset + 8
+		if id == "data" && size == 0xffffffff && dataSize >= 0 {
+			size = dataSize
+		}
+		if size > end-payload {
+			size = end - payload
+		}
+		if id == "ds64" && size >= 24 {
+			var ds64 [24]byte
+			if _, err := r.ReadAt(ds64[:], payload); err == nil {
+				dataSize = int64(binary.LittleEndian.Uint64(ds64[8:]))
+				w.sampleFrames = binary.LittleEndian.Uint64(ds64[16:])
+			}
+		}
+		add(id, region{payload, payload + size})
+		offset = payload + size + size%2
+	}
+	return &w
+}
+
+// readWideWAV reads the tags and properties of an RF64 or Wave64 file at path. Tags are read from the ID3v2 chunk, or
+// from the RIFF INFO chunk if there is none, like TagLib reads WAV files. It reports false for other files, including
+// those which can't be opened, leaving them to TagLib.
+func readWideWAV(path string) (map[string][]string, Properties, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Properties{}, false, nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, Properties{}, false, err
+	}
+	w := wideWAVChunks(f, info.Size())
+	if w == nil {
+		return nil, Properties{}, false, nil
+	}
+
+	read := func(r region) ([]byte, error) {
+		b := make([]byte, r.end-r.start)
+		_, err := f.ReadAt(b, r.start)
+		return b, err
+	}
+
+	var format [24]byte
+	if w.format.end-w.format.start < 16 {
+		return nil, Properties{}, false, ErrInvalidFile
+	}
+	if _, err := f.ReadAt(format[:min(len(format), int(w.format.end-w.format.start))], w.format.start); err != nil {
+		return nil, Properties{}, false, err
+	}
+	formatTag := binary.LittleEndian.Uint16(format[0:])
+	channels := uint(binary.LittleEndian.Uint16(format[2:]))
+	sampleRate := uint(binary.LittleEndian.Uint32(format[4:]))
+	byteRate := uint(binary.LittleEndian.Uint32(format[8:]))
+	blockAlign := int64(binary.LittleEndian.Uint16(format[12:]))
+
+	properties := Properties{
+		Channels:      channels,
+		SampleRate:    sampleRate,
+		Bitrate:       byteRate * 8 / 1000,
+		BitsPerSample: uint(binary.LittleEndian.Uint16(format[14:])),
+		SampleFrames:  w.sampleFrames,
+		ChannelLayout: channelCountLayout(channels),
+		Size:          info.Size(),
+		ModTime:       info.ModTime(),
+	}
+	if w.data.end > w.data.start {
+		properties.MetadataSize = info.Size() - (w.data.end - w.data.start)
+	}
+	if properties.SampleFrames == 0 && blockAlign > 0 {
+		properties.SampleFrames = uint64((w.data.end - w.data.start) / blockAlign)
+	}
+	if sampleRate > 0 {
+		properties.Length = time.Duration(properties.SampleFrames) * time.Second / time.Duration(sampleRate)
+	}
+	if formatTag == 0xfffe && w.format.end-w.format.start >= 24 { // WAVE_FORMAT_EXTENSIBLE
+		properties.ChannelLayout = channelMasks[binary.LittleEndian.Uint32(format[20:])]
+	}
+	if seconds := properties.Length.Seconds(); seconds > 0 {
+		properties.AverageBitrate = float64(w.data.end-w.data.start) * 8 / seconds / 1000
+	}
+
+	tags := map[string][]string{}
+	if w.id3.end > w.id3.start {
+		data, err := read(w.id3)
+		if err != nil {
+			return nil, Properties{}, false, err
+		}
+		if found, _ := salvageID3v2(data); len(found) > 0 {
+			tags = found
+		}
+	}
+	if len(tags) == 0 && w.info.end > w.info.start {
+		data, err := read(w.info)
+		if err != nil {
+			return nil, Properties{}, false, err
+		}
+		for len(data) >= 8 {
+			id := string(data[:4])
+			size := int(binary.LittleEndian.Uint32(data[4:]))
+			if size > len(data)-8 {
+				size = len(data) - 8
+			}
+			if key := riffInfoKeys[id]; key != "" {
+				if value := chunkString(data[8 : 8+size]); value != "" {
+					tags[key] = append(tags[key], value)
+				}
+			}
+			data = data[min(len(data), 8+size+size%2):]
+		}
+	}
+	return tags, properties, true, nil
+}
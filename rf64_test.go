@@ -0,0 +1,133 @@
+package taglib_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestWideWAV(t *testing.T) {
+	t.Parallel()
+
+	format := []byte{1, 0, 2, 0, 0x44, 0xac, 0, 0, 0x10, 0xb1, 2, 0, 4, 0, 16, 0} // PCM, stereo, 44.1 kHz, 16 bit
+	audio := bytes.Repeat([]byte{1, 2, 3, 4}, 44100*2)
+	info := append([]byte("INFO"), riffChunk("INAM", []byte("title\x00"))...)
+	info = append(info, riffChunk("IART", []byte("artist\x00"))...)
+
+	var rf64 bytes.Buffer
+	ds64 := make([]byte, 28)
+	binary.LittleEndian.PutUint64(ds64[8:], uint64(len(audio)))
+	binary.LittleEndian.PutUint64(ds64[16:], uint64(len(audio)/4))
+	rf64.WriteString("RF64\xff\xff\xff\xffWAVE")
+	rf64.Write(riffChunk("ds64", ds64))
+	rf64.Write(riffChunk("fmt ", format))
+	rf64.WriteString("data\xff\xff\xff\xff") // the size is in the ds64 chunk
+	rf64.Write(audio)
+	rf64.Write(riffChunk("LIST", info))
+
+	var w64 bytes.Buffer
+	w64.Write(w64GUID("riff"))
+	binary.Write(&w64, binary.LittleEndian, uint64(0))
+	w64.Write(w64GUID("wave"))
+	w64.Write(w64Chunk("fmt ", format))
+	w64.Write(w64Chunk("data", audio))
+	w64.Write(w64Chunk("list", info))
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"eg.rf64", rf64.Bytes()},
+		{"eg.wav", rf64.Bytes()},
+		{"eg.bw64", append([]byte("BW64"), rf64.Bytes()[4:]...)},
+		{"eg.w64", w64.Bytes()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := tmpf(t, tc.data, tc.name)
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{taglib.Title: {"title"}, taglib.Artist: {"artist"}})
+
+			tags, err = taglib.ReadTagKeys(path, "title")
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{taglib.Title: {"title"}})
+
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			eq(t, properties.Length, 2*time.Second)
+			eq(t, properties.Channels, 2)
+			eq(t, properties.SampleRate, 44100)
+			eq(t, properties.BitsPerSample, 16)
+			eq(t, properties.Bitrate, 1411)
+			eq(t, properties.SampleFrames, 88200)
+			eq(t, properties.ChannelLayout, "stereo")
+			eq(t, properties.Size, int64(len(tc.data)))
+			eq(t, properties.MetadataSize, int64(len(tc.data)-len(audio)))
+
+			_, all, err := taglib.ReadAll(path)
+			nilErr(t, err)
+			eq(t, all.Length, properties.Length)
+
+			offset, length, err := taglib.ReadAudioRange(path)
+			nilErr(t, err)
+			eq(t, length, int64(len(audio)))
+			eq(t, string(tc.data[offset:offset+length]), string(audio))
+		})
+	}
+
+	// an ID3v2 chunk is read instead of the INFO chunk
+	var id3 bytes.Buffer
+	rf64.WriteTo(&id3)
+	id3.Write(riffChunk("id3 ", id3v2Tag("TIT2", "\x03id3 title")))
+	tags, err := taglib.ReadTags(tmpf(t, id3.Bytes(), "eg.rf64"))
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"id3 title"}})
+
+	// other files still fail
+	_, err = taglib.ReadTags(tmpf(t, []byte("not audio"), "eg.rf64"))
+	eq(t, errors.Is(err, taglib.ErrInvalidFile), true)
+}
+
+func riffChunk(id string, data []byte) []byte {
+	b := binary.LittleEndian.AppendUint32([]byte(id), uint32(len(data)))
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func w64GUID(id string) []byte {
+	if id == "riff" || id == "list" {
+		return append([]byte(id), 0x2e, 0x91, 0xcf, 0x11, 0xa5, 0xd6, 0x28, 0xdb, 0x04, 0xc1, 0x00, 0x00)
+	}
+	return append([]byte(id), 0xf3, 0xac, 0xd3, 0x11, 0x8c, 0xd1, 0x00, 0xc0, 0x4f, 0x8e, 0xdb, 0x8a)
+}
+
+func w64Chunk(id string, data []byte) []byte {
+	b := binary.LittleEndian.AppendUint64(w64GUID(id), uint64(24+len(data)))
+	b = append(b, data...)
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// id3v2Tag is an ID3v2.4 tag with a single frame.
+func id3v2Tag(id, content string) []byte {
+	frame := append([]byte(id), synchsafe(len(content))...)
+	frame = append(frame, 0, 0)
+	frame = append(frame, content...)
+	tag := append([]byte("ID3\x04\x00\x00"), synchsafe(len(frame))...)
+	return append(tag, frame...)
+}
+
+func synchsafe(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
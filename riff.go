@@ -0,0 +1,728 @@
+package taglib
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+)
+
+// ReadWAVInfo reads the fields of the RIFF INFO chunk from a WAV file at the given path.
+// Fields are keyed by their four character ID, such as "IART", "INAM", "ICMT", or "ICRD".
+func ReadWAVInfo(path string) (_ map[string]string, err error) {
+	defer wrapErr(&err, "read wav info", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	form, err := readIFFForm(f)
+	if err != nil {
+		return nil, err
+	}
+	if !form.isWAV() {
+		return nil, ErrInvalidFile
+	}
+
+	var fields = map[string]string{}
+	for _, c := range form.infoChunks(f) {
+		data, err := form.read(f, c)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(fields, parseRIFFInfo(data))
+	}
+	return fields, nil
+}
+
+// WriteWAVInfo writes the RIFF INFO chunk fields to a WAV file at path. Fields are keyed by their four character ID.
+// A field with an empty value is removed, and with [Clear] every field not in fields is removed. The INFO chunk is
+// replaced in place, and other chunks are kept, except that [SkipID3v2] removes the ID3v2 chunk.
+func WriteWAVInfo(path string, fields map[string]string, opts WriteOption) (err error) {
+	defer wrapErr(&err, "write wav info", path)
+	for id := range fields {
+		if !validChunkID(id) {
+			return fmt.Errorf("invalid field id %q", id)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	form, err := readIFFForm(f)
+	if err != nil {
+		return err
+	}
+	if !form.isWAV() {
+		return ErrInvalidFile
+	}
+
+	infos := form.infoChunks(f)
+	var info = map[string]string{}
+	if opts&Clear == 0 {
+		for _, c := range infos {
+			data, err := form.read(f, c)
+			if err != nil {
+				return err
+			}
+			maps.Copy(info, parseRIFFInfo(data))
+		}
+	}
+	for id, v := range fields {
+		if v == "" {
+			delete(info, id)
+			continue
+		}
+		info[id] = v
+	}
+
+	// the first INFO chunk is replaced, and the others removed from the end so the offsets before them hold
+	var chunk []byte
+	if len(info) > 0 {
+		chunk = form.encode("LIST", renderRIFFInfo(info))
+	}
+	for i := len(infos) - 1; i > 0; i-- {
+		if err := form.rewrite(f, infos[i].offset, infos[i].end, nil); err != nil {
+			return err
+		}
+	}
+	switch {
+	case len(infos) > 0:
+		err = form.rewrite(f, infos[0].offset, infos[0].end, chunk)
+	case chunk != nil:
+		err = form.rewrite(f, form.end, form.end, chunk)
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts&SkipID3v2 != 0 {
+		return removeWAVTags(f, SkipID3v2)
+	}
+	return nil
+}
+
+// parseRIFFInfo reads the fields of the data of a "LIST" chunk of type "INFO", where each field is a chunk of NUL
+// terminated text.
+func parseRIFFInfo(data []byte) map[string]string {
+	var fields = map[string]string{}
+	for p := 4; p+8 <= len(data); {
+		id := string(data[p : p+4])
+		size := int(binary.LittleEndian.Uint32(data[p+4:]))
+		text := data[p+8 : p+8+min(size, len(data)-p-8)]
+		if validChunkID(id) {
+			fields[id] = string(bytes.TrimRight(text, "\x00"))
+		}
+		p += 8 + size + size%2
+	}
+	return fields
+}
+
+// renderRIFFInfo renders fields as the data of a "LIST" chunk of type "INFO", in order of their IDs as TagLib
+// writes them.
+func renderRIFFInfo(fields map[string]string) []byte {
+	data := []byte("INFO")
+	for _, id := range slices.Sorted(maps.Keys(fields)) {
+		text := append([]byte(fields[id]), 0)
+		data = append(data, id...)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(text)))
+		data = append(data, text...)
+		if len(text)%2 != 0 {
+			data = append(data, 0)
+		}
+	}
+	return data
+}
+
+// validChunkID reports whether id is four printable ASCII characters.
+func validChunkID(id string) bool {
+	if len(id) != 4 {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] < ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// skipWAVTags removes the tags of the WAV file at path which opts skips, after TagLib has saved them all.
+func skipWAVTags(path string, opts WriteOption) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+	return removeWAVTags(f, opts)
+}
+
+// removeWAVTags removes the ID3v2 chunk of a WAV file for [SkipID3v2] in opts, and its INFO chunks for
+// [SkipRIFFInfo]. Other files are left as they are.
+func removeWAVTags(f *os.File, opts WriteOption) error {
+	form, err := readIFFForm(f)
+	if err != nil || !form.isWAV() {
+		return nil
+	}
+	var remove []iffChunk
+	if opts&SkipID3v2 != 0 {
+		remove = append(remove, form.find("ID3 ")...)
+		remove = append(remove, form.find("id3 ")...)
+	}
+	if opts&SkipRIFFInfo != 0 {
+		remove = append(remove, form.infoChunks(f)...)
+	}
+	// from the end, so the offsets before each removed chunk hold
+	slices.SortFunc(remove, func(a, b iffChunk) int { return cmp.Compare(b.offset, a.offset) })
+	for _, c := range remove {
+		if err := form.rewrite(f, c.offset, c.end, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BEXT contains the fields of a Broadcast Wave Format "bext" chunk, as defined by EBU Tech 3285.
+type BEXT struct {
+	// Description is a free text description of the sound sequence, up to 256 characters
+	Description string
+	// Originator is the name of the originator or producer, up to 32 characters
+	Originator string
+	// OriginatorReference is an unambiguous reference allocated by the originator, up to 32 characters
+	OriginatorReference string
+	// OriginationDate is the date of creation formatted as "yyyy:mm:dd"
+	OriginationDate string
+	// OriginationTime is the time of creation formatted as "hh:mm:ss"
+	OriginationTime string
+	// TimeReference is the first sample count since midnight
+	TimeReference uint64
+	// Version is the version of the BWF chunk
+	Version uint16
+	// UMID is the SMPTE Unique Material Identifier
+	UMID [64]byte
+	// LoudnessValue is the integrated loudness in LUFS multiplied by 100
+	LoudnessValue int16
+	// LoudnessRange is the loudness range in LU multiplied by 100
+	LoudnessRange int16
+	// MaxTruePeakLevel is the maximum true peak level in dBTP multiplied by 100
+	MaxTruePeakLevel int16
+	// MaxMomentaryLoudness is the highest momentary loudness in LUFS multiplied by 100
+	MaxMomentaryLoudness int16
+	// MaxShortTermLoudness is the highest short term loudness in LUFS multiplied by 100
+	MaxShortTermLoudness int16
+	// CodingHistory is a series of CR/LF terminated lines describing the coding processes applied to the audio
+	CodingHistory string
+}
+
+// ReadBEXT reads the Broadcast Wave Format "bext" chunk from a WAV file at the given path.
+// Returns nil if the file has no such chunk.
+func ReadBEXT(path string) (_ *BEXT, err error) {
+	defer wrapErr(&err, "read bext", path)
+	data, err := readChunk(path, "bext")
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) < bextSize {
+		return nil, fmt.Errorf("short bext chunk: %w", ErrInvalidFile)
+	}
+
+	var b BEXT
+	b.Description = chunkString(data[0:256])
+	b.Originator = chunkString(data[256:288])
+	b.OriginatorReference = chunkString(data[288:320])
+	b.OriginationDate = chunkString(data[320:330])
+	b.OriginationTime = chunkString(data[330:338])
+	b.TimeReference = binary.LittleEndian.Uint64(data[338:346])
+	b.Version = binary.LittleEndian.Uint16(data[346:348])
+	copy(b.UMID[:], data[348:412])
+	b.LoudnessValue = int16(binary.LittleEndian.Uint16(data[412:414]))
+	b.LoudnessRange = int16(binary.LittleEndian.Uint16(data[414:416]))
+	b.MaxTruePeakLevel = int16(binary.LittleEndian.Uint16(data[416:418]))
+	b.MaxMomentaryLoudness = int16(binary.LittleEndian.Uint16(data[418:420]))
+	b.MaxShortTermLoudness = int16(binary.LittleEndian.Uint16(data[420:422]))
+	b.CodingHistory = chunkString(data[bextSize:])
+	return &b, nil
+}
+
+// WriteBEXT writes the Broadcast Wave Format "bext" chunk to a WAV file at path, replacing any existing one.
+// Set b to nil to remove the chunk.
+func WriteBEXT(path string, b *BEXT) (err error) {
+	defer wrapErr(&err, "write bext", path)
+	if b == nil {
+		return writeChunk(path, "bext", nil)
+	}
+
+	data := make([]byte, bextSize, bextSize+len(b.CodingHistory))
+	copy(data[0:256], b.Description)
+	copy(data[256:288], b.Originator)
+	copy(data[288:320], b.OriginatorReference)
+	copy(data[320:330], b.OriginationDate)
+	copy(data[330:338], b.OriginationTime)
+	binary.LittleEndian.PutUint64(data[338:346], b.TimeReference)
+	binary.LittleEndian.PutUint16(data[346:348], b.Version)
+	copy(data[348:412], b.UMID[:])
+	binary.LittleEndian.PutUint16(data[412:414], uint16(b.LoudnessValue))
+	binary.LittleEndian.PutUint16(data[414:416], uint16(b.LoudnessRange))
+	binary.LittleEndian.PutUint16(data[416:418], uint16(b.MaxTruePeakLevel))
+	binary.LittleEndian.PutUint16(data[418:420], uint16(b.MaxMomentaryLoudness))
+	binary.LittleEndian.PutUint16(data[420:422], uint16(b.MaxShortTermLoudness))
+	data = append(data, b.CodingHistory...)
+	return writeChunk(path, "bext", data)
+}
+
+// ReadIXML reads the raw XML document of the "iXML" chunk from a WAV or RF64 file at the given path.
+// Returns an empty string if the file has no such chunk.
+func ReadIXML(path string) (_ string, err error) {
+	defer wrapErr(&err, "read ixml", path)
+	data, err := readChunk(path, "iXML")
+	if err != nil {
+		return "", err
+	}
+	// writers often pad the document with NULs to leave room for edits
+	return string(bytes.TrimRight(data, "\x00")), nil
+}
+
+// WriteIXML writes the raw XML document of the "iXML" chunk to a WAV or RF64 file at path, replacing any existing one.
+// Set xml to an empty string to remove the chunk.
+func WriteIXML(path string, xml string) (err error) {
+	defer wrapErr(&err, "write ixml", path)
+	return writeChunk(path, "iXML", []byte(xml))
+}
+
+// CART contains the fields of a radio traffic "cart" chunk, as defined by AES46-2002.
+type CART struct {
+	// Version is the version of the cart chunk as four ASCII digits, such as "0101"
+	Version string
+	// Title is the title of the cut
+	Title string
+	// Artist is the artist or creator of the cut
+	Artist string
+	// CutID is the identifier of the cut, such as a cart number
+	CutID string
+	// ClientID is the identifier of the client or customer
+	ClientID string
+	// Category is the category of the cut, such as "COM" or "PSA"
+	Category string
+	// Classification is used for sorting cuts
+	Classification string
+	// OutCue is the text or cue of the last words of the cut
+	OutCue string
+	// StartDate is the date the cut becomes valid, formatted as "yyyy/mm/dd"
+	StartDate string
+	// StartTime is the time the cut becomes valid, formatted as "hh:mm:ss"
+	StartTime string
+	// EndDate is the date the cut expires, formatted as "yyyy/mm/dd"
+	EndDate string
+	// EndTime is the time the cut expires, formatted as "hh:mm:ss"
+	EndTime string
+	// ProducerAppID is the name of the application that created the chunk
+	ProducerAppID string
+	// ProducerAppVersion is the version of the application that created the chunk
+	ProducerAppVersion string
+	// UserDef is user defined text
+	UserDef string
+	// LevelReference is the sample value corresponding to 0 dB full scale
+	LevelReference int32
+	// PostTimers contains up to eight timer markers. Unused timers have an empty Usage
+	PostTimers [8]CARTTimer
+	// URL is a link to more information about the cut
+	URL string
+	// TagText is free form text, CR/LF terminated
+	TagText string
+}
+
+// CARTTimer is a timer marker of a [CART] chunk.
+type CARTTimer struct {
+	// Usage is the four character code of the timer, such as "SEGs", "INTe", or "AUDs"
+	Usage string
+	// Value is the timer position in samples from the start of the audio
+	Value uint32
+}
+
+// ReadCART reads the "cart" chunk from a WAV file at the given path.
+// Returns nil if the file has no such chunk.
+func ReadCART(path string) (_ *CART, err error) {
+	defer wrapErr(&err, "read cart", path)
+	data, err := readChunk(path, "cart")
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) < cartSize {
+		return nil, fmt.Errorf("short cart chunk: %w", ErrInvalidFile)
+	}
+
+	var c CART
+	c.Version = chunkString(data[0:4])
+	c.Title = chunkString(data[4:68])
+	c.Artist = chunkString(data[68:132])
+	c.CutID = chunkString(data[132:196])
+	c.ClientID = chunkString(data[196:260])
+	c.Category = chunkString(data[260:324])
+	c.Classification = chunkString(data[324:388])
+	c.OutCue = chunkString(data[388:452])
+	c.StartDate = chunkString(data[452:462])
+	c.StartTime = chunkString(data[462:470])
+	c.EndDate = chunkString(data[470:480])
+	c.EndTime = chunkString(data[480:488])
+	c.ProducerAppID = chunkString(data[488:552])
+	c.ProducerAppVersion = chunkString(data[552:616])
+	c.UserDef = chunkString(data[616:680])
+	c.LevelReference = int32(binary.LittleEndian.Uint32(data[680:684]))
+	for i := range c.PostTimers {
+		timer := data[684+i*8 : 684+(i+1)*8]
+		c.PostTimers[i].Usage = chunkString(timer[0:4])
+		c.PostTimers[i].Value = binary.LittleEndian.Uint32(timer[4:8])
+	}
+	c.URL = chunkString(data[1024:2048])
+	c.TagText = chunkString(data[cartSize:])
+	return &c, nil
+}
+
+// WriteCART writes the "cart" chunk to a WAV file at path, replacing any existing one.
+// Set c to nil to remove the chunk.
+func WriteCART(path string, c *CART) (err error) {
+	defer wrapErr(&err, "write cart", path)
+	if c == nil {
+		return writeChunk(path, "cart", nil)
+	}
+
+	data := make([]byte, cartSize, cartSize+len(c.TagText))
+	copy(data[0:4], c.Version)
+	copy(data[4:68], c.Title)
+	copy(data[68:132], c.Artist)
+	copy(data[132:196], c.CutID)
+	copy(data[196:260], c.ClientID)
+	copy(data[260:324], c.Category)
+	copy(data[324:388], c.Classification)
+	copy(data[388:452], c.OutCue)
+	copy(data[452:462], c.StartDate)
+	copy(data[462:470], c.StartTime)
+	copy(data[470:480], c.EndDate)
+	copy(data[480:488], c.EndTime)
+	copy(data[488:552], c.ProducerAppID)
+	copy(data[552:616], c.ProducerAppVersion)
+	copy(data[616:680], c.UserDef)
+	binary.LittleEndian.PutUint32(data[680:684], uint32(c.LevelReference))
+	for i, t := range c.PostTimers {
+		timer := data[684+i*8 : 684+(i+1)*8]
+		copy(timer[0:4], t.Usage)
+		binary.LittleEndian.PutUint32(timer[4:8], t.Value)
+	}
+	copy(data[1024:2048], c.URL)
+	data = append(data, c.TagText...)
+	return writeChunk(path, "cart", data)
+}
+
+// sizes of the chunks without their variable length trailing text
+const (
+	bextSize = 602
+	cartSize = 2048
+)
+
+// chunk text fields are ASCII, padded with NULs to their fixed size
+func chunkString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// AIFFText contains the text chunks of an AIFF file.
+type AIFFText struct {
+	// Name is the contents of the "NAME" chunk
+	Name string
+	// Author is the contents of the "AUTH" chunk
+	Author string
+	// Copyright is the contents of the "(c) " chunk
+	Copyright string
+	// Annotations contains the contents of every "ANNO" chunk
+	Annotations []string
+}
+
+// ReadAIFFText reads the NAME, AUTH, "(c) ", and ANNO text chunks from an AIFF file at the given path.
+// These are separate from the ID3 chunk read by [ReadTags].
+func ReadAIFFText(path string) (_ AIFFText, err error) {
+	defer wrapErr(&err, "read aiff text", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return AIFFText{}, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	form, err := readIFFForm(f)
+	if err != nil {
+		return AIFFText{}, err
+	}
+
+	var text AIFFText
+	for name, dest := range map[string]*string{"NAME": &text.Name, "AUTH": &text.Author, "(c) ": &text.Copyright} {
+		data, err := form.chunkAt(f, name, 0)
+		if err != nil {
+			return AIFFText{}, err
+		}
+		*dest = chunkString(data)
+	}
+	for _, c := range form.find("ANNO") {
+		data, err := form.read(f, c)
+		if err != nil {
+			return AIFFText{}, err
+		}
+		text.Annotations = append(text.Annotations, chunkString(data))
+	}
+	return text, nil
+}
+
+// WriteAIFFText writes the NAME, AUTH, "(c) ", and ANNO text chunks to an AIFF file at path.
+// Empty fields remove their chunk, and existing annotations are replaced by text.Annotations.
+func WriteAIFFText(path string, text AIFFText) (err error) {
+	defer wrapErr(&err, "write aiff text", path)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	for name, value := range map[string]string{"NAME": text.Name, "AUTH": text.Author, "(c) ": text.Copyright} {
+		if err := setChunkAt(f, name, 0, []byte(value)); err != nil {
+			return err
+		}
+	}
+	if err := setChunkAt(f, "ANNO", -1, nil); err != nil {
+		return err
+	}
+	for i, anno := range text.Annotations {
+		if err := setChunkAt(f, "ANNO", i, []byte(anno)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readChunk reads the data of the first top level chunk with the given four character name
+// from a WAV or AIFF file. Returns an empty slice if there is no such chunk.
+func readChunk(path string, name string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	form, err := readIFFForm(f)
+	if err != nil {
+		return nil, err
+	}
+	return form.chunkAt(f, name, 0)
+}
+
+// writeChunk sets the data of the first top level chunk with the given four character name
+// in a WAV or AIFF file, appending a new chunk if needed. Set data to nil to remove every chunk with the name.
+func writeChunk(path string, name string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	index := 0
+	if data == nil {
+		index = -1
+	}
+	return setChunkAt(f, name, index, data)
+}
+
+// setChunkAt sets the data of the index-th chunk with the given name, appending a new chunk if index is out of range.
+// Empty data removes the chunk, and a negative index removes every chunk with the name.
+func setChunkAt(f *os.File, name string, index int, data []byte) error {
+	form, err := readIFFForm(f)
+	if err != nil {
+		return err
+	}
+	chunks := form.find(name)
+	if index < 0 {
+		for i := len(chunks) - 1; i >= 0; i-- {
+			if err := form.rewrite(f, chunks[i].offset, chunks[i].end, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var chunk []byte
+	if len(data) > 0 {
+		chunk = form.encode(name, data)
+	}
+	switch {
+	case index < len(chunks):
+		return form.rewrite(f, chunks[index].offset, chunks[index].end, chunk)
+	case chunk != nil:
+		return form.rewrite(f, form.end, form.end, chunk)
+	}
+	return nil
+}
+
+// iffForm is the outermost chunk of a WAV or AIFF file, which holds the others. RF64 and BW64 files are read with
+// the sizes of their "ds64" chunk.
+type iffForm struct {
+	typ    string
+	order  binary.ByteOrder
+	end    int64
+	chunks []iffChunk
+	// ds64 is the offset of the data of the "ds64" chunk of RF64 files, 0 for others
+	ds64 int64
+}
+
+// iffChunk is a top level chunk of an [iffForm], from the offset of its header to the end of its padding.
+type iffChunk struct {
+	id          string
+	offset, end int64
+	size        int64
+}
+
+// readIFFForm reads the header of a WAV, RF64, or AIFF file and lists its chunks.
+func readIFFForm(f fileReader) (*iffForm, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+	var header [12]byte
+	if _, err := f.ReadAt(header[:], 0); err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	form := &iffForm{typ: string(header[8:12])}
+	switch {
+	case string(header[:4]) == "RIFF" && form.typ == "WAVE":
+		form.order = binary.LittleEndian
+	case (string(header[:4]) == "RF64" || string(header[:4]) == "BW64") && form.typ == "WAVE":
+		form.order = binary.LittleEndian
+		form.ds64 = 12 + 8
+	case string(header[:4]) == "FORM" && (form.typ == "AIFF" || form.typ == "AIFC"):
+		form.order = binary.BigEndian
+	default:
+		return nil, ErrInvalidFile
+	}
+
+	var ds64 [16]byte // the sizes of the file and the "data" chunk
+	form.end = 8 + int64(form.order.Uint32(header[4:]))
+	if form.ds64 != 0 {
+		if !hasAt(f, 12, "ds64") {
+			return nil, ErrInvalidFile
+		}
+		if _, err := f.ReadAt(ds64[:], form.ds64); err != nil {
+			return nil, ErrInvalidFile
+		}
+		form.end = 8 + int64(binary.LittleEndian.Uint64(ds64[:]))
+	}
+	form.end = min(form.end, info.Size())
+
+	var chunk [8]byte
+	for offset := int64(12); offset+8 <= form.end; {
+		if _, err := f.ReadAt(chunk[:], offset); err != nil {
+			break
+		}
+		c := iffChunk{id: string(chunk[:4]), offset: offset, size: int64(form.order.Uint32(chunk[4:]))}
+		if form.ds64 != 0 && c.id == "data" && c.size == 0xffffffff {
+			c.size = int64(binary.LittleEndian.Uint64(ds64[8:]))
+		}
+		c.size = min(c.size, form.end-offset-8)
+		c.end = min(offset+8+c.size+c.size%2, form.end)
+		form.chunks = append(form.chunks, c)
+		offset = c.end
+	}
+	return form, nil
+}
+
+func (form *iffForm) isWAV() bool { return form.typ == "WAVE" }
+
+// find lists the chunks with the given ID.
+func (form *iffForm) find(id string) []iffChunk {
+	var found []iffChunk
+	for _, c := range form.chunks {
+		if c.id == id {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// infoChunks lists the "LIST" chunks of type "INFO".
+func (form *iffForm) infoChunks(r io.ReaderAt) []iffChunk {
+	var found []iffChunk
+	for _, c := range form.find("LIST") {
+		if c.size >= 4 && hasAt(r, c.offset+8, "INFO") {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// read reads the data of c.
+func (form *iffForm) read(r io.ReaderAt, c iffChunk) ([]byte, error) {
+	data := make([]byte, c.size)
+	if _, err := r.ReadAt(data, c.offset+8); err != nil {
+		return nil, fmt.Errorf("read %q chunk: %w", c.id, err)
+	}
+	return data, nil
+}
+
+// chunkAt reads the data of the index-th chunk with the given ID. Returns an empty slice if there is no such chunk.
+func (form *iffForm) chunkAt(r io.ReaderAt, id string, index int) ([]byte, error) {
+	chunks := form.find(id)
+	if index >= len(chunks) {
+		return []byte{}, nil
+	}
+	return form.read(r, chunks[index])
+}
+
+// encode renders a chunk with the given ID and data, padded to an even size.
+func (form *iffForm) encode(id string, data []byte) []byte {
+	chunk := make([]byte, 8, 8+len(data)+1)
+	copy(chunk, id)
+	form.order.PutUint32(chunk[4:], uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 != 0 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// rewrite replaces the bytes of f between start and end, which are the bounds of chunks, with chunks, and updates the
+// size of the form and its end. Offsets before end are unchanged, so chunks can be rewritten from the last.
+func (form *iffForm) rewrite(f *os.File, start, end int64, chunks []byte) error {
+	newEnd := form.end + int64(len(chunks)) - (end - start)
+	if form.ds64 == 0 && newEnd-8 > math.MaxUint32 {
+		return fmt.Errorf("form size over 4 GB: %w", ErrSavingFile)
+	}
+	if err := rewriteRange(f, start, end, chunks); err != nil {
+		return err
+	}
+
+	var size []byte
+	var offset int64 = 4
+	if form.ds64 != 0 {
+		size, offset = binary.LittleEndian.AppendUint64(nil, uint64(newEnd-8)), form.ds64
+	} else {
+		size = make([]byte, 4)
+		form.order.PutUint32(size, uint32(newEnd-8))
+	}
+	if _, err := f.WriteAt(size, offset); err != nil {
+		return fmt.Errorf("write form size: %w", err)
+	}
+	form.end = newEnd
+	return nil
+}
@@ -0,0 +1,208 @@
+package taglib_test
+
+import (
+	"encoding/binary"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestWAVInfo(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egWAV, "eg.wav")
+	nilErr(t, taglib.WriteWAVInfo(path, map[string]string{"INAM": "Title", "IART": ""}, 0))
+
+	info, err := taglib.ReadWAVInfo(path)
+	nilErr(t, err)
+	eq(t, maps.Equal(info, map[string]string{"INAM": "Title", "IPRD": "example album"}), true)
+
+	nilErr(t, taglib.WriteWAVInfo(path, map[string]string{"ICMT": "Odd"}, taglib.Clear))
+	info, err = taglib.ReadWAVInfo(path)
+	nilErr(t, err)
+	eq(t, maps.Equal(info, map[string]string{"ICMT": "Odd"}), true)
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.SampleFrames, 220568)
+
+	err = taglib.WriteWAVInfo(path, map[string]string{"INAME": "Title"}, 0)
+	eq(t, err != nil, true)
+}
+
+func TestWAVSkipTags(t *testing.T) {
+	t.Parallel()
+
+	// the MusicBrainz ID has no INFO field, so it is only saved in the ID3v2 chunk
+	tags := map[string][]string{taglib.Title: {"Title"}, taglib.MusicBrainzTrackID: {"id"}}
+
+	path := tmpf(t, egWAV, "eg.wav")
+	nilErr(t, taglib.WriteTags(path, tags, taglib.Clear|taglib.SkipRIFFInfo))
+	info, err := taglib.ReadWAVInfo(path)
+	nilErr(t, err)
+	eq(t, len(info), 0)
+	got, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, tags)
+
+	nilErr(t, taglib.WriteTags(path, tags, taglib.Clear|taglib.SkipID3v2))
+	info, err = taglib.ReadWAVInfo(path)
+	nilErr(t, err)
+	eq(t, maps.Equal(info, map[string]string{"INAM": "Title"}), true)
+	got, err = taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, got, map[string][]string{taglib.Title: {"Title"}})
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.SampleFrames, 220568)
+}
+
+func TestBEXT(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egWAV, "eg.wav")
+	b, err := taglib.ReadBEXT(path)
+	nilErr(t, err)
+	eq(t, b, nil)
+
+	exp := taglib.BEXT{
+		Description:      "Scene 1, take 2",
+		Originator:       "Recorder",
+		OriginationDate:  "2024:01:02",
+		OriginationTime:  "03:04:05",
+		TimeReference:    1 << 40,
+		Version:          2,
+		LoudnessValue:    -2300,
+		MaxTruePeakLevel: -100,
+		CodingHistory:    "A=PCM,F=22050,W=16,M=mono\r\n",
+	}
+	exp.UMID[0] = 0x06
+	nilErr(t, taglib.WriteBEXT(path, &exp))
+
+	b, err = taglib.ReadBEXT(path)
+	nilErr(t, err)
+	eq(t, *b, exp)
+
+	nilErr(t, taglib.WriteBEXT(path, nil))
+	b, err = taglib.ReadBEXT(path)
+	nilErr(t, err)
+	eq(t, b, nil)
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.SampleFrames, 220568)
+}
+
+func TestIXML(t *testing.T) {
+	t.Parallel()
+
+	const doc = "<?xml version=\"1.0\"?><BWFXML><SCENE>1</SCENE><TAKE>2</TAKE></BWFXML>"
+
+	rf64 := append([]byte("RF64\xff\xff\xff\xffWAVE"), riffChunk("ds64", make([]byte, 28))...)
+	rf64 = append(rf64, riffChunk("fmt ", []byte{1, 0, 1, 0, 0x44, 0xac, 0, 0, 0x88, 0x58, 1, 0, 2, 0, 16, 0})...)
+	rf64 = append(rf64, riffChunk("data", make([]byte, 100))...)
+	binary.LittleEndian.PutUint64(rf64[20:], uint64(len(rf64)-8))
+	binary.LittleEndian.PutUint64(rf64[28:], 100)
+
+	for _, path := range []string{tmpf(t, egWAV, "eg.wav"), tmpf(t, rf64, "eg.rf64")} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			before, err := os.Stat(path)
+			nilErr(t, err)
+
+			xml, err := taglib.ReadIXML(path)
+			nilErr(t, err)
+			eq(t, xml, "")
+
+			// padded with NULs, as recorders leave room for edits
+			nilErr(t, taglib.WriteIXML(path, doc+"\x00\x00\x00"))
+			xml, err = taglib.ReadIXML(path)
+			nilErr(t, err)
+			eq(t, xml, doc)
+
+			nilErr(t, taglib.WriteIXML(path, ""))
+			xml, err = taglib.ReadIXML(path)
+			nilErr(t, err)
+			eq(t, xml, "")
+
+			after, err := os.Stat(path)
+			nilErr(t, err)
+			eq(t, after.Size(), before.Size())
+		})
+	}
+}
+
+func TestCART(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egWAV, "eg.wav")
+	c, err := taglib.ReadCART(path)
+	nilErr(t, err)
+	eq(t, c, nil)
+
+	exp := taglib.CART{
+		Version:            "0101",
+		Title:              "Spot",
+		Artist:             "Artist",
+		CutID:              "12345",
+		Category:           "COM",
+		StartDate:          "2024/01/02",
+		StartTime:          "00:00:00",
+		EndDate:            "2024/12/31",
+		EndTime:            "23:59:59",
+		ProducerAppID:      "go-taglib",
+		LevelReference:     32768,
+		URL:                "https://example.com",
+		TagText:            "Text\r\n",
+		ProducerAppVersion: "1",
+	}
+	exp.PostTimers[0] = taglib.CARTTimer{Usage: "SEGs", Value: 100}
+	exp.PostTimers[7] = taglib.CARTTimer{Usage: "AUDe", Value: 220000}
+	nilErr(t, taglib.WriteCART(path, &exp))
+
+	c, err = taglib.ReadCART(path)
+	nilErr(t, err)
+	eq(t, *c, exp)
+
+	nilErr(t, taglib.WriteCART(path, nil))
+	c, err = taglib.ReadCART(path)
+	nilErr(t, err)
+	eq(t, c, nil)
+}
+
+func TestAIFFText(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egAIFF, "eg.aiff")
+	nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, taglib.Clear))
+
+	exp := taglib.AIFFText{Name: "Name", Author: "Author", Copyright: "(c) 2024", Annotations: []string{"One", "Two"}}
+	nilErr(t, taglib.WriteAIFFText(path, exp))
+
+	text, err := taglib.ReadAIFFText(path)
+	nilErr(t, err)
+	eq(t, text.Name, exp.Name)
+	eq(t, text.Author, exp.Author)
+	eq(t, text.Copyright, exp.Copyright)
+	eq(t, slices.Equal(text.Annotations, exp.Annotations), true)
+
+	nilErr(t, taglib.WriteAIFFText(path, taglib.AIFFText{Name: "Other", Annotations: []string{"Three"}}))
+	text, err = taglib.ReadAIFFText(path)
+	nilErr(t, err)
+	eq(t, text.Name, "Other")
+	eq(t, text.Author, "")
+	eq(t, slices.Equal(text.Annotations, []string{"Three"}), true)
+
+	// the ID3 chunk and the audio are kept
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}})
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.SampleFrames, 4410)
+	eq(t, properties.BitsPerSample, 16)
+}
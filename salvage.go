@@ -0,0 +1,272 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseError is a tag of a damaged file which [ReadTagsSalvage] could only read in part, or not at all.
+type ParseError struct {
+	// Tag is the tag or block which is damaged, such as "ID3v2", "APEv2", or "FLAC VORBIS_COMMENT"
+	Tag string
+	// Offset is the offset in the file of the start of the tag
+	Offset int64
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s tag at %d: %v", e.Tag, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// ReadTagsSalvage reads the tags of a file at the given path like [ReadTags], but if TagLib can't open the file
+// because it is damaged, it returns whatever can be read from its ID3v2, APEv2, and ID3v1 tags, and its FLAC or Ogg
+// Vorbis comments, instead of [ErrInvalidFile]. Each tag which could only be read in part, or not at all, is reported
+// as a [*ParseError]. ID3v2 frames are mapped to the keys of the most common text frames, and TXXX frames to their
+// description.
+func ReadTagsSalvage(path string) (_ map[string][]string, _ []error, err error) {
+	tags, err := ReadTags(path)
+	if err == nil || !errors.Is(err, ErrInvalidFile) {
+		return tags, nil, err
+	}
+
+	defer wrapErr(&err, "salvage tags", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat: %w", err)
+	}
+
+	tags = map[string][]string{}
+	var errs []error
+	add := func(found map[string][]string, tag string, offset int64, err error) {
+		for k, v := range found {
+			if _, ok := tags[k]; !ok {
+				tags[k] = v
+			}
+		}
+		if err != nil {
+			errs = append(errs, &ParseError{Tag: tag, Offset: offset, Err: err})
+		}
+	}
+
+	if tag, _, err := readID3v2Tag(f); err != nil {
+		add(nil, "ID3v2", 0, err)
+	} else if tag != nil {
+		found, err := salvageID3v2(tag)
+		add(found, "ID3v2", 0, err)
+	}
+
+	if start, _ := id3v2TagSize(f); hasAt(f, start, "fLaC") {
+		blocks, err := readFLACBlocks(f)
+		if err != nil {
+			add(nil, "FLAC", 0, err)
+		}
+		for _, block := range blocks {
+			if block.typ != flacBlockComment {
+				continue
+			}
+			data, err := block.read(f)
+			if err != nil {
+				// the block was cut short, so read what is there
+				data = make([]byte, block.length)
+				n, _ := f.ReadAt(data, block.offset+4)
+				data = data[:n]
+			}
+			found, err := salvageVorbisComments(data)
+			add(found, "FLAC VORBIS_COMMENT", block.offset, err)
+		}
+	} else if packets := oggHeaderPackets(f, 2); len(packets) == 2 {
+		for _, prefix := range []string{"\x03vorbis", "OpusTags"} {
+			if comments, ok := bytes.CutPrefix(packets[1], []byte(prefix)); ok {
+				found, err := salvageVorbisComments(comments)
+				add(found, "Ogg comment header", 0, err)
+			}
+		}
+	}
+
+	trailing, err := trailingTags(f, info.Size())
+	if err != nil {
+		add(nil, "trailing tags", 0, err)
+	}
+	for _, r := range trailing {
+		data := make([]byte, r.Length)
+		if _, err := f.ReadAt(data, r.Offset); err != nil {
+			add(nil, r.Kind, r.Offset, err)
+			continue
+		}
+		switch r.Kind {
+		case "APEv2":
+			found, err := salvageAPE(data)
+			add(found, r.Kind, r.Offset, err)
+		case "ID3v1":
+			add(salvageID3v1(data), r.Kind, r.Offset, nil)
+		}
+	}
+	return tags, errs, nil
+}
+
+// salvageID3v2 reads the text, user text, and comment frames of an ID3v2 tag, up to a frame which overruns the tag.
+func salvageID3v2(tag []byte) (map[string][]string, error) {
+	major, body, ok := id3v2Body(tag)
+	if !ok {
+		return nil, fmt.Errorf("bad header: %w", ErrInvalidFile)
+	}
+	tags := map[string][]string{}
+	headerSize := id3v2FrameHeaderSize(major)
+	for len(body) >= headerSize && body[0] != 0 {
+		id, size, format := id3v2FrameHeader(major, body)
+		if size > len(body)-headerSize {
+			return tags, fmt.Errorf("frame %q overruns the tag: %w", id, ErrInvalidFile)
+		}
+		data, ok := id3v2FrameContent(major, format, body[headerSize:headerSize+size])
+		body = body[headerSize+size:]
+		if major == 2 {
+			id = id3v22Frames[id]
+		}
+		if !ok || len(data) == 0 {
+			continue
+		}
+
+		values := splitID3v2Texts(data[0], data[1:])
+		switch {
+		case id == "TXXX" && len(values) >= 2:
+			key := id3v2UserTextKey(values[0])
+			tags[key] = append(tags[key], values[1:]...)
+		case id == "COMM" && len(data) >= 4:
+			desc, rest := splitID3v2Text(data[0], data[4:])
+			if desc == "" {
+				text, _ := splitID3v2Text(data[0], rest)
+				tags[Comment] = append(tags[Comment], text)
+			}
+		case salvageID3v2Keys[id] != "":
+			key := salvageID3v2Keys[id]
+			tags[key] = append(tags[key], values...)
+		}
+	}
+	return tags, nil
+}
+
+// splitID3v2Texts decodes the null separated values of a text frame.
+func splitID3v2Texts(encoding byte, data []byte) []string {
+	var values []string
+	for len(data) > 0 {
+		var value string
+		value, data = splitID3v2Text(encoding, data)
+		values = append(values, value)
+	}
+	return values
+}
+
+// salvageID3v2Keys maps the common text frames to the keys TagLib reads them as.
+var salvageID3v2Keys = map[string]string{
+	"TALB": Album, "TBPM": BPM, "TCMP": Compilation, "TCOM": Composer, "TCON": Genre, "TCOP": Copyright,
+	"TDOR": OriginalDate, "TDRC": Date, "TDRL": ReleaseDate, "TENC": EncodedBy, "TEXT": Lyricist, "TIT1": Work,
+	"TIT2": Title, "TIT3": Subtitle, "TKEY": InitialKey, "TLAN": Language, "TMED": Media, "TMOO": Mood,
+	"TPE1": Artist, "TPE2": AlbumArtist, "TPE3": Conductor, "TPE4": Remixer, "TPOS": DiscNumber, "TPUB": Label,
+	"TRCK": TrackNumber, "TSO2": AlbumArtistSort, "TSOA": AlbumSort, "TSOC": ComposerSort, "TSOP": ArtistSort,
+	"TSOT": TitleSort, "TSRC": ISRC, "TSSE": Encoding, "TSST": DiscSubtitle, "GRP1": Grouping,
+}
+
+// salvageVorbisComments reads Vorbis comments up to the first which is cut short.
+func salvageVorbisComments(data []byte) (map[string][]string, error) {
+	tags := map[string][]string{}
+	next := func() ([]byte, bool) {
+		if len(data) < 4 {
+			return nil, false
+		}
+		size := binary.LittleEndian.Uint32(data)
+		if uint64(size) > uint64(len(data)-4) {
+			return nil, false
+		}
+		field := data[4 : 4+size]
+		data = data[4+size:]
+		return field, true
+	}
+
+	if _, ok := next(); !ok {
+		return tags, fmt.Errorf("vendor cut short: %w", ErrInvalidFile)
+	}
+	if len(data) < 4 {
+		return tags, fmt.Errorf("count cut short: %w", ErrInvalidFile)
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+	for i := range count {
+		field, ok := next()
+		if !ok {
+			return tags, fmt.Errorf("comment %d of %d cut short: %w", i+1, count, ErrInvalidFile)
+		}
+		key, value, ok := strings.Cut(string(field), "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToUpper(key)
+		tags[key] = append(tags[key], value)
+	}
+	return tags, nil
+}
+
+// salvageAPE reads the text items of an APEv2 tag, which ends with its footer, up to the first which is cut short.
+func salvageAPE(tag []byte) (map[string][]string, error) {
+	tags := map[string][]string{}
+	footer := tag[len(tag)-32:]
+	count := binary.LittleEndian.Uint32(footer[16:])
+	items := tag[:len(tag)-32]
+	if binary.LittleEndian.Uint32(footer[20:])&(1<<31) != 0 && len(items) >= 32 {
+		items = items[32:] // header
+	}
+	for i := range count {
+		if len(items) < 8 {
+			return tags, fmt.Errorf("item %d of %d cut short: %w", i+1, count, ErrInvalidFile)
+		}
+		size := binary.LittleEndian.Uint32(items)
+		flags := binary.LittleEndian.Uint32(items[4:])
+		end := bytes.IndexByte(items[8:], 0)
+		if end < 0 || uint64(size) > uint64(len(items)-8-end-1) {
+			return tags, fmt.Errorf("item %d of %d cut short: %w", i+1, count, ErrInvalidFile)
+		}
+		key := strings.ToUpper(string(items[8 : 8+end]))
+		value := items[8+end+1 : 8+end+1+int(size)]
+		items = items[8+end+1+int(size):]
+		if flags&0x06 == 0 { // text
+			tags[key] = append(tags[key], strings.Split(string(value), "\x00")...)
+		}
+	}
+	return tags, nil
+}
+
+// salvageID3v1 reads the text fields and track number of an ID3v1 tag.
+func salvageID3v1(tag []byte) map[string][]string {
+	tags := map[string][]string{}
+	field := func(key string, b []byte) {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		if s := strings.TrimRight(decodeID3v2Text(0, b), " "); s != "" {
+			tags[key] = []string{s}
+		}
+	}
+	field(Title, tag[3:33])
+	field(Artist, tag[33:63])
+	field(Album, tag[63:93])
+	field(Date, tag[93:97])
+	if tag[125] == 0 && tag[126] != 0 { // ID3v1.1
+		field(Comment, tag[97:125])
+		tags[TrackNumber] = []string{strconv.Itoa(int(tag[126]))}
+	} else {
+		field(Comment, tag[97:127])
+	}
+	return tags
+}
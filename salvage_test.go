@@ -0,0 +1,46 @@
+package taglib_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestReadTagsSalvage(t *testing.T) {
+	t.Parallel()
+
+	// an intact file reads like ReadTags
+	path := tmpf(t, egFLAC, "eg.flac")
+	tags, errs, err := taglib.ReadTagsSalvage(path)
+	nilErr(t, err)
+	eq(t, len(errs), 0)
+	want, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, tags, want)
+
+	// a FLAC file with a bad STREAMINFO, cut short after its VORBIS_COMMENT block
+	damaged := append([]byte{}, egFLAC[:120]...)
+	damaged[8] = 0xff
+	path = tmpf(t, damaged, "eg.flac")
+	_, err = taglib.ReadTags(path)
+	eq(t, errors.Is(err, taglib.ErrInvalidFile), true)
+
+	tags, errs, err = taglib.ReadTagsSalvage(path)
+	nilErr(t, err)
+	tagEq(t, tags, want)
+	eq(t, len(errs), 1)
+	var parseErr *taglib.ParseError
+	eq(t, errors.As(errs[0], &parseErr), true)
+	eq(t, parseErr.Tag, "FLAC")
+
+	// cut short inside the VORBIS_COMMENT block, keeping only some comments
+	path = tmpf(t, damaged[:100], "eg.flac")
+	tags, errs, err = taglib.ReadTagsSalvage(path)
+	nilErr(t, err)
+	eq(t, len(tags) < len(want), true)
+	eq(t, len(errs), 2)
+	eq(t, errors.As(errs[1], &parseErr), true)
+	eq(t, parseErr.Tag, "FLAC VORBIS_COMMENT")
+	eq(t, parseErr.Offset, int64(42))
+}
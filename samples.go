@@ -0,0 +1,121 @@
+package taglib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// readSampleFrames reads the number of sample frames of the formats TagLib doesn't count them for, from the media
+// duration of the sound track of MP4 files, converted to sampleRate if its timescale differs, and from the granule
+// position of the last page of Ogg Vorbis, Opus, and Speex streams. It reports false for other formats.
+func readSampleFrames(f fileReader, sampleRate uint) (uint64, bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var magic [8]byte
+	if _, err := f.ReadAt(magic[:], 0); err != nil && err != io.EOF {
+		return 0, false, err
+	}
+	switch {
+	case string(magic[4:8]) == "ftyp":
+		return mp4SampleFrames(f, info.Size(), sampleRate), true, nil
+	case string(magic[:4]) == "OggS":
+		return oggSampleFrames(f, info.Size())
+	}
+	return 0, false, nil
+}
+
+// mp4SampleFrames reads the duration of the first sound track from its "mdhd" atom, whose timescale is usually the
+// sample rate. The duration includes any encoder delay and padding trimmed by an edit list.
+func mp4SampleFrames(r io.ReaderAt, end int64, sampleRate uint) uint64 {
+	for _, moov := range mp4Atoms(r, 0, end) {
+		if moov.typ != "moov" {
+			continue
+		}
+		for _, trak := range mp4Atoms(r, moov.offset+moov.headerSize, moov.offset+moov.size) {
+			if trak.typ != "trak" {
+				continue
+			}
+			for _, mdia := range mp4Atoms(r, trak.offset+trak.headerSize, trak.offset+trak.size) {
+				if mdia.typ != "mdia" {
+					continue
+				}
+				var sound bool
+				var timescale, duration uint64
+				for _, atom := range mp4Atoms(r, mdia.offset+mdia.headerSize, mdia.offset+mdia.size) {
+					// both are full atoms, with a version and flags first
+					data := make([]byte, min(atom.size-atom.headerSize, 32))
+					if _, err := r.ReadAt(data, atom.offset+atom.headerSize); err != nil {
+						continue
+					}
+					switch {
+					case atom.typ == "hdlr" && len(data) >= 12:
+						sound = string(data[8:12]) == "soun"
+					case atom.typ == "mdhd" && len(data) >= 20 && data[0] == 0:
+						timescale = uint64(binary.BigEndian.Uint32(data[12:]))
+						duration = uint64(binary.BigEndian.Uint32(data[16:]))
+					case atom.typ == "mdhd" && len(data) >= 32 && data[0] == 1:
+						timescale = uint64(binary.BigEndian.Uint32(data[20:]))
+						duration = binary.BigEndian.Uint64(data[24:])
+					}
+				}
+				if !sound || timescale == 0 {
+					continue
+				}
+				if sampleRate == 0 || uint64(sampleRate) == timescale {
+					return duration
+				}
+				return duration * uint64(sampleRate) / timescale
+			}
+		}
+	}
+	return 0
+}
+
+// oggMaxPage is the largest size of an Ogg page, with 255 segments of 255 bytes.
+const oggMaxPage = 27 + 255 + 255*255
+
+// oggSampleFrames reads the granule position of the last page of the first logical stream, which counts its samples
+// for Vorbis, Opus, and Speex. For Opus, the pre-skip samples are left out. Other codecs, such as FLAC, are counted by
+// TagLib, and report false.
+func oggSampleFrames(r io.ReaderAt, end int64) (uint64, bool, error) {
+	packets := oggHeaderPackets(r, 1)
+	if len(packets) == 0 {
+		return 0, false, nil
+	}
+	id := packets[0]
+	var preSkip uint64
+	switch {
+	case bytes.HasPrefix(id, []byte("OpusHead")) && len(id) >= 12:
+		preSkip = uint64(binary.LittleEndian.Uint16(id[10:]))
+	case bytes.HasPrefix(id, []byte("\x01vorbis")), bytes.HasPrefix(id, []byte("Speex   ")):
+	default:
+		return 0, false, nil
+	}
+
+	var first [27]byte
+	if _, err := r.ReadAt(first[:], 0); err != nil {
+		return 0, false, err
+	}
+	serial := first[14:18]
+
+	tail := make([]byte, min(end, oggMaxPage))
+	if _, err := r.ReadAt(tail, end-int64(len(tail))); err != nil && err != io.EOF {
+		return 0, false, err
+	}
+	for i := bytes.LastIndex(tail, []byte("OggS")); i >= 0; i = bytes.LastIndex(tail[:i], []byte("OggS")) {
+		page := tail[i:]
+		if len(page) < 27 || !bytes.Equal(page[14:18], serial) {
+			continue
+		}
+		granule := binary.LittleEndian.Uint64(page[6:])
+		if granule == ^uint64(0) { // no packet ends on this page
+			continue
+		}
+		return granule - min(granule, preSkip), true, nil
+	}
+	return 0, true, nil
+}
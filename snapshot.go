@@ -0,0 +1,171 @@
+package taglib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrSnapshotMismatch is returned by [Restore] when the audio of the file isn't the audio the snapshot was taken of.
+var ErrSnapshotMismatch = fmt.Errorf("snapshot doesn't match file")
+
+// snapshotMagic starts every snapshot, followed by the version.
+const snapshotMagic = "taglib snapshot\x00\x01"
+
+// Snapshot captures all the metadata of the file at path byte for byte, as a blob which can be stored and passed to
+// [Restore] to write it back. It holds everything before and after the audio data found by [ReadAudioRange], so it
+// covers every frame, field, and image, including those the other functions of this package don't read, along with
+// a checksum of the audio data itself. Files whose audio data can't be found fail with [ErrInvalidFile].
+func Snapshot(path string) (_ []byte, err error) {
+	defer wrapErr(&err, "snapshot", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+	offset, length, err := ReadAudioRange(path)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := audioChecksum(f, offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, offset)
+	if _, err := f.ReadAt(head, 0); err != nil {
+		return nil, fmt.Errorf("read head: %w", err)
+	}
+	tail := make([]byte, info.Size()-offset-length)
+	if _, err := f.ReadAt(tail, offset+length); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read tail: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	binary.Write(&buf, binary.BigEndian, uint64(len(head)))
+	buf.Write(head)
+	binary.Write(&buf, binary.BigEndian, uint64(len(tail)))
+	buf.Write(tail)
+	binary.Write(&buf, binary.BigEndian, uint64(length))
+	buf.Write(sum)
+	return buf.Bytes(), nil
+}
+
+// Restore writes the metadata captured by [Snapshot] back to the file at path, which must have the same audio data.
+// The file is restored byte for byte, undoing any change to its metadata since the snapshot. Otherwise, it fails with
+// [ErrSnapshotMismatch] without changing the file. The file is written to a copy which is renamed over it.
+func Restore(path string, snapshot []byte) (err error) {
+	defer wrapErr(&err, "restore", path)
+	head, tail, length, sum, err := parseSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	offset, currentLength, err := ReadAudioRange(path)
+	if err != nil {
+		return err
+	}
+	if currentLength != length {
+		return fmt.Errorf("audio of %d bytes, not %d: %w", currentLength, length, ErrSnapshotMismatch)
+	}
+	currentSum, err := audioChecksum(f, offset, length)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(currentSum, sum) {
+		return fmt.Errorf("audio checksum: %w", ErrSnapshotMismatch)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".taglib-*"+filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	defer tmp.Close()
+
+	if _, err := tmp.Write(head); err != nil {
+		return fmt.Errorf("write head: %w", err)
+	}
+	if _, err := io.Copy(tmp, io.NewSectionReader(f, offset, length)); err != nil {
+		return fmt.Errorf("copy audio: %w", err)
+	}
+	if _, err := tmp.Write(tail); err != nil {
+		return fmt.Errorf("write tail: %w", err)
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		return fmt.Errorf("chmod temp: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename temp: %w", err)
+	}
+	return nil
+}
+
+func parseSnapshot(snapshot []byte) (head, tail []byte, length int64, sum []byte, err error) {
+	r := bytes.NewReader(snapshot)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, nil, 0, nil, fmt.Errorf("read magic: %w", ErrInvalidFile)
+	}
+	readBlock := func() ([]byte, error) {
+		var size uint64
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := make([]byte, size)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	var size uint64
+	sum = make([]byte, sha256.Size)
+	head, err = readBlock()
+	if err == nil {
+		tail, err = readBlock()
+	}
+	if err == nil {
+		err = binary.Read(r, binary.BigEndian, &size)
+	}
+	if err == nil {
+		_, err = io.ReadFull(r, sum)
+	}
+	if err != nil {
+		return nil, nil, 0, nil, fmt.Errorf("read snapshot: %w", errors.Join(ErrInvalidFile, err))
+	}
+	return head, tail, int64(size), sum, nil
+}
+
+// audioChecksum is the SHA-256 of the audio range of a file, including anything between the parts of its audio data.
+func audioChecksum(f *os.File, offset, length int64) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, offset, length)); err != nil {
+		return nil, fmt.Errorf("hash audio: %w", err)
+	}
+	return h.Sum(nil), nil
+}
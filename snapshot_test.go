@@ -0,0 +1,48 @@
+package taglib_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range append(testPaths(t), nichePaths(t)...) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			// the Vorbis example has header packets only
+			if filepath.Base(path) == "eg.ogg" {
+				t.Skip()
+			}
+
+			before, err := os.ReadFile(path)
+			nilErr(t, err)
+			snapshot, err := taglib.Snapshot(path)
+			nilErr(t, err)
+
+			err = taglib.WriteTags(path, map[string][]string{
+				taglib.Title:   {"Title"},
+				taglib.Comment: {string(bytes.Repeat([]byte("comment "), 1024))},
+			}, taglib.Clear)
+			nilErr(t, err)
+			nilErr(t, taglib.WriteImage(path, coverJPG))
+
+			nilErr(t, taglib.Restore(path, snapshot))
+			after, err := os.ReadFile(path)
+			nilErr(t, err)
+			eq(t, bytes.Equal(before, after), true)
+		})
+	}
+
+	snapshot, err := taglib.Snapshot(tmpf(t, egFLAC, "eg.flac"))
+	nilErr(t, err)
+	err = taglib.Restore(tmpf(t, egMP3, "eg.mp3"), snapshot)
+	eq(t, errors.Is(err, taglib.ErrSnapshotMismatch), true)
+	err = taglib.Restore(tmpf(t, egFLAC, "eg.flac"), snapshot[:len(snapshot)-1])
+	eq(t, errors.Is(err, taglib.ErrInvalidFile), true)
+}
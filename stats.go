@@ -0,0 +1,164 @@
+package taglib
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Stats aggregates the tags of many files, such as a whole library, into a [StatsReport] of the keys in use, how many
+// distinct values each has, the files missing required keys, and the files with mixed tag containers. Its methods
+// may be called from many goroutines at once. The zero Stats is ready to use.
+type Stats struct {
+	// Required are the keys every file is expected to have, such as [Artist] and [Title]
+	Required []string
+
+	mu       sync.Mutex
+	files    int
+	keys     map[string]*keyStats
+	missing  []MissingKeys
+	warnings []StatsWarning
+}
+
+type keyStats struct {
+	files  int
+	values map[string]struct{}
+}
+
+// StatsReport is the report of a [Stats]. Keys are sorted, and files are in the order they were added.
+type StatsReport struct {
+	// Files is the number of files added
+	Files int
+	// Keys are the keys found in any file
+	Keys []KeyStats
+	// Missing are the files without some of the required keys
+	Missing []MissingKeys
+	// Warnings are the files with problems found while adding them, such as mixed tag containers
+	Warnings []StatsWarning
+}
+
+// KeyStats is how a key is used across the files of a [Stats].
+type KeyStats struct {
+	Key string
+	// Files is the number of files with the key
+	Files int
+	// Values is the number of distinct values of the key across all files
+	Values int
+}
+
+// MissingKeys are the required keys of a [Stats] which a file doesn't have.
+type MissingKeys struct {
+	Path string
+	Keys []string
+}
+
+// StatsWarning is a problem with a file found by [Stats.Add].
+type StatsWarning struct {
+	Path    string
+	Message string
+}
+
+// Add reads the tags and tag regions of the file at path and adds them to the report. A file with more than one tag
+// container, such as ID3v2 and APEv2 tags in an MPEG file or an ID3v2 tag in a FLAC file, gets a warning, since
+// players disagree on which to read. ID3v1 tags alongside ID3v2 tags are common, and don't.
+func (s *Stats) Add(path string) error {
+	tags, err := ReadTags(path)
+	if err != nil {
+		return err
+	}
+	regions, err := ReadTagRegions(path)
+	if err != nil {
+		return err
+	}
+
+	s.AddTags(path, tags)
+	if containers := mixedContainers(regions); containers != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.warnings = append(s.warnings, StatsWarning{
+			Path:    path,
+			Message: fmt.Sprintf("mixed tag containers: %s", strings.Join(containers, ", ")),
+		})
+	}
+	return nil
+}
+
+// AddTags adds tags already read from the file at path to the report, without reading the file again.
+func (s *Stats) AddTags(path string, tags map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files++
+	if s.keys == nil {
+		s.keys = map[string]*keyStats{}
+	}
+	for k, vs := range tags {
+		if len(vs) == 0 {
+			continue
+		}
+		ks := s.keys[k]
+		if ks == nil {
+			ks = &keyStats{values: map[string]struct{}{}}
+			s.keys[k] = ks
+		}
+		ks.files++
+		for _, v := range vs {
+			ks.values[v] = struct{}{}
+		}
+	}
+
+	var missing []string
+	for _, k := range s.Required {
+		if len(tags[strings.ToUpper(k)]) == 0 {
+			missing = append(missing, k)
+		}
+	}
+	if missing != nil {
+		s.missing = append(s.missing, MissingKeys{Path: path, Keys: missing})
+	}
+}
+
+// Report returns the report of the files added so far.
+func (s *Stats) Report() StatsReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := StatsReport{
+		Files:    s.files,
+		Missing:  slices.Clone(s.missing),
+		Warnings: slices.Clone(s.warnings),
+	}
+	for _, k := range slices.Sorted(maps.Keys(s.keys)) {
+		ks := s.keys[k]
+		report.Keys = append(report.Keys, KeyStats{Key: k, Files: ks.files, Values: len(ks.values)})
+	}
+	return report
+}
+
+// mixedContainers returns the tag containers of a file if it has more than one, leaving out an ID3v1 tag alongside
+// an ID3v2 tag.
+func mixedContainers(regions []TagRegion) []string {
+	var containers []string
+	for _, r := range regions {
+		kind := r.Kind
+		switch {
+		case kind == "FLAC VORBIS_COMMENT":
+			kind = "Vorbis comments"
+		case kind != "ID3v2" && kind != "ID3v1" && kind != "APEv2" && kind != "Lyrics3v2":
+			continue
+		}
+		if !slices.Contains(containers, kind) {
+			containers = append(containers, kind)
+		}
+	}
+	count := len(containers)
+	if slices.Contains(containers, "ID3v1") && slices.Contains(containers, "ID3v2") {
+		count--
+	}
+	if count < 2 {
+		return nil
+	}
+	return containers
+}
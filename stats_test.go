@@ -0,0 +1,54 @@
+package taglib_test
+
+import (
+	"slices"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	a := tmpf(t, egFLAC, "a.flac")
+	nilErr(t, taglib.WriteTags(a, map[string][]string{
+		taglib.Artist: {"Artist"},
+		taglib.Title:  {"Title A"},
+		taglib.Genre:  {"Rock", "Pop"},
+	}, taglib.Clear))
+
+	b := tmpf(t, egMP3, "b.mp3")
+	nilErr(t, taglib.WriteTags(b, map[string][]string{
+		taglib.Artist: {"Artist"},
+		taglib.Genre:  {"Rock"},
+	}, taglib.Clear))
+
+	// an ID3v2 tag in front of a FLAC file
+	c := tmpf(t, append([]byte("ID3\x04\x00\x00\x00\x00\x00\x0a"+string(make([]byte, 10))), egFLAC...), "c.flac")
+
+	stats := taglib.Stats{Required: []string{taglib.Artist, taglib.Title}}
+	for _, path := range []string{a, b, c} {
+		nilErr(t, stats.Add(path))
+	}
+	stats.AddTags("d.flac", map[string][]string{taglib.Title: {"Title D"}})
+
+	report := stats.Report()
+	eq(t, report.Files, 4)
+
+	byKey := map[string]taglib.KeyStats{}
+	for _, ks := range report.Keys {
+		byKey[ks.Key] = ks
+	}
+	eq(t, byKey[taglib.Artist], taglib.KeyStats{Key: taglib.Artist, Files: 3, Values: 2})
+	eq(t, byKey[taglib.Genre], taglib.KeyStats{Key: taglib.Genre, Files: 2, Values: 2})
+	eq(t, byKey[taglib.Title], taglib.KeyStats{Key: taglib.Title, Files: 2, Values: 2})
+
+	eq(t, len(report.Missing), 3)
+	eq(t, report.Missing[0].Path, b)
+	eq(t, slices.Equal(report.Missing[0].Keys, []string{taglib.Title}), true)
+	eq(t, report.Missing[2].Path, "d.flac")
+	eq(t, slices.Equal(report.Missing[2].Keys, []string{taglib.Artist}), true)
+
+	eq(t, len(report.Warnings), 1)
+	eq(t, report.Warnings[0].Path, c)
+}
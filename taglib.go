@@ -4,25 +4,59 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
 //go:embed taglib.wasm
-var binary []byte // WASM blob. To override, go build -ldflags="-X 'go.senan.xyz/taglib.binaryPath=/path/to/taglib.wasm'"
+var embeddedBinary []byte // WASM blob. To override, go build -ldflags="-X 'go.senan.xyz/taglib.binaryPath=/path/to/taglib.wasm'"
 var binaryPath string
 
+// ErrInvalidFile is returned when TagLib can't parse a file. A file which doesn't exist fails with an error matching
+// [io/fs.ErrNotExist] instead.
 var ErrInvalidFile = fmt.Errorf("invalid file")
 var ErrSavingFile = fmt.Errorf("can't save file")
 
+// Error records the operation and file of an error returned by the package. It unwraps to the underlying error, so
+// errors.Is(err, ErrInvalidFile) still matches.
+type Error struct {
+	// Op is the operation, such as "read tags" or "write image"
+	Op string
+	// Path is the absolute path of the file, or its name in an [FS]
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Op + " " + e.Path + ": " + e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// wrapErr wraps a non nil *err in an [Error] for op on path. It is deferred by exported functions with a named error
+// result.
+func wrapErr(err *error, op, path string) {
+	if *err == nil {
+		return
+	}
+	if abs, absErr := filepath.Abs(path); absErr == nil {
+		path = abs
+	}
+	*err = &Error{Op: op, Path: path, Err: *err}
+}
+
 // These constants define normalized tag keys used by TagLib's [property mapping].
 // When using [ReadTags], the library will map format-specific metadata to these standardized keys.
 // Similarly, [WriteTags] will map these keys back to the appropriate format-specific fields.
@@ -32,123 +66,191 @@ var ErrSavingFile = fmt.Errorf("can't save file")
 //
 // [property mapping]: https://taglib.org/api/p_propertymapping.html
 const (
-	AcoustIDFingerprint       = "ACOUSTID_FINGERPRINT"
-	AcoustIDID                = "ACOUSTID_ID"
-	Album                     = "ALBUM"
-	AlbumArtist               = "ALBUMARTIST"
-	AlbumArtistSort           = "ALBUMARTISTSORT"
-	AlbumSort                 = "ALBUMSORT"
-	Arranger                  = "ARRANGER"
-	Artist                    = "ARTIST"
-	Artists                   = "ARTISTS"
-	ArtistSort                = "ARTISTSORT"
-	ArtistWebpage             = "ARTISTWEBPAGE"
-	ASIN                      = "ASIN"
-	AudioSourceWebpage        = "AUDIOSOURCEWEBPAGE"
-	Barcode                   = "BARCODE"
-	BPM                       = "BPM"
-	CatalogNumber             = "CATALOGNUMBER"
-	Comment                   = "COMMENT"
-	Compilation               = "COMPILATION"
-	Composer                  = "COMPOSER"
-	ComposerSort              = "COMPOSERSORT"
-	Conductor                 = "CONDUCTOR"
-	Copyright                 = "COPYRIGHT"
-	CopyrightURL              = "COPYRIGHTURL"
-	Date                      = "DATE"
-	DiscNumber                = "DISCNUMBER"
-	DiscSubtitle              = "DISCSUBTITLE"
-	DJMixer                   = "DJMIXER"
-	EncodedBy                 = "ENCODEDBY"
-	Encoding                  = "ENCODING"
-	EncodingTime              = "ENCODINGTIME"
-	Engineer                  = "ENGINEER"
-	FileType                  = "FILETYPE"
-	FileWebpage               = "FILEWEBPAGE"
-	GaplessPlayback           = "GAPLESSPLAYBACK"
-	Genre                     = "GENRE"
-	Grouping                  = "GROUPING"
-	InitialKey                = "INITIALKEY"
-	InvolvedPeople            = "INVOLVEDPEOPLE"
-	ISRC                      = "ISRC"
-	Label                     = "LABEL"
-	Language                  = "LANGUAGE"
-	Length                    = "LENGTH"
-	License                   = "LICENSE"
-	Lyricist                  = "LYRICIST"
-	Lyrics                    = "LYRICS"
-	Media                     = "MEDIA"
-	Mixer                     = "MIXER"
-	Mood                      = "MOOD"
-	MovementCount             = "MOVEMENTCOUNT"
-	MovementName              = "MOVEMENTNAME"
-	MovementNumber            = "MOVEMENTNUMBER"
-	MusicBrainzAlbumID        = "MUSICBRAINZ_ALBUMID"
-	MusicBrainzAlbumArtistID  = "MUSICBRAINZ_ALBUMARTISTID"
-	MusicBrainzArtistID       = "MUSICBRAINZ_ARTISTID"
-	MusicBrainzReleaseGroupID = "MUSICBRAINZ_RELEASEGROUPID"
-	MusicBrainzReleaseTrackID = "MUSICBRAINZ_RELEASETRACKID"
-	MusicBrainzTrackID        = "MUSICBRAINZ_TRACKID"
-	MusicBrainzWorkID         = "MUSICBRAINZ_WORKID"
-	MusicianCredits           = "MUSICIANCREDITS"
-	MusicIPPUID               = "MUSICIP_PUID"
-	OriginalAlbum             = "ORIGINALALBUM"
-	OriginalArtist            = "ORIGINALARTIST"
-	OriginalDate              = "ORIGINALDATE"
-	OriginalFilename          = "ORIGINALFILENAME"
-	OriginalLyricist          = "ORIGINALLYRICIST"
-	Owner                     = "OWNER"
-	PaymentWebpage            = "PAYMENTWEBPAGE"
-	Performer                 = "PERFORMER"
-	PlaylistDelay             = "PLAYLISTDELAY"
-	Podcast                   = "PODCAST"
-	PodcastCategory           = "PODCASTCATEGORY"
-	PodcastDesc               = "PODCASTDESC"
-	PodcastID                 = "PODCASTID"
-	PodcastURL                = "PODCASTURL"
-	ProducedNotice            = "PRODUCEDNOTICE"
-	Producer                  = "PRODUCER"
-	PublisherWebpage          = "PUBLISHERWEBPAGE"
-	RadioStation              = "RADIOSTATION"
-	RadioStationOwner         = "RADIOSTATIONOWNER"
-	RadioStationWebpage       = "RADIOSTATIONWEBPAGE"
-	ReleaseCountry            = "RELEASECOUNTRY"
-	ReleaseDate               = "RELEASEDATE"
-	ReleaseStatus             = "RELEASESTATUS"
-	ReleaseType               = "RELEASETYPE"
-	Remixer                   = "REMIXER"
-	Script                    = "SCRIPT"
-	ShowSort                  = "SHOWSORT"
-	ShowWorkMovement          = "SHOWWORKMOVEMENT"
-	Subtitle                  = "SUBTITLE"
-	TaggingDate               = "TAGGINGDATE"
-	Title                     = "TITLE"
-	TitleSort                 = "TITLESORT"
-	TrackNumber               = "TRACKNUMBER"
-	TVEpisode                 = "TVEPISODE"
-	TVEpisodeID               = "TVEPISODEID"
-	TVNetwork                 = "TVNETWORK"
-	TVSeason                  = "TVSEASON"
-	TVShow                    = "TVSHOW"
-	URL                       = "URL"
-	Work                      = "WORK"
+	AcoustIDFingerprint         = "ACOUSTID_FINGERPRINT"
+	AcoustIDID                  = "ACOUSTID_ID"
+	Album                       = "ALBUM"
+	AlbumArtist                 = "ALBUMARTIST"
+	AlbumArtistSort             = "ALBUMARTISTSORT"
+	AlbumSort                   = "ALBUMSORT"
+	Arranger                    = "ARRANGER"
+	Artist                      = "ARTIST"
+	Artists                     = "ARTISTS"
+	ArtistSort                  = "ARTISTSORT"
+	ArtistWebpage               = "ARTISTWEBPAGE"
+	ASIN                        = "ASIN"
+	AudioSourceWebpage          = "AUDIOSOURCEWEBPAGE"
+	Barcode                     = "BARCODE"
+	BPM                         = "BPM"
+	CatalogNumber               = "CATALOGNUMBER"
+	Comment                     = "COMMENT"
+	Compilation                 = "COMPILATION"
+	Composer                    = "COMPOSER"
+	ComposerSort                = "COMPOSERSORT"
+	Conductor                   = "CONDUCTOR"
+	Copyright                   = "COPYRIGHT"
+	CopyrightURL                = "COPYRIGHTURL"
+	Date                        = "DATE"
+	Description                 = "DESCRIPTION"
+	DiscNumber                  = "DISCNUMBER"
+	DiscSubtitle                = "DISCSUBTITLE"
+	DiscTotal                   = "DISCTOTAL"
+	DJMixer                     = "DJMIXER"
+	EncodedBy                   = "ENCODEDBY"
+	Encoding                    = "ENCODING"
+	EncodingTime                = "ENCODINGTIME"
+	Engineer                    = "ENGINEER"
+	FileType                    = "FILETYPE"
+	FileWebpage                 = "FILEWEBPAGE"
+	GaplessPlayback             = "GAPLESSPLAYBACK"
+	Genre                       = "GENRE"
+	Grouping                    = "GROUPING"
+	InitialKey                  = "INITIALKEY"
+	InvolvedPeople              = "INVOLVEDPEOPLE"
+	ISRC                        = "ISRC"
+	Label                       = "LABEL"
+	Language                    = "LANGUAGE"
+	Length                      = "LENGTH"
+	License                     = "LICENSE"
+	Lyricist                    = "LYRICIST"
+	Lyrics                      = "LYRICS"
+	Media                       = "MEDIA"
+	Mixer                       = "MIXER"
+	Mood                        = "MOOD"
+	MovementCount               = "MOVEMENTCOUNT"
+	MovementName                = "MOVEMENTNAME"
+	MovementNumber              = "MOVEMENTNUMBER"
+	MusicBrainzAlbumID          = "MUSICBRAINZ_ALBUMID"
+	MusicBrainzAlbumArtistID    = "MUSICBRAINZ_ALBUMARTISTID"
+	MusicBrainzArtistID         = "MUSICBRAINZ_ARTISTID"
+	MusicBrainzDiscID           = "MUSICBRAINZ_DISCID"
+	MusicBrainzReleaseGroupID   = "MUSICBRAINZ_RELEASEGROUPID"
+	MusicBrainzReleaseTrackID   = "MUSICBRAINZ_RELEASETRACKID"
+	MusicBrainzTrackID          = "MUSICBRAINZ_TRACKID"
+	MusicBrainzWorkID           = "MUSICBRAINZ_WORKID"
+	MusicianCredits             = "MUSICIANCREDITS"
+	MusicIPPUID                 = "MUSICIP_PUID"
+	OriginalAlbum               = "ORIGINALALBUM"
+	OriginalArtist              = "ORIGINALARTIST"
+	OriginalDate                = "ORIGINALDATE"
+	OriginalFilename            = "ORIGINALFILENAME"
+	OriginalLyricist            = "ORIGINALLYRICIST"
+	OriginalYear                = "ORIGINALYEAR"
+	Owner                       = "OWNER"
+	PaymentWebpage              = "PAYMENTWEBPAGE"
+	Performer                   = "PERFORMER"
+	PlaylistDelay               = "PLAYLISTDELAY"
+	Podcast                     = "PODCAST"
+	PodcastCategory             = "PODCASTCATEGORY"
+	PodcastDesc                 = "PODCASTDESC"
+	PodcastID                   = "PODCASTID"
+	PodcastURL                  = "PODCASTURL"
+	ProducedNotice              = "PRODUCEDNOTICE"
+	Producer                    = "PRODUCER"
+	PublisherWebpage            = "PUBLISHERWEBPAGE"
+	R128AlbumGain               = "R128_ALBUM_GAIN"
+	R128TrackGain               = "R128_TRACK_GAIN"
+	RadioStation                = "RADIOSTATION"
+	RadioStationOwner           = "RADIOSTATIONOWNER"
+	RadioStationWebpage         = "RADIOSTATIONWEBPAGE"
+	Rating                      = "RATING"
+	ReleaseCountry              = "RELEASECOUNTRY"
+	ReleaseDate                 = "RELEASEDATE"
+	ReleaseStatus               = "RELEASESTATUS"
+	ReleaseType                 = "RELEASETYPE"
+	Remixer                     = "REMIXER"
+	ReplayGainAlbumGain         = "REPLAYGAIN_ALBUM_GAIN"
+	ReplayGainAlbumPeak         = "REPLAYGAIN_ALBUM_PEAK"
+	ReplayGainReferenceLoudness = "REPLAYGAIN_REFERENCE_LOUDNESS"
+	ReplayGainTrackGain         = "REPLAYGAIN_TRACK_GAIN"
+	ReplayGainTrackPeak         = "REPLAYGAIN_TRACK_PEAK"
+	Script                      = "SCRIPT"
+	ShowSort                    = "SHOWSORT"
+	ShowWorkMovement            = "SHOWWORKMOVEMENT"
+	Subtitle                    = "SUBTITLE"
+	TaggingDate                 = "TAGGINGDATE"
+	Title                       = "TITLE"
+	TitleSort                   = "TITLESORT"
+	TrackNumber                 = "TRACKNUMBER"
+	TrackTotal                  = "TRACKTOTAL"
+	TVEpisode                   = "TVEPISODE"
+	TVEpisodeID                 = "TVEPISODEID"
+	TVNetwork                   = "TVNETWORK"
+	TVSeason                    = "TVSEASON"
+	TVShow                      = "TVSHOW"
+	URL                         = "URL"
+	Work                        = "WORK"
 )
 
 // ReadTags reads all metadata tags from an audio file at the given path.
-func ReadTags(path string) (map[string][]string, error) {
+func ReadTags(path string) (_ map[string][]string, err error) {
+	defer wrapErr(&err, "read tags", path)
+	return readTags(path, nil)
+}
+
+func readTags(path string, m Mapping) (map[string][]string, error) {
 	var err error
 	path, err = filepath.Abs(path)
 	if err != nil {
 		return nil, fmt.Errorf("make path abs %w", err)
 	}
+	if tags, _, ok, err := readWideWAV(path); ok || err != nil {
+		return tags, err
+	}
 
-	dir := filepath.Dir(path)
-	mod, err := newModuleRO(dir)
+	mod, err := newModuleRO(path)
 	if err != nil {
 		return nil, fmt.Errorf("init module: %w", err)
 	}
 	defer mod.close()
 
+	return readFileTagsModule(&mod, path, m)
+}
+
+// ReadTagKeys reads the given keys from an audio file at the given path. It is a filter over [ReadTags], which still
+// reads every tag, so it costs the same. Keys match regardless of case, and keys the file doesn't have are left out.
+func ReadTagKeys(path string, keys ...string) (_ map[string][]string, err error) {
+	defer wrapErr(&err, "read tags", path)
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("make path abs %w", err)
+	}
+	if tags, _, ok, err := readWideWAV(path); ok || err != nil {
+		maps.DeleteFunc(tags, func(k string, _ []string) bool {
+			return !slices.ContainsFunc(keys, func(key string) bool { return strings.EqualFold(key, k) })
+		})
+		return tags, err
+	}
+
+	mod, err := newModuleRO(path)
+	if err != nil {
+		return nil, fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	tags, err := readFileTagsModule(&mod, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	maps.DeleteFunc(tags, func(k string, _ []string) bool {
+		return !slices.ContainsFunc(keys, func(key string) bool { return strings.EqualFold(key, k) })
+	})
+	return tags, nil
+}
+
+// readFileTagsModule reads the tags of the file on disk at path with mod, with the keys of m and of [nativeMapping]
+// read from their native names.
+func readFileTagsModule(mod *module, path string, m Mapping) (map[string][]string, error) {
+	tags, err := readTagsModule(mod, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := readNativeTags(path, tags, m); err != nil {
+		return nil, fmt.Errorf("read native tags: %w", err)
+	}
+	return tags, nil
+}
+
+func readTagsModule(mod *module, path string) (map[string][]string, error) {
 	var raw wasmStrings
 	if err := mod.call("taglib_file_tags", &raw, wasmString(wasmPath(path))); err != nil {
 		return nil, fmt.Errorf("call: %w", err)
@@ -174,55 +276,296 @@ type Properties struct {
 	Length time.Duration
 	// Channels is the number of audio channels
 	Channels uint
+	// ChannelLayout is the channel configuration where known, such as "mono", "stereo", "joint stereo", or "5.1", from
+	// the MPEG channel mode, the WAV channel mask, or the channel order of FLAC, Vorbis, and Opus. Empty if unknown
+	ChannelLayout string
 	// SampleRate in Hz
 	SampleRate uint
-	// Bitrate in kbit/s
+	// Bitrate in kbit/s, as computed or declared by TagLib for the format
 	Bitrate uint
+	// AverageBitrate is the average bitrate of the audio data in kbit/s, from its size and the length, without the
+	// rounding of Bitrate. It leaves out tags and padding, so it is the true bitrate of lossless files. 0 if unknown
+	AverageBitrate float64
+	// NominalBitrate is the bitrate declared by the stream in kbit/s, from the frame headers of CBR MPEG files and the
+	// nominal bitrate of Vorbis streams. 0 if unknown or variable
+	NominalBitrate uint
 	// Images contains metadata about all embedded images
 	Images []ImageDesc
+	// SampleFrames is the number of sample frames, 0 if unknown (reported for FLAC, Ogg FLAC, AIFF, WAV, DSF, DSDIFF, WavPack, Musepack, Ogg Vorbis, Opus, Speex, and MP4). MP4 counts include encoder delay and padding, Opus counts leave out the pre-skip
+	SampleFrames uint64
+	// BitsPerSample is the sample bit depth, 0 if unknown. DSD streams report 1, with SampleRate as the 1-bit rate (e.g. 2822400 for DSD64)
+	BitsPerSample uint
+	// WavPack contains WavPack specific properties, nil for other formats
+	WavPack *WavPackProperties
+	// MPC contains Musepack specific properties, nil for other formats
+	MPC *MPCProperties
+	// Vorbis contains Ogg Vorbis specific properties, nil for other formats
+	Vorbis *VorbisProperties
+	// Opus contains Ogg Opus specific properties, nil for other formats
+	Opus *OpusProperties
+	// MPEG contains MPEG audio specific properties, nil for other formats
+	MPEG *MPEGProperties
+	// AAC contains AAC specific properties of MP4 files and ADTS streams, nil for other formats
+	AAC *AACProperties
+	// ALAC contains Apple Lossless specific properties of MP4 files, nil for other formats
+	ALAC *ALACProperties
+	// EncoderInfo identifies the encoder, from the LAME tag, Vorbis comment vendor, or ID3v2 frames
+	EncoderInfo EncoderInfo
+	// Size is the size of the file in bytes, and ModTime its modification time, as of when the properties were read.
+	// Both are unset by [ReadPropertiesFS]
+	Size    int64
+	ModTime time.Time
+	// MetadataSize is the number of bytes of the file outside the range of its audio data found by [ReadAudioRange],
+	// which are its tags, embedded images, and padding, along with the few bytes of container headers. 0 if the audio
+	// data can't be found, and unset by [ReadPropertiesFS]
+	MetadataSize int64
+}
+
+// WavPackProperties contains properties specific to WavPack files.
+type WavPackProperties struct {
+	// Version is the WavPack stream version (e.g. 0x410)
+	Version uint
+	// Lossless is false for hybrid mode files, which are lossy unless paired with a correction file
+	Lossless bool
+	// CorrectionFile reports whether a ".wvc" correction file exists alongside the file
+	CorrectionFile bool
+}
+
+// MPCProperties contains properties specific to Musepack files.
+type MPCProperties struct {
+	// StreamVersion is the Musepack stream version, 7 or 8
+	StreamVersion uint
+}
+
+// VorbisProperties contains properties specific to Ogg Vorbis files. The bitrates are the hints from the identification header, in bit/s, 0 if unset by the encoder.
+type VorbisProperties struct {
+	// Version is the Vorbis version, 0 for all current streams
+	Version uint
+	// BitrateNominal is the target average bitrate
+	BitrateNominal uint
+	// BitrateMinimum is the lower bound for VBR streams
+	BitrateMinimum uint
+	// BitrateMaximum is the upper bound for VBR streams
+	BitrateMaximum uint
+}
+
+// OpusProperties contains the details of the Opus identification header, see RFC 7845.
+type OpusProperties struct {
+	// Version is the encapsulation version, 1 for all current streams
+	Version uint
+	// PreSkip is the number of 48 kHz samples to discard from the start of the decoded output
+	PreSkip uint
+	// InputSampleRate is the sample rate of the original input in Hz, 0 if unknown. Opus always decodes at 48 kHz
+	InputSampleRate uint
+	// OutputGain is the gain to apply to the decoded output in dB
+	OutputGain float64
+	// ChannelMappingFamily is 0 for mono or stereo, 1 for Vorbis channel order surround, 255 for unmapped channels
+	ChannelMappingFamily uint
 }
 
+// PictureType is the role of an embedded image, named as TagLib names the ID3v2 APIC picture types. Formats without
+// picture types, such as MP4, ignore it. Custom values are accepted, but formats which store the type as a number write
+// them as [PictureOther].
+type PictureType string
+
+// These constants define the picture types of the ID3v2 APIC frame, which FLAC, Ogg, and ASF pictures share.
+const (
+	PictureOther              PictureType = "Other"
+	PictureFileIcon           PictureType = "File Icon"
+	PictureOtherFileIcon      PictureType = "Other File Icon"
+	PictureFrontCover         PictureType = "Front Cover"
+	PictureBackCover          PictureType = "Back Cover"
+	PictureLeafletPage        PictureType = "Leaflet Page"
+	PictureMedia              PictureType = "Media"
+	PictureLeadArtist         PictureType = "Lead Artist"
+	PictureArtist             PictureType = "Artist"
+	PictureConductor          PictureType = "Conductor"
+	PictureBand               PictureType = "Band"
+	PictureComposer           PictureType = "Composer"
+	PictureLyricist           PictureType = "Lyricist"
+	PictureRecordingLocation  PictureType = "Recording Location"
+	PictureDuringRecording    PictureType = "During Recording"
+	PictureDuringPerformance  PictureType = "During Performance"
+	PictureMovieScreenCapture PictureType = "Movie Screen Capture"
+	PictureColouredFish       PictureType = "Coloured Fish"
+	PictureIllustration       PictureType = "Illustration"
+	PictureBandLogo           PictureType = "Band Logo"
+	PicturePublisherLogo      PictureType = "Publisher Logo"
+)
+
 // ImageDesc contains metadata about an embedded image without the actual image data.
 type ImageDesc struct {
-	// Type is the picture type (e.g., "Front Cover", "Back Cover")
+	// Type is the picture type (e.g., "Front Cover", "Back Cover"), see [ImageDesc.PictureType]
 	Type string
 	// Description is a textual description of the image
 	Description string
 	// MIMEType is the MIME type of the image (e.g., "image/jpeg")
 	MIMEType string
+	// Width and Height are the dimensions of the image in pixels, read from the headers of PNG, JPEG, GIF, and WebP
+	// images by [ReadImages]. 0 for other formats, and in [Properties.Images], which doesn't read the image data
+	Width, Height uint
+	// SHA1 is the hex SHA-1 of the image data, to find changed or duplicate artwork, computed by [ReadImages]. Empty
+	// in [Properties.Images]
+	SHA1 string
+}
+
+// PictureType returns Type as a [PictureType], to compare with the constants such as [PictureFrontCover].
+func (d ImageDesc) PictureType() PictureType {
+	return PictureType(d.Type)
 }
 
 // ReadProperties reads the audio properties from a file at the given path.
-func ReadProperties(path string) (Properties, error) {
-	var err error
+func ReadProperties(path string) (_ Properties, err error) {
+	defer wrapErr(&err, "read properties", path)
 	path, err = filepath.Abs(path)
 	if err != nil {
 		return Properties{}, fmt.Errorf("make path abs %w", err)
 	}
+	if _, properties, ok, err := readWideWAV(path); ok || err != nil {
+		return properties, err
+	}
 
-	dir := filepath.Dir(path)
-	mod, err := newModuleRO(dir)
+	mod, err := newModuleRO(path)
 	if err != nil {
 		return Properties{}, fmt.Errorf("init module: %w", err)
 	}
 	defer mod.close()
 
-	var raw wasmFileProperties
+	return readPropertiesModule(&mod, path)
+}
+
+func readPropertiesModule(mod *module, path string) (Properties, error) {
+	properties, err := readTagLibProperties(mod, path)
+	if err != nil {
+		return Properties{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Properties{}, err
+	}
+	defer f.Close()
+
+	if err := readGoProperties(f, &properties); err != nil {
+		return Properties{}, err
+	}
+	if properties.WavPack != nil {
+		correctionPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".wvc"
+		if _, err := os.Stat(correctionPath); err == nil {
+			properties.WavPack.CorrectionFile = true
+		}
+	}
+	return properties, nil
+}
+
+// fileReader is a file read by Go as well as TagLib, an *os.File or a [vfsFile].
+type fileReader interface {
+	io.ReaderAt
+	Stat() (fs.FileInfo, error)
+}
+
+// readGoProperties reads the properties TagLib doesn't report from f into properties, which holds those it does.
+func readGoProperties(f fileReader, properties *Properties) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	properties.Size = info.Size()
+	properties.ModTime = info.ModTime()
+
+	if err := readFormatProperties(f, properties); err != nil {
+		return fmt.Errorf("read format properties: %w", err)
+	}
+
+	encoderInfo, err := readEncoderInfo(f)
+	if err != nil {
+		return fmt.Errorf("read encoder info: %w", err)
+	}
+	properties.EncoderInfo = encoderInfo
+
+	mpeg, mpegBitrate, err := readMPEGProperties(f)
+	if err != nil {
+		return fmt.Errorf("read mpeg properties: %w", err)
+	}
+	properties.MPEG = mpeg
+	if mpeg != nil && !mpeg.VBR {
+		properties.NominalBitrate = uint(mpegBitrate)
+	}
+
+	sampleFrames, ok, err := readSampleFrames(f, properties.SampleRate)
+	if err != nil {
+		return fmt.Errorf("read sample frames: %w", err)
+	}
+	if ok {
+		properties.SampleFrames = sampleFrames
+	}
+
+	properties.AAC, err = readAACProperties(f)
+	if err != nil {
+		return fmt.Errorf("read aac properties: %w", err)
+	}
+	properties.ALAC, err = readALACProperties(f)
+	if err != nil {
+		return fmt.Errorf("read alac properties: %w", err)
+	}
+	if properties.ALAC != nil && properties.BitsPerSample == 0 {
+		properties.BitsPerSample = properties.ALAC.BitDepth
+	}
+
+	properties.ChannelLayout, err = readChannelLayout(f, properties.Channels, mpeg)
+	if err != nil {
+		return fmt.Errorf("read channel layout: %w", err)
+	}
+
+	properties.AverageBitrate, properties.MetadataSize, err = readAudioExtent(f, info.Size(), properties.Length.Seconds())
+	if err != nil {
+		return fmt.Errorf("read audio extent: %w", err)
+	}
+	return nil
+}
+
+// ReadPropertiesFast reads only the Length, Channels, SampleRate, and Bitrate of the audio of a file at the given path,
+// for scans of large libraries where the rest of [Properties] isn't needed. Unlike [ReadProperties], it doesn't copy
+// the descriptions of embedded images out of the module, and doesn't read the file again in Go for the other
+// properties.
+func ReadPropertiesFast(path string) (_ Properties, err error) {
+	defer wrapErr(&err, "read properties", path)
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return Properties{}, fmt.Errorf("make path abs %w", err)
+	}
+	if _, properties, ok, err := readWideWAV(path); ok || err != nil {
+		return audioProperties(properties), err
+	}
+
+	mod, err := newModuleRO(path)
+	if err != nil {
+		return Properties{}, fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	raw := wasmFileProperties{skipImages: true}
 	if err := mod.call("taglib_file_read_properties", &raw, wasmString(wasmPath(path))); err != nil {
 		return Properties{}, fmt.Errorf("call: %w", err)
 	}
+	return Properties{
+		Length:     time.Duration(raw.lengthInMilliseconds) * time.Millisecond,
+		Channels:   uint(raw.channels),
+		SampleRate: uint(raw.sampleRate),
+		Bitrate:    uint(raw.bitrate),
+	}, nil
+}
 
-	var images []ImageDesc
-	for _, row := range raw.imageDescs {
-		parts := strings.SplitN(row, "\t", 3)
-		if len(parts) != 3 {
-			continue
-		}
-		images = append(images, ImageDesc{
-			Type:        parts[0],
-			Description: parts[1],
-			MIMEType:    parts[2],
-		})
+// audioProperties returns the properties read by [ReadPropertiesFast] from p.
+func audioProperties(p Properties) Properties {
+	return Properties{Length: p.Length, Channels: p.Channels, SampleRate: p.SampleRate, Bitrate: p.Bitrate}
+}
+
+// readTagLibProperties reads the properties reported by TagLib, without those parsed from the file in Go.
+func readTagLibProperties(mod *module, path string) (Properties, error) {
+	var raw wasmFileProperties
+	if err := mod.call("taglib_file_read_properties", &raw, wasmString(wasmPath(path))); err != nil {
+		return Properties{}, fmt.Errorf("call: %w", err)
 	}
 
 	return Properties{
@@ -230,46 +573,269 @@ func ReadProperties(path string) (Properties, error) {
 		Channels:   uint(raw.channels),
 		SampleRate: uint(raw.sampleRate),
 		Bitrate:    uint(raw.bitrate),
-		Images:     images,
+		Images:     describeImages(raw.imageDescs),
 	}, nil
 }
 
+// ReadAll reads the tags and audio properties, including the descriptions of embedded images, from an audio file at
+// the given path. It is cheaper than calling [ReadTags] and [ReadProperties] separately, since both are read with one
+// instance of the module, though TagLib parses the file for each.
+func ReadAll(path string) (_ map[string][]string, _ Properties, err error) {
+	defer wrapErr(&err, "read all", path)
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, Properties{}, fmt.Errorf("make path abs %w", err)
+	}
+	if tags, properties, ok, err := readWideWAV(path); ok || err != nil {
+		return tags, properties, err
+	}
+
+	mod, err := newModuleRO(path)
+	if err != nil {
+		return nil, Properties{}, fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	tags, err := readFileTagsModule(&mod, path, nil)
+	if err != nil {
+		return nil, Properties{}, fmt.Errorf("read tags: %w", err)
+	}
+	properties, err := readPropertiesModule(&mod, path)
+	if err != nil {
+		return nil, Properties{}, fmt.Errorf("read properties: %w", err)
+	}
+	return tags, properties, nil
+}
+
 // WriteOption configures the behavior of write operations. The can be passed to [WriteTags] and combined with the bitwise OR operator.
 type WriteOption uint8
 
 const (
 	// Clear indicates that all existing tags not present in the new map should be removed.
 	Clear WriteOption = 1 << iota
+	// SkipID3v2 indicates that WAV files should be saved without an ID3v2 chunk, removing any existing one.
+	SkipID3v2
+	// SkipRIFFInfo indicates that WAV files should be saved without a RIFF INFO chunk, removing any existing one.
+	SkipRIFFInfo
+	// ID3v2Footer indicates that an ID3v2.4 tag at the start of the file should be saved with a footer, which lets
+	// readers find the tag when scanning from the end. The padding is removed, as the spec requires, and an empty
+	// PRIV frame ends the frames so TagLib reads the others back. The file is rewritten.
+	ID3v2Footer
+	// ID3v2Unsynchronisation indicates that unsynchronisation should be applied to an ID3v2.4 tag at the start of the
+	// file, as required by some legacy hardware. Without it tags are saved without unsynchronisation.
+	ID3v2Unsynchronisation
+	// Compact indicates that the padding of an ID3v2 tag at the start of the file and the PADDING blocks of FLAC files
+	// should be removed after saving, rewriting the file. Later writes which grow the tags will need to rewrite the file
+	// again. The padding of MP4 files is kept, since removing it would move the audio data.
+	Compact
 )
 
 // WriteTags writes the metadata key-values pairs to path. The behavior can be controlled with [WriteOption].
-func WriteTags(path string, tags map[string][]string, opts WriteOption) error {
+func WriteTags(path string, tags map[string][]string, opts WriteOption) (err error) {
+	defer wrapErr(&err, "write tags", path)
+	return writeTags(path, tags, nil, newWriteConfig(opts))
+}
+
+// WriteTagsTo writes the metadata key-values pairs to dst, as a copy of the file at src, like [WriteTags]. The file at
+// src is only read, so it can be in a read-only snapshot. The copy is written in the directory of dst and renamed to
+// it, replacing any file at dst, and has the mode of the file at src.
+func WriteTagsTo(src, dst string, tags map[string][]string, opts WriteOption) (err error) {
+	defer wrapErr(&err, "write tags", dst)
+	tmp, mode, err := copyToTemp(src, filepath.Dir(dst))
+	if err != nil {
+		return fmt.Errorf("copy to temp: %w", err)
+	}
+	defer os.Remove(tmp) // no-op after a successful rename
+
+	if err := writeTags(tmp, tags, nil, newWriteConfig(opts)); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		return fmt.Errorf("chmod temp: %w", err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("rename temp: %w", err)
+	}
+	return nil
+}
+
+func writeTags(path string, tags map[string][]string, m Mapping, cfg writeConfig) error {
 	var err error
 	path, err = filepath.Abs(path)
 	if err != nil {
 		return fmt.Errorf("make path abs %w", err)
 	}
 
-	dir := filepath.Dir(path)
-	mod, err := newModule(dir)
+	tags, keyErrs := validTags(readKeyRule(path), tags, cfg)
+	if len(keyErrs) > 0 && len(tags) == 0 && cfg.opts&Clear == 0 {
+		return errors.Join(keyErrs...)
+	}
+	return joinKeyErrors(keyErrs, writeValidTags(path, tags, m, cfg))
+}
+
+// writeValidTags writes tags which have been checked by splitInvalidKeys.
+func writeValidTags(path string, tags map[string][]string, m Mapping, cfg writeConfig) error {
+	var err error
+	if cfg.skipUnchanged {
+		unchanged, err := tagsUnchanged(path, tags, m, cfg)
+		if err != nil {
+			return fmt.Errorf("read tags: %w", err)
+		}
+		if unchanged {
+			return nil
+		}
+	}
+
+	var mtime time.Time
+	if cfg.preserveMtime {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("stat: %w", err)
+		}
+		mtime = info.ModTime()
+	}
+
+	if cfg.preserveUnknown {
+		if err := checkDroppedFrames(path, cfg); err != nil {
+			return err
+		}
+	}
+
+	var before Properties
+	if cfg.verify {
+		before, err = readVerifyProperties(path)
+		if err != nil {
+			return fmt.Errorf("read properties: %w", err)
+		}
+	}
+
+	target := path
+	var mode os.FileMode
+	if cfg.atomicRename {
+		target, mode, err = copyToTemp(path, filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("copy to temp: %w", err)
+		}
+		defer os.Remove(target) // no-op after a successful rename
+	}
+
+	if err := saveTags(target, tags, m, cfg); err != nil {
+		return err
+	}
+
+	if cfg.atomicRename {
+		if err := os.Chmod(target, mode); err != nil {
+			return fmt.Errorf("chmod temp: %w", err)
+		}
+		if err := os.Rename(target, path); err != nil {
+			return fmt.Errorf("rename temp: %w", err)
+		}
+	}
+	if cfg.preserveMtime {
+		if err := os.Chtimes(path, time.Time{}, mtime); err != nil {
+			return fmt.Errorf("restore mtime: %w", err)
+		}
+	}
+	if cfg.verify {
+		if err := verifyWrite(path, tags, m, before); err != nil {
+			return fmt.Errorf("verify: %w", err)
+		}
+	}
+	return nil
+}
+
+func saveTags(path string, tags map[string][]string, m Mapping, cfg writeConfig) error {
+	mod, err := newModule(path)
 	if err != nil {
 		return fmt.Errorf("init module: %w", err)
 	}
 	defer mod.close()
+	return saveTagsModule(&mod, path, tags, m, cfg)
+}
 
+// saveTagsModule saves tags to path with mod, then applies the options which rewrite the file after TagLib.
+func saveTagsModule(mod *module, path string, tags map[string][]string, m Mapping, cfg writeConfig) error {
+	format, natives, tags, err := splitNativeTags(path, tags, m)
+	if err != nil {
+		return fmt.Errorf("split native tags: %w", err)
+	}
+	if cfg.id3v2Version == 3 && format == nativeID3v2 {
+		// the binary saves the ID3v2 tags TagLib edits as ID3v2.4
+		return fmt.Errorf("save id3v2.3: %w", errors.ErrUnsupported)
+	}
+	var comments []ID3v2LanguageText
+	var restoreComments bool
+	if format == nativeID3v2 {
+		if comments, err = readID3v2LanguageFrames(path, "COMM"); err != nil {
+			return fmt.Errorf("read comments: %w", err)
+		}
+		comments, restoreComments = id3v2CommentsAfter(comments, tags, cfg.opts&Clear != 0)
+	}
+	opts, err := writeTagsModule(mod, path, tags, cfg)
+	if err != nil {
+		return err
+	}
+	if restoreComments {
+		if err := restoreID3v2Comments(path, comments, tags); err != nil {
+			return fmt.Errorf("restore comments: %w", err)
+		}
+	}
+	major := byte(4)
+	if cfg.id3v2Version == 3 {
+		major = 3
+	}
+	if err := writeNativeTags(path, format, natives, tags, m, opts&Clear != 0, major); err != nil {
+		return fmt.Errorf("write native tags: %w", err)
+	}
+
+	if opts&(SkipID3v2|SkipRIFFInfo) != 0 {
+		if err := skipWAVTags(path, opts); err != nil {
+			return fmt.Errorf("remove wav tags: %w", err)
+		}
+	}
+	if opts&ID3v2Unsynchronisation != 0 {
+		if err := unsynchroniseID3v2(path); err != nil {
+			return fmt.Errorf("unsynchronise id3v2: %w", err)
+		}
+	}
+	if opts&Compact != 0 && cfg.padding < 0 {
+		cfg.padding = 0
+	}
+	if cfg.padding >= 0 {
+		if err := setPadding(path, cfg.padding); err != nil {
+			return fmt.Errorf("set padding: %w", err)
+		}
+	}
+	if opts&ID3v2Footer != 0 {
+		if err := addID3v2Footer(path); err != nil {
+			return fmt.Errorf("add id3v2 footer: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeTagsModule saves tags to path with TagLib, returning the options it saved with.
+func writeTagsModule(mod *module, path string, tags map[string][]string, cfg writeConfig) (WriteOption, error) {
 	var raw []string
 	for k, vs := range tags {
 		raw = append(raw, fmt.Sprintf("%s\t%s", k, strings.Join(vs, "\v")))
 	}
 
+	opts := cfg.opts
+	switch cfg.id3v2Version {
+	case 0, 3, 4:
+	default:
+		return 0, fmt.Errorf("id3v2 version %d: %w", cfg.id3v2Version, errors.ErrUnsupported)
+	}
+
 	var out wasmBool
 	if err := mod.call("taglib_file_write_tags", &out, wasmString(wasmPath(path)), wasmStrings(raw), wasmUint8(opts)); err != nil {
-		return fmt.Errorf("call: %w", err)
+		return 0, fmt.Errorf("call: %w", err)
 	}
 	if !out {
-		return ErrSavingFile
+		return 0, ErrSavingFile
 	}
-	return nil
+	return opts, nil
 }
 
 // ReadImage reads the first embedded image from path. Returns empty byte slice if no images exist.
@@ -284,19 +850,19 @@ func WriteImage(path string, image []byte) error {
 	if image != nil {
 		mimeType = detectImageMIME(image)
 	}
-	return WriteImageOptions(path, image, 0, "Front Cover", "Added by go-taglib", mimeType)
+	return WriteImageOptions(path, image, 0, PictureFrontCover, "Added by go-taglib", mimeType)
 }
 
 // ReadImageOptions reads the embedded image at the specified index from path.
 // Index 0 is the first image. Returns empty byte slice if index is out of range.
-func ReadImageOptions(path string, index int) ([]byte, error) {
-	var err error
+func ReadImageOptions(path string, index int) (_ []byte, err error) {
+	defer wrapErr(&err, "read image", path)
 	path, err = filepath.Abs(path)
 	if err != nil {
 		return nil, fmt.Errorf("make path abs %w", err)
 	}
 
-	mod, err := newModuleRO(filepath.Dir(path))
+	mod, err := newModuleRO(path)
 	if err != nil {
 		return nil, fmt.Errorf("init module: %w", err)
 	}
@@ -313,21 +879,24 @@ func ReadImageOptions(path string, index int) ([]byte, error) {
 // WriteImageOptions writes an image with custom metadata.
 // Index specifies which image slot to write to (0 = first image).
 // Set image to nil to clear the image at that index.
-func WriteImageOptions(path string, image []byte, index int, imageType, description, mimeType string) error {
-	var err error
+func WriteImageOptions(path string, image []byte, index int, imageType PictureType, description, mimeType string) (err error) {
+	defer wrapErr(&err, "write image", path)
+	if err := checkPictureSize(currentLimits(), int64(len(image))); err != nil {
+		return err
+	}
 	path, err = filepath.Abs(path)
 	if err != nil {
 		return fmt.Errorf("make path abs %w", err)
 	}
 
-	mod, err := newModule(filepath.Dir(path))
+	mod, err := newModule(path)
 	if err != nil {
 		return fmt.Errorf("init module: %w", err)
 	}
 	defer mod.close()
 
 	var out wasmBool
-	if err := mod.call("taglib_file_write_image", &out, wasmString(wasmPath(path)), wasmBytes(image), wasmInt(len(image)), wasmInt(index), wasmString(imageType), wasmString(description), wasmString(mimeType)); err != nil {
+	if err := mod.call("taglib_file_write_image", &out, wasmString(wasmPath(path)), wasmBytes(image), wasmInt(len(image)), wasmInt(index), wasmString(string(imageType)), wasmString(description), wasmString(mimeType)); err != nil {
 		return fmt.Errorf("call: %w", err)
 	}
 	if !out {
@@ -339,22 +908,72 @@ func WriteImageOptions(path string, image []byte, index int, imageType, descript
 type rc struct {
 	wazero.Runtime
 	wazero.CompiledModule
+	// initialized is restored to new modules instead of running _initialize, nil if the binary has none
+	initialized *initSnapshot
 }
 
-var getRuntimeOnce = sync.OnceValues(func() (rc, error) {
-	ctx := context.Background()
+var runtimeState struct {
+	sync.Mutex
+	rc atomic.Pointer[rc]
+}
 
-	cacheDir := filepath.Join(os.TempDir(), "go-taglib-wasm")
-	compilationCache, err := wazero.NewCompilationCacheWithDir(cacheDir)
+// getRuntime returns the runtime shared by all modules, creating it on first use. Unlike a [sync.Once], a failed
+// attempt isn't cached, so a briefly unwritable cache directory doesn't fail every later call.
+func getRuntime() (rc, error) {
+	if r := runtimeState.rc.Load(); r != nil {
+		return *r, nil
+	}
+
+	runtimeState.Lock()
+	defer runtimeState.Unlock()
+	if r := runtimeState.rc.Load(); r != nil {
+		return *r, nil
+	}
+	r, err := newRuntime()
 	if err != nil {
 		return rc{}, err
 	}
+	runtimeState.rc.Store(&r)
+	return r, nil
+}
+
+func newRuntime() (_ rc, err error) {
+	ctx := context.Background()
+
+	var bin = embeddedBinary
+	if binaryPath != "" {
+		bin, err = os.ReadFile(binaryPath)
+		if err != nil {
+			return rc{}, fmt.Errorf("read custom binary path: %w", err)
+		}
+		clear(embeddedBinary)
+	}
+
+	config := wazero.NewRuntimeConfig()
+	if dir := cacheDir(); dir != "" {
+		dir = binaryCacheDir(dir, bin)
+		defer lockCacheDir(dir)()
+		compilationCache, err := wazero.NewCompilationCacheWithDir(dir)
+		if err != nil {
+			return rc{}, err
+		}
+		defer func() {
+			if err != nil {
+				compilationCache.Close(ctx)
+			}
+		}()
+		config = config.WithCompilationCache(compilationCache)
+	}
 
-	runtime := wazero.NewRuntimeWithConfig(ctx,
-		wazero.NewRuntimeConfig().
-			WithCompilationCache(compilationCache),
-	)
-	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	defer func() {
+		if err != nil {
+			runtime.Close(ctx)
+		}
+	}()
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		return rc{}, err
+	}
 
 	_, err = runtime.
 		NewHostModuleBuilder("env").
@@ -365,71 +984,123 @@ var getRuntimeOnce = sync.OnceValues(func() (rc, error) {
 		return rc{}, err
 	}
 
-	var bin = binary
-	if binaryPath != "" {
-		bin, err = os.ReadFile(binaryPath)
-		if err != nil {
-			return rc{}, fmt.Errorf("read custom binary path: %w", err)
-		}
-		clear(binary)
+	compileCtx := ctx
+	if listenerFactory != nil {
+		compileCtx = experimental.WithFunctionListenerFactory(ctx, listenerFactory)
 	}
-
-	compiled, err := runtime.CompileModule(ctx, bin)
+	compiled, err := runtime.CompileModule(compileCtx, bin)
 	if err != nil {
 		return rc{}, err
 	}
 
+	initialized, err := takeInitSnapshot(ctx, runtime, compiled)
+	if err != nil {
+		return rc{}, fmt.Errorf("snapshot initialized module: %w", err)
+	}
+
 	return rc{
 		Runtime:        runtime,
 		CompiledModule: compiled,
+		initialized:    initialized,
 	}, nil
-})
+}
 
 type module struct {
 	mod api.Module
+	// buf is reused to encode string arguments
+	buf []byte
+	// instantiate is the time taken to create the module, reported to the timing hook with the first call
+	instantiate time.Duration
+	// memory backs the linear memory if it is limited by [Limits.MaxMemory]
+	memory *limitedMemory
 }
 
-func newModule(dir string) (module, error)   { return newModuleOpt(dir, false) }
-func newModuleRO(dir string) (module, error) { return newModuleOpt(dir, true) }
-func newModuleOpt(dir string, readOnly bool) (module, error) {
-	rt, err := getRuntimeOnce()
-	if err != nil {
-		return module{}, fmt.Errorf("get runtime once: %w", err)
+// newModule and newModuleRO instantiate a module for the file at path, which can read and write or only read the
+// directory of the file.
+func newModule(path string) (module, error)   { return newModuleOpt(path, false) }
+func newModuleRO(path string) (module, error) { return newModuleOpt(path, true) }
+func newModuleOpt(path string, readOnly bool) (module, error) {
+	start := time.Now()
+	// TagLib can't tell a missing file from an invalid one, so check before paying for the module
+	if _, err := os.Stat(path); err != nil {
+		return module{}, err
 	}
+	limits := currentLimits()
+	if err := checkLimits(path, limits); err != nil {
+		return module{}, err
+	}
+	return newModuleDir(filepath.Dir(path), readOnly, limits, start)
+}
 
+// newModuleDir instantiates a module with dir mounted, for calls on any of the files in it. The files are not checked
+// against limits.
+func newModuleDir(dir string, readOnly bool, limits Limits, start time.Time) (module, error) {
 	fsConfig := wazero.NewFSConfig()
 	if readOnly {
 		fsConfig = fsConfig.WithReadOnlyDirMount(dir, wasmPath(dir))
 	} else {
 		fsConfig = fsConfig.WithDirMount(dir, wasmPath(dir))
 	}
+	return instantiateModule(fsConfig, limits, start)
+}
+
+// instantiateModule instantiates the module with the files of fsConfig, where start is when opening the module began.
+func instantiateModule(fsConfig wazero.FSConfig, limits Limits, start time.Time) (module, error) {
+	rt, err := getRuntime()
+	if err != nil {
+		return module{}, fmt.Errorf("get runtime once: %w", err)
+	}
 
 	cfg := wazero.
 		NewModuleConfig().
 		WithName("").
 		WithStartFunctions("_initialize").
 		WithFSConfig(fsConfig)
+	if rt.initialized != nil {
+		cfg = cfg.WithStartFunctions()
+	}
 
 	ctx := context.Background()
+	var memory *limitedMemory
+	if limits.MaxMemory > 0 {
+		memory = &limitedMemory{max: limits.MaxMemory}
+		ctx = experimental.WithMemoryAllocator(ctx, newMemoryAllocator(memory))
+	}
+
+	acquireInstance()
 	mod, err := rt.InstantiateModule(ctx, rt.CompiledModule, cfg)
 	if err != nil {
+		releaseInstance()
 		return module{}, err
 	}
+	if rt.initialized != nil {
+		if err := rt.initialized.restore(mod); err != nil {
+			mod.Close(ctx)
+			releaseInstance()
+			return module{}, fmt.Errorf("restore initialized memory: %w", err)
+		}
+	}
 
 	return module{
-		mod: mod,
+		mod:         mod,
+		memory:      memory,
+		instantiate: time.Since(start),
 	}, nil
 }
 
 func (m *module) malloc(size uint32) uint32 {
-	var ptr wasmUint32
-	if err := m.call("malloc", &ptr, wasmUint32(size)); err != nil {
+	results, err := m.mod.ExportedFunction("malloc").Call(context.Background(), uint64(size))
+	if err != nil {
 		panic(err)
 	}
+	ptr := uint32(results[0])
+	if ptr == 0 && m.memory != nil && m.memory.exceeded {
+		panic(errMemoryLimit) // recovered by call
+	}
 	if ptr == 0 {
 		panic("no ptr")
 	}
-	return uint32(ptr)
+	return ptr
 }
 
 type wasmArg interface {
@@ -474,7 +1145,8 @@ func (u *wasmUint32) decode(_ *module, val uint64) {
 type wasmString string
 
 func (s wasmString) encode(m *module) uint64 {
-	b := append([]byte(s), 0)
+	m.buf = append(append(m.buf[:0], s...), 0)
+	b := m.buf
 	ptr := m.malloc(uint32(len(b)))
 	if !m.mod.Memory().Write(ptr, b) {
 		panic("failed to write to mod.module.Memory()")
@@ -507,7 +1179,8 @@ type wasmStrings []string
 func (s wasmStrings) encode(m *module) uint64 {
 	arrayPtr := m.malloc(uint32((len(s) + 1) * 4))
 	for i, str := range s {
-		b := append([]byte(str), 0)
+		m.buf = append(append(m.buf[:0], str...), 0)
+		b := m.buf
 		ptr := m.malloc(uint32(len(b)))
 		if !m.mod.Memory().Write(ptr, b) {
 			panic("failed to write to mod.module.Memory()")
@@ -527,7 +1200,27 @@ func (s *wasmStrings) decode(m *module, val uint64) {
 	}
 }
 
+// describeImages parses the imageDescs rows of the file properties, "type\tdescription\tmime".
+func describeImages(rows []string) []ImageDesc {
+	var images []ImageDesc
+	for _, row := range rows {
+		parts := strings.SplitN(row, "\t", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		images = append(images, ImageDesc{
+			Type:        parts[0],
+			Description: parts[1],
+			MIMEType:    parts[2],
+		})
+	}
+	return images
+}
+
 type wasmFileProperties struct {
+	// skipImages is set before the call to leave imageDescs unread
+	skipImages bool
+
 	lengthInMilliseconds uint32
 	channels             uint32
 	sampleRate           uint32
@@ -547,18 +1240,43 @@ func (f *wasmFileProperties) decode(m *module, val uint64) {
 	f.bitrate, _ = m.mod.Memory().ReadUint32Le(ptr + 12)
 
 	imageMetadataPtr, _ := m.mod.Memory().ReadUint32Le(ptr + 16)
-	if imageMetadataPtr != 0 {
+	if imageMetadataPtr != 0 && !f.skipImages {
 		f.imageDescs = readStrings(m, imageMetadataPtr)
 	}
 }
 
-func (m *module) call(name string, dest wasmResult, args ...wasmArg) error {
+// errMemoryLimit is panicked by malloc when the arguments of a call don't fit in the memory limit.
+var errMemoryLimit = &LimitError{Limit: "MaxMemory", Kind: "memory"}
+
+func (m *module) call(name string, dest wasmResult, args ...wasmArg) (err error) {
+	fn := m.mod.ExportedFunction(name)
+	if fn == nil {
+		return fmt.Errorf("%q not exported by binary: %w", name, errors.ErrUnsupported)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errMemoryLimit {
+				panic(r)
+			}
+			err = fmt.Errorf("call %q: %w", name, errMemoryLimit)
+		}
+	}()
+
+	timer := newCallTimer(name, m.instantiate)
+	defer timer.report()
+	m.instantiate = 0
+
 	params := make([]uint64, 0, len(args))
 	for _, a := range args {
 		params = append(params, a.encode(m))
 	}
+	timer.lap(&timer.timing.Encode)
 
-	results, err := m.mod.ExportedFunction(name).Call(context.Background(), params...)
+	results, err := fn.Call(context.Background(), params...)
+	timer.lap(&timer.timing.Call)
+	if m.memory != nil && m.memory.exceeded {
+		return fmt.Errorf("call %q: %w", name, errMemoryLimit)
+	}
 	if err != nil {
 		return fmt.Errorf("call %q: %w", name, err)
 	}
@@ -567,10 +1285,12 @@ func (m *module) call(name string, dest wasmResult, args ...wasmArg) error {
 	}
 
 	dest.decode(m, results[0])
+	timer.lap(&timer.timing.Decode)
 	return nil
 }
 
 func (m *module) close() {
+	defer releaseInstance()
 	if err := m.mod.Close(context.Background()); err != nil {
 		panic(err)
 	}
@@ -668,7 +1388,54 @@ func detectImageMIME(data []byte) string {
 		return "image/jpeg"
 	case len(data) >= 14 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:14], []byte("WEBPVP")):
 		return "image/webp"
+	case len(data) >= 12 && (bytes.Equal(data[4:12], []byte("ftypavif")) || bytes.Equal(data[4:12], []byte("ftypavis"))):
+		return "image/avif"
+	case bytes.HasPrefix(data, []byte("\xFF\x0A")), bytes.HasPrefix(data, []byte("\x00\x00\x00\x0CJXL \x0D\x0A\x87\x0A")):
+		return "image/jxl"
 	default:
 		return ""
 	}
 }
+
+// rewriteRange replaces the bytes of f between start and end with data in place, moving the rest of the file when the
+// size changes and truncating it if it shrinks. The file keeps its inode, so hard links, symlinks, ownership, and
+// extended attributes are kept. A failure part way through can leave the file corrupt, which [WithAtomicRename]
+// guards against.
+func rewriteRange(f *os.File, start, end int64, data []byte) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	size := info.Size()
+	newEnd := start + int64(len(data))
+
+	// the tail is moved from its end when it grows, so no byte is overwritten before it is copied, and from its start
+	// when it shrinks
+	var buf []byte
+	if newEnd != end {
+		buf = make([]byte, min(size-end, 1<<20))
+	}
+	for done := int64(0); done < size-end && newEnd != end; {
+		n := min(int64(len(buf)), size-end-done)
+		offset := done
+		if newEnd > end {
+			offset = size - end - done - n
+		}
+		if _, err := f.ReadAt(buf[:n], end+offset); err != nil {
+			return fmt.Errorf("read tail: %w", err)
+		}
+		if _, err := f.WriteAt(buf[:n], newEnd+offset); err != nil {
+			return fmt.Errorf("write tail: %w", err)
+		}
+		done += n
+	}
+	if _, err := f.WriteAt(data, start); err != nil {
+		return fmt.Errorf("write data: %w", err)
+	}
+	if newEnd < end {
+		if err := f.Truncate(size - (end - newEnd)); err != nil {
+			return fmt.Errorf("truncate: %w", err)
+		}
+	}
+	return nil
+}
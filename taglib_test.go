@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"io/fs"
 	"maps"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -20,6 +22,7 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 
+	"github.com/tetratelabs/wazero/experimental/logging"
 	"go.senan.xyz/taglib"
 )
 
@@ -28,7 +31,75 @@ func TestInvalid(t *testing.T) {
 
 	path := tmpf(t, []byte("not a file"), "eg.flac")
 	_, err := taglib.ReadTags(path)
-	eq(t, err, taglib.ErrInvalidFile)
+	eq(t, errors.Is(err, taglib.ErrInvalidFile), true)
+}
+
+func TestMissing(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "eg.flac")
+	_, err := taglib.ReadTags(path)
+	eq(t, errors.Is(err, fs.ErrNotExist), true)
+	eq(t, errors.Is(err, taglib.ErrInvalidFile), false)
+
+	err = taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, 0)
+	eq(t, errors.Is(err, fs.ErrNotExist), true)
+	_, err = os.Stat(path)
+	eq(t, errors.Is(err, fs.ErrNotExist), true)
+}
+
+func TestWriteTagsTo(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range testPaths(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			nilErr(t, os.Chmod(path, 0o444))
+			before, err := os.ReadFile(path)
+			nilErr(t, err)
+			wantTags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+
+			dst := filepath.Join(t.TempDir(), "out"+filepath.Ext(path))
+			err = taglib.WriteTagsTo(path, dst, map[string][]string{taglib.Title: {"New Title"}}, 0)
+			nilErr(t, err)
+
+			// the source is untouched
+			after, err := os.ReadFile(path)
+			nilErr(t, err)
+			eq(t, bytes.Equal(before, after), true)
+
+			wantTags[taglib.Title] = []string{"New Title"}
+			tags, err := taglib.ReadTags(dst)
+			nilErr(t, err)
+			tagEq(t, tags, wantTags)
+
+			info, err := os.Stat(dst)
+			nilErr(t, err)
+			eq(t, info.Mode().Perm(), os.FileMode(0o444))
+
+			// no temporary files left behind
+			entries, err := os.ReadDir(filepath.Dir(dst))
+			nilErr(t, err)
+			eq(t, len(entries), 1)
+		})
+	}
+}
+
+func TestErrorContext(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, []byte("not a file"), "eg.flac")
+	_, err := taglib.ReadTags(path)
+
+	var tagErr *taglib.Error
+	eq(t, errors.As(err, &tagErr), true)
+	eq(t, tagErr.Op, "read tags")
+	eq(t, tagErr.Path, path)
+	eq(t, errors.Is(err, taglib.ErrInvalidFile), true)
+
+	err = taglib.WriteImage(path, coverJPG)
+	eq(t, errors.As(err, &tagErr), true)
+	eq(t, tagErr.Op, "write image")
 }
 
 func TestClear(t *testing.T) {
@@ -113,6 +184,32 @@ func TestReadWrite(t *testing.T) {
 	}
 }
 
+func TestReadTagKeys(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range testPaths(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := taglib.WriteTags(path, map[string][]string{
+				taglib.Artist: {"Artist 1", "Artist 2"},
+				taglib.Album:  {"Album"},
+				taglib.Lyrics: {strings.Repeat("la ", 1000)},
+			}, taglib.Clear)
+			nilErr(t, err)
+
+			tags, err := taglib.ReadTagKeys(path, taglib.Artist, "album", taglib.Title)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{
+				taglib.Artist: {"Artist 1", "Artist 2"},
+				taglib.Album:  {"Album"},
+			})
+
+			tags, err = taglib.ReadTagKeys(path)
+			nilErr(t, err)
+			eq(t, len(tags), 0)
+		})
+	}
+}
+
 func TestMergeWrite(t *testing.T) {
 	t.Parallel()
 
@@ -188,6 +285,218 @@ func TestMergeWrite(t *testing.T) {
 	}
 }
 
+func TestOriginalDate(t *testing.T) {
+	t.Parallel()
+
+	// TDOR and TDRC in ID3v2, ORIGINALDATE and DATE in Vorbis comments and APE
+	for _, path := range append(testPaths(t), nichePaths(t)...) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := taglib.WriteTags(path, map[string][]string{
+				taglib.Date:         {"2011-03-04"},
+				taglib.OriginalDate: {"1979-05-06"},
+			}, taglib.Clear)
+			nilErr(t, err)
+
+			// a reissue date doesn't touch the original
+			err = taglib.WriteTags(path, map[string][]string{
+				taglib.Date: {"2012"},
+			}, 0)
+			nilErr(t, err)
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, tags, map[string][]string{
+				taglib.Date:         {"2012"},
+				taglib.OriginalDate: {"1979-05-06"},
+			})
+		})
+	}
+}
+
+func TestOriginalDateID3v23(t *testing.T) {
+	t.Parallel()
+
+	// TORY holds the original year in ID3v2.3, and TYER with TDAT the date
+	frame := func(id, text string) []byte {
+		data := append([]byte{0}, text...)
+		return append(append([]byte(id), 0, 0, byte(len(data)>>8), byte(len(data)), 0, 0), data...)
+	}
+	var frames []byte
+	frames = append(frames, frame("TYER", "2011")...)
+	frames = append(frames, frame("TDAT", "0403")...)
+	frames = append(frames, frame("TORY", "1979")...)
+	tag := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(frames))}, frames...)
+
+	// replacing the ID3v2.4 tag of the example
+	audio := egMP3[10+(int(egMP3[6])<<21|int(egMP3[7])<<14|int(egMP3[8])<<7|int(egMP3[9])):]
+	path := tmpf(t, append(tag, audio...), "eg.mp3")
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, strings.Join(tags[taglib.Date], ""), "2011-03-04")
+	eq(t, strings.Join(tags[taglib.OriginalDate], ""), "1979")
+
+	err = taglib.WriteTags(path, map[string][]string{taglib.Date: {"2012"}}, 0)
+	nilErr(t, err)
+
+	tags, err = taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, strings.Join(tags[taglib.Date], ""), "2012")
+	eq(t, strings.Join(tags[taglib.OriginalDate], ""), "1979")
+}
+
+func TestReplayGain(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range testPaths(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			want := map[string][]string{
+				taglib.ReplayGainTrackGain: {"-6.48 dB"},
+				taglib.ReplayGainTrackPeak: {"0.988556"},
+				taglib.ReplayGainAlbumGain: {"-7.03 dB"},
+				taglib.ReplayGainAlbumPeak: {"1.000000"},
+			}
+			nilErr(t, taglib.WriteTags(path, want, taglib.Clear))
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, tags, want)
+		})
+	}
+}
+
+func TestNicheFormats(t *testing.T) {
+	t.Parallel()
+
+	tags := map[string][]string{
+		"ARTIST": {"Example A", "Example B"},
+		"TITLE":  {"Hello, 世界"},
+	}
+
+	for _, path := range nichePaths(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			err := taglib.WriteTags(path, tags, taglib.Clear)
+			nilErr(t, err)
+
+			got, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			tagEq(t, got, tags)
+
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			eq(t, properties.Length > 0, true)
+		})
+	}
+}
+
+func TestVorbisProperties(t *testing.T) {
+	t.Parallel()
+
+	properties, err := taglib.ReadProperties(tmpf(t, egOgg, "eg.ogg"))
+	nilErr(t, err)
+	if properties.Vorbis == nil {
+		t.Fatalf("no vorbis properties")
+	}
+	eq(t, *properties.Vorbis, taglib.VorbisProperties{BitrateNominal: 112_000})
+	eq(t, properties.NominalBitrate, 112)
+}
+
+func TestOpusProperties(t *testing.T) {
+	t.Parallel()
+
+	properties, err := taglib.ReadProperties(tmpf(t, egOpus, "eg.opus"))
+	nilErr(t, err)
+	if properties.Opus == nil {
+		t.Fatalf("no opus properties")
+	}
+	eq(t, *properties.Opus, taglib.OpusProperties{Version: 1, PreSkip: 312, InputSampleRate: 44_100})
+}
+
+func TestMPCProperties(t *testing.T) {
+	t.Parallel()
+
+	properties, err := taglib.ReadProperties(tmpf(t, egMPC, "eg.mpc"))
+	nilErr(t, err)
+	if properties.MPC == nil {
+		t.Fatalf("no musepack properties")
+	}
+	eq(t, properties.MPC.StreamVersion, 7)
+}
+
+func TestWavPackProperties(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egWV, "eg.wv")
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	if properties.WavPack == nil {
+		t.Fatalf("no wavpack properties")
+	}
+	eq(t, *properties.WavPack, taglib.WavPackProperties{Version: 0x410, Lossless: true})
+	eq(t, properties.BitsPerSample, 16)
+	eq(t, properties.SampleFrames, 4410)
+
+	nilErr(t, os.WriteFile(strings.TrimSuffix(path, ".wv")+".wvc", nil, 0o644))
+	properties, err = taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.WavPack.CorrectionFile, true)
+}
+
+func TestShorten(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egSHN, "eg.shn")
+
+	properties, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	eq(t, properties.Length, 1*time.Second)
+	eq(t, properties.Channels, 2)
+	eq(t, properties.SampleRate, 44100)
+
+	// shorten has no tag format
+	err = taglib.WriteTags(path, map[string][]string{"TITLE": {"title"}}, 0)
+	eq(t, errors.Is(err, taglib.ErrSavingFile), true)
+}
+
+func TestWriteKeyErrors(t *testing.T) {
+	t.Parallel()
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	err := taglib.WriteTags(path, map[string][]string{
+		"TITLE":  {"title"},
+		"A=B":    {"x"},
+		"ARTIST": {"a\vb"},
+		"ALBUM":  {"album"},
+		"":       {"empty"},
+	}, 0)
+	eq(t, errors.Is(err, taglib.ErrSavingFile), true)
+
+	var keyErr *taglib.KeyError
+	eq(t, errors.As(err, &keyErr), true)
+	eq(t, keyErr.Key, "")
+
+	var keys []string
+	for _, err := range errors.Unwrap(err).(interface{ Unwrap() []error }).Unwrap() {
+		if errors.As(err, &keyErr) {
+			keys = append(keys, keyErr.Key)
+		}
+	}
+	eq(t, strings.Join(keys, ","), ",A=B,ARTIST")
+
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, len(tags[taglib.Title]), 1)
+	eq(t, tags[taglib.Title][0], "title")
+	eq(t, len(tags[taglib.Album]), 1)
+	eq(t, len(tags["A=B"]), 0)
+
+	// only invalid keys leave the file as is
+	err = taglib.WriteTags(path, map[string][]string{"A=B": {"x"}}, 0)
+	eq(t, errors.Is(err, taglib.ErrSavingFile), true)
+	tags, err = taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, len(tags[taglib.Title]), 1)
+}
+
 func TestReadExistingUnicode(t *testing.T) {
 	tags, err := taglib.ReadTags("testdata/normal.flac")
 	nilErr(t, err)
@@ -219,6 +528,109 @@ func TestConcurrent(t *testing.T) {
 	nilErr(t, err)
 }
 
+func TestSetCompileOptionsAfterUse(t *testing.T) {
+	t.Parallel()
+
+	_, err := taglib.ReadTags(tmpf(t, egFLAC, "eg.flac"))
+	nilErr(t, err)
+
+	// the module is compiled by the first call, so the cache can no longer change
+	err = taglib.SetCacheDir(t.TempDir())
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// listeners are compiled in too
+	err = taglib.SetFunctionListenerFactory(logging.NewLoggingListenerFactory(&bytes.Buffer{}))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestMaxInstances(t *testing.T) {
+	// not parallel, the limit is global
+	taglib.SetMaxInstances(2)
+	t.Cleanup(func() { taglib.SetMaxInstances(0) })
+
+	paths := testPaths(t)
+
+	var peak atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if n := int64(taglib.Instances()); n > peak.Load() {
+					peak.Store(n)
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := taglib.ReadTags(paths[i%len(paths)]); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+
+	eq(t, peak.Load() <= 2, true)
+	eq(t, taglib.Instances(), 0)
+}
+
+func TestTimingHook(t *testing.T) {
+	// not parallel, the hook is global
+	var mu sync.Mutex
+	var timings []taglib.CallTiming
+	taglib.SetTimingHook(func(ct taglib.CallTiming) {
+		mu.Lock()
+		timings = append(timings, ct)
+		mu.Unlock()
+	})
+	t.Cleanup(func() { taglib.SetTimingHook(nil) })
+
+	path := tmpf(t, egFLAC, "eg.flac")
+	_, err := taglib.ReadTags(path)
+	nilErr(t, err)
+
+	taglib.SetTimingHook(nil)
+	_, err = taglib.ReadTags(path)
+	nilErr(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	eq(t, len(timings), 1)
+	eq(t, timings[0].Function, "taglib_file_tags")
+	eq(t, timings[0].Instantiate > 0, true)
+	eq(t, timings[0].Call > 0, true)
+}
+
+func TestReadPropertiesFast(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range testPaths(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			fast, err := taglib.ReadPropertiesFast(path)
+			nilErr(t, err)
+			eq(t, fast.Length, properties.Length)
+			eq(t, fast.Channels, properties.Channels)
+			eq(t, fast.SampleRate, properties.SampleRate)
+			eq(t, fast.Bitrate, properties.Bitrate)
+			eq(t, len(fast.Images), 0)
+		})
+	}
+}
+
 func TestProperties(t *testing.T) {
 	t.Parallel()
 
@@ -229,16 +641,132 @@ func TestProperties(t *testing.T) {
 
 	eq(t, 1*time.Second, properties.Length)
 	eq(t, 1460, properties.Bitrate)
+	eq(t, 1460, int(properties.AverageBitrate))
+	eq(t, 0, properties.NominalBitrate)
 	eq(t, 48_000, properties.SampleRate)
 	eq(t, 2, properties.Channels)
 
 	eq(t, len(properties.Images), 2)
 	eq(t, properties.Images[0].Type, "Front Cover")
+	eq(t, properties.Images[0].PictureType(), taglib.PictureFrontCover)
 	eq(t, properties.Images[0].Description, "The first image")
 	eq(t, properties.Images[0].MIMEType, "image/png")
+	eq(t, properties.Images[0].Width, 0) // measured by ReadImages
+	eq(t, properties.Images[0].SHA1, "")
 	eq(t, properties.Images[1].Type, "Lead Artist")
+	eq(t, properties.Images[1].PictureType(), taglib.PictureLeadArtist)
 	eq(t, properties.Images[1].Description, "The second image")
 	eq(t, properties.Images[1].MIMEType, "image/jpeg")
+
+	info, err := os.Stat(path)
+	nilErr(t, err)
+	eq(t, properties.Size, int64(len(egFLAC)))
+	eq(t, properties.ModTime.Equal(info.ModTime()), true)
+
+	_, length, err := taglib.ReadAudioRange(path)
+	nilErr(t, err)
+	eq(t, properties.MetadataSize, properties.Size-length)
+
+	// replacing the images changes the metadata around the same audio
+	nilErr(t, taglib.WriteImage(path, coverJPG))
+	withCover, err := taglib.ReadProperties(path)
+	nilErr(t, err)
+	_, coverLength, err := taglib.ReadAudioRange(path)
+	nilErr(t, err)
+	eq(t, coverLength, length)
+	eq(t, withCover.MetadataSize, withCover.Size-length)
+}
+
+func TestSampleFrames(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		data []byte
+		name string
+		want uint64
+	}{
+		{egM4a, "eg.m4a", 1068},
+		{egAIFF, "eg.aiff", 4410},
+		{egFLAC, "eg.flac", 48_000},
+		{egOggFLAC, "eg.oga", 44_100},
+		{egWAV, "eg.wav", 220_568},
+		{egDSF, "eg.dsf", 32_768},
+		{egDFF, "eg.dff", 32_768},
+		{egOpus, "eg.opus", 48_000},
+		{egSpeex, "eg.spx", 44_100},
+	} {
+		properties, err := taglib.ReadProperties(tmpf(t, tc.data, tc.name))
+		nilErr(t, err)
+		eq(t, properties.SampleFrames, tc.want)
+	}
+}
+
+func TestBitsPerSample(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		data []byte
+		name string
+		want uint
+	}{
+		{egFLAC, "eg.flac", 24},
+		{egOggFLAC, "eg.oga", 16},
+		{egWAV, "eg.wav", 16},
+		{egAIFF, "eg.aiff", 16},
+		{egDSF, "eg.dsf", 1},
+		{egDFF, "eg.dff", 1},
+	} {
+		properties, err := taglib.ReadProperties(tmpf(t, tc.data, tc.name))
+		nilErr(t, err)
+		eq(t, properties.BitsPerSample, tc.want)
+	}
+}
+
+func TestFormatPropertiesOnlyForTheirFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range append(testPaths(t), nichePaths(t)...) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+
+			ext := filepath.Ext(path)
+			eq(t, properties.WavPack != nil && ext != ".wv", false)
+			eq(t, properties.MPC != nil && ext != ".mpc", false)
+			eq(t, properties.Vorbis != nil && ext != ".ogg", false)
+			eq(t, properties.Opus != nil && ext != ".opus", false)
+			eq(t, properties.BitsPerSample <= 32, true)
+		})
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range append(testPaths(t), nichePaths(t)...) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			wantTags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			wantProperties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+
+			tags, properties, err := taglib.ReadAll(path)
+			nilErr(t, err)
+
+			tagEq(t, tags, wantTags)
+			eq(t, properties.Length, wantProperties.Length)
+			eq(t, properties.Bitrate, wantProperties.Bitrate)
+			eq(t, properties.SampleRate, wantProperties.SampleRate)
+			eq(t, properties.Channels, wantProperties.Channels)
+			eq(t, properties.EncoderInfo, wantProperties.EncoderInfo)
+			eq(t, properties.Size, wantProperties.Size)
+			eq(t, properties.ModTime.Equal(wantProperties.ModTime), true)
+			eq(t, len(properties.Images), len(wantProperties.Images))
+			for i := range properties.Images {
+				eq(t, properties.Images[i], wantProperties.Images[i])
+			}
+		})
+	}
 }
 
 func TestMultiOpen(t *testing.T) {
@@ -299,6 +827,49 @@ func TestWriteImage(t *testing.T) {
 	}
 }
 
+func TestWriteImageMIME(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		mime string
+	}{
+		{"avif", []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf"), "image/avif"},
+		{"avis", []byte("\x00\x00\x00\x1cftypavis\x00\x00\x00\x00avismsf1miaf"), "image/avif"},
+		{"jxl codestream", []byte("\xff\x0a\xfa\x1f\x42\x09\x00\x00"), "image/jxl"},
+		{"jxl container", []byte("\x00\x00\x00\x0cJXL \x0d\x0a\x87\x0a\x00\x00\x00\x14ftypjxl "), "image/jxl"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := tmpf(t, egFLAC, "eg.flac")
+			nilErr(t, taglib.WriteImage(path, tc.data))
+
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			eq(t, properties.Images[0].MIMEType, tc.mime)
+
+			img, err := taglib.ReadImage(path)
+			nilErr(t, err)
+			eq(t, bytes.Equal(img, tc.data), true)
+		})
+	}
+}
+
+func TestWriteImagePictureType(t *testing.T) {
+	for _, typ := range []taglib.PictureType{taglib.PictureBackCover, taglib.PictureColouredFish, "Not A Type"} {
+		t.Run(string(typ), func(t *testing.T) {
+			path := tmpf(t, egFLAC, "eg.flac")
+			nilErr(t, taglib.WriteImageOptions(path, coverJPG, 0, typ, "", "image/jpeg"))
+
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			want := typ
+			if want == "Not A Type" {
+				want = taglib.PictureOther // FLAC stores the type as a number
+			}
+			eq(t, properties.Images[0].PictureType(), want)
+		})
+	}
+}
+
 func TestClearImage(t *testing.T) {
 	path := tmpf(t, egFLAC, "eg.flac")
 
@@ -425,8 +996,30 @@ var (
 	egM4a []byte
 	//go:embed testdata/eg.ogg
 	egOgg []byte
+	//go:embed testdata/eg.oga
+	egOggFLAC []byte
+	//go:embed testdata/eg.spx
+	egSpeex []byte
+	//go:embed testdata/eg.opus
+	egOpus []byte
 	//go:embed testdata/eg.wav
 	egWAV []byte
+	//go:embed testdata/eg.aiff
+	egAIFF []byte
+	//go:embed testdata/eg.dsf
+	egDSF []byte
+	//go:embed testdata/eg.dff
+	egDFF []byte
+	//go:embed testdata/eg.mpc
+	egMPC []byte
+	//go:embed testdata/eg.wv
+	egWV []byte
+	//go:embed testdata/eg.tta
+	egTTA []byte
+	//go:embed testdata/eg.ape
+	egAPE []byte
+	//go:embed testdata/eg.shn
+	egSHN []byte
 	//go:embed testdata/cover.jpg
 	coverJPG []byte
 )
@@ -438,6 +1031,22 @@ func testPaths(t testing.TB) []string {
 		tmpf(t, egM4a, "eg.m4a"),
 		tmpf(t, egWAV, "eg.wav"),
 		tmpf(t, egOgg, "eg.ogg"),
+		tmpf(t, egOggFLAC, "eg.oga"),
+		tmpf(t, egSpeex, "eg.spx"),
+		tmpf(t, egOpus, "eg.opus"),
+		tmpf(t, egAIFF, "eg.aiff"),
+		tmpf(t, egDSF, "eg.dsf"),
+		tmpf(t, egDFF, "eg.dff"),
+	}
+}
+
+// nichePaths are formats with tag mappings that differ from the common set in [testPaths], such as APEv2
+func nichePaths(t testing.TB) []string {
+	return []string{
+		tmpf(t, egMPC, "eg.mpc"),
+		tmpf(t, egWV, "eg.wv"),
+		tmpf(t, egTTA, "eg.tta"),
+		tmpf(t, egAPE, "eg.ape"),
 	}
 }
 
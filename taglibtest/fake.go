@@ -0,0 +1,132 @@
+// Package taglibtest helps test applications which use package [go.senan.xyz/taglib], without audio files or the
+// Wasm runtime.
+package taglibtest
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.senan.xyz/taglib"
+)
+
+// File is a file held by a [Fake].
+type File struct {
+	Tags       map[string][]string
+	Properties taglib.Properties
+	// Image is the first embedded image, nil if there is none
+	Image []byte
+}
+
+// Fake is a [taglib.Tagger] which keeps files in memory, keyed by path. Like the files on disk, keys are upper cased
+// when written, values are replaced key by key unless [taglib.Clear] is passed, and keys written with no values are
+// removed. Paths which weren't added fail with an error matching [fs.ErrNotExist]. Its methods may be called from many
+// goroutines at once. The zero Fake is ready to use.
+type Fake struct {
+	mu    sync.Mutex
+	files map[string]*File
+}
+
+var _ taglib.Tagger = (*Fake)(nil)
+
+// Add adds a file at path, replacing any file already there.
+func (f *Fake) Add(path string, file File) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.files == nil {
+		f.files = map[string]*File{}
+	}
+	file.Tags = cloneTags(file.Tags)
+	file.Image = slices.Clone(file.Image)
+	f.files[path] = &file
+}
+
+// File returns a copy of the file at path, and false if there is none.
+func (f *Fake) File(path string) (File, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.files[path]
+	if !ok {
+		return File{}, false
+	}
+	return File{Tags: cloneTags(file.Tags), Properties: file.Properties, Image: slices.Clone(file.Image)}, true
+}
+
+func (f *Fake) ReadTags(path string) (map[string][]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.file("read tags", path)
+	if err != nil {
+		return nil, err
+	}
+	return cloneTags(file.Tags), nil
+}
+
+func (f *Fake) WriteTags(path string, tags map[string][]string, opts taglib.WriteOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.file("write tags", path)
+	if err != nil {
+		return err
+	}
+	if opts&taglib.Clear != 0 {
+		file.Tags = map[string][]string{}
+	}
+	for k, vs := range tags {
+		k = strings.ToUpper(k)
+		if len(vs) == 0 {
+			delete(file.Tags, k)
+			continue
+		}
+		file.Tags[k] = slices.Clone(vs)
+	}
+	return nil
+}
+
+func (f *Fake) ReadProperties(path string) (taglib.Properties, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.file("read properties", path)
+	if err != nil {
+		return taglib.Properties{}, err
+	}
+	return file.Properties, nil
+}
+
+func (f *Fake) ReadImage(path string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.file("read image", path)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(file.Image), nil
+}
+
+func (f *Fake) WriteImage(path string, image []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.file("write image", path)
+	if err != nil {
+		return err
+	}
+	file.Image = slices.Clone(image)
+	return nil
+}
+
+func (f *Fake) file(op, path string) (*File, error) {
+	file, ok := f.files[path]
+	if !ok {
+		return nil, &taglib.Error{Op: op, Path: path, Err: fs.ErrNotExist}
+	}
+	return file, nil
+}
+
+func cloneTags(tags map[string][]string) map[string][]string {
+	clone := make(map[string][]string, len(tags))
+	for k, vs := range tags {
+		clone[k] = slices.Clone(vs)
+	}
+	return clone
+}
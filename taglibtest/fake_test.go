@@ -0,0 +1,74 @@
+package taglibtest_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"go.senan.xyz/taglib"
+	"go.senan.xyz/taglib/taglibtest"
+)
+
+// retitle is application code under test, which only needs a [taglib.Tagger].
+func retitle(t taglib.Tagger, path string) error {
+	tags, err := t.ReadTags(path)
+	if err != nil {
+		return err
+	}
+	return t.WriteTags(path, map[string][]string{"title": {tags[taglib.Title][0] + " (Remastered)"}}, 0)
+}
+
+func TestFake(t *testing.T) {
+	t.Parallel()
+
+	var fake taglibtest.Fake
+	fake.Add("/music/a.flac", taglibtest.File{
+		Tags:       map[string][]string{taglib.Title: {"Title"}, taglib.Artist: {"Artist"}},
+		Properties: taglib.Properties{SampleRate: 44100},
+	})
+
+	if err := retitle(&fake, "/music/a.flac"); err != nil {
+		t.Fatal(err)
+	}
+	tags, err := fake.ReadTags("/music/a.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tags[taglib.Title]; len(got) != 1 || got[0] != "Title (Remastered)" {
+		t.Fatalf("title %q", got)
+	}
+	if got := tags[taglib.Artist]; len(got) != 1 {
+		t.Fatalf("artist %q", got)
+	}
+
+	// keys written without values are removed, and Clear removes the rest
+	if err := fake.WriteTags("/music/a.flac", map[string][]string{taglib.Artist: nil}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.WriteTags("/music/a.flac", map[string][]string{taglib.Album: {"Album"}}, taglib.Clear); err != nil {
+		t.Fatal(err)
+	}
+	file, ok := fake.File("/music/a.flac")
+	if !ok || len(file.Tags) != 1 || file.Tags[taglib.Album][0] != "Album" {
+		t.Fatalf("tags %q", file.Tags)
+	}
+
+	properties, err := fake.ReadProperties("/music/a.flac")
+	if err != nil || properties.SampleRate != 44100 {
+		t.Fatalf("properties %v %v", properties, err)
+	}
+
+	if err := fake.WriteImage("/music/a.flac", []byte("image")); err != nil {
+		t.Fatal(err)
+	}
+	image, err := fake.ReadImage("/music/a.flac")
+	if err != nil || string(image) != "image" {
+		t.Fatalf("image %q %v", image, err)
+	}
+
+	_, err = fake.ReadTags("/music/missing.flac")
+	var tagErr *taglib.Error
+	if !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &tagErr) || tagErr.Op != "read tags" {
+		t.Fatalf("err %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package taglibtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+// WriteFile writes a minimal valid audio file with the given tags to path, in the format of its extension: ".flac",
+// ".mp3", ".m4a", ".ogg" for Ogg Vorbis, ".opus", or ".wav". The files are stereo at 44.1 kHz, or 48 kHz for Opus, and
+// hold little or no audio, so they are only a few kilobytes. Other extensions fail with [errors.ErrUnsupported].
+func WriteFile(path string, tags map[string][]string) error {
+	var data []byte
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".flac":
+		data = flacFile()
+	case ".mp3":
+		data = mp3File()
+	case ".m4a":
+		data = m4aFile()
+	case ".ogg":
+		data = vorbisFile()
+	case ".opus":
+		data = opusFile()
+	case ".wav":
+		data = wavFile()
+	default:
+		return fmt.Errorf("write %q file: %w", ext, errors.ErrUnsupported)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return taglib.WriteTags(path, tags, 0)
+}
+
+// TempFile writes a file with [WriteFile] named name in a temporary directory removed after the test, and returns its
+// path. It fails the test on error.
+func TempFile(tb testing.TB, name string, tags map[string][]string) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), name)
+	if err := WriteFile(path, tags); err != nil {
+		tb.Fatalf("write test file: %v", err)
+	}
+	return path
+}
+
+const (
+	sampleRate = 44100
+	channels   = 2
+)
+
+// flacFile is a FLAC file with a STREAMINFO block of no samples, and no audio frames.
+func flacFile() []byte {
+	streamInfo := make([]byte, 34)
+	binary.BigEndian.PutUint16(streamInfo[0:], 4096) // min block size
+	binary.BigEndian.PutUint16(streamInfo[2:], 4096) // max block size
+	// 20 bits of sample rate, 3 of channels - 1, 5 of bits per sample - 1, and 36 of samples
+	binary.BigEndian.PutUint64(streamInfo[10:], uint64(sampleRate)<<44|uint64(channels-1)<<41|uint64(16-1)<<36)
+
+	b := []byte("fLaC")
+	b = append(b, 0x80, 0, 0, byte(len(streamInfo))) // last block, STREAMINFO
+	return append(b, streamInfo...)
+}
+
+// mp3File is an MPEG-1 Layer III file of silent 128 kbit/s frames.
+func mp3File() []byte {
+	frame := make([]byte, 144*128000/sampleRate)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	return bytes.Repeat(frame, 20)
+}
+
+// wavFile is a WAV file of a tenth of a second of silent 16 bit PCM.
+func wavFile() []byte {
+	format := make([]byte, 16)
+	binary.LittleEndian.PutUint16(format[0:], 1) // PCM
+	binary.LittleEndian.PutUint16(format[2:], channels)
+	binary.LittleEndian.PutUint32(format[4:], sampleRate)
+	binary.LittleEndian.PutUint32(format[8:], sampleRate*channels*2)
+	binary.LittleEndian.PutUint16(format[12:], channels*2)
+	binary.LittleEndian.PutUint16(format[14:], 16)
+
+	body := []byte("WAVE")
+	body = append(body, riffChunk("fmt ", format)...)
+	body = append(body, riffChunk("data", make([]byte, sampleRate/10*channels*2))...)
+	return riffChunk("RIFF", body)
+}
+
+func riffChunk(id string, data []byte) []byte {
+	b := binary.LittleEndian.AppendUint32([]byte(id), uint32(len(data)))
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// m4aFile is an MP4 file with an AAC sound track of no samples.
+func m4aFile() []byte {
+	matrix := make([]byte, 36)
+	binary.BigEndian.PutUint32(matrix[0:], 0x00010000)
+	binary.BigEndian.PutUint32(matrix[16:], 0x00010000)
+	binary.BigEndian.PutUint32(matrix[32:], 0x40000000)
+
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)       // timescale
+	binary.BigEndian.PutUint32(mvhd[20:], 0x00010000) // rate
+	binary.BigEndian.PutUint16(mvhd[24:], 0x0100)     // volume
+	copy(mvhd[36:], matrix)
+	binary.BigEndian.PutUint32(mvhd[96:], 2) // next track ID
+
+	tkhd := make([]byte, 84)
+	tkhd[3] = 7                              // enabled, in movie, in preview
+	binary.BigEndian.PutUint32(tkhd[12:], 1) // track ID
+	binary.BigEndian.PutUint16(tkhd[36:], 0x0100)
+	copy(tkhd[40:], matrix)
+
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:], sampleRate) // timescale
+	binary.BigEndian.PutUint16(mdhd[20:], 0x55c4)     // "und"
+
+	hdlr := append(make([]byte, 8), "soun"+strings.Repeat("\x00", 13)...)
+
+	mp4a := make([]byte, 28)
+	binary.BigEndian.PutUint16(mp4a[6:], 1) // data reference index
+	binary.BigEndian.PutUint16(mp4a[16:], channels)
+	binary.BigEndian.PutUint16(mp4a[18:], 16)
+	binary.BigEndian.PutUint32(mp4a[24:], sampleRate<<16)
+	stsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, mp4Atom("mp4a", mp4a)...)
+
+	empty := make([]byte, 8) // version, flags, and no entries
+	stbl := mp4Atom("stbl", bytes.Join([][]byte{
+		mp4Atom("stsd", stsd),
+		mp4Atom("stts", empty),
+		mp4Atom("stsc", empty),
+		mp4Atom("stsz", make([]byte, 12)),
+		mp4Atom("stco", empty),
+	}, nil))
+	dref := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, mp4Atom("url ", []byte{0, 0, 0, 1})...)
+	minf := mp4Atom("minf", bytes.Join([][]byte{
+		mp4Atom("smhd", make([]byte, 8)),
+		mp4Atom("dinf", mp4Atom("dref", dref)),
+		stbl,
+	}, nil))
+	trak := mp4Atom("trak", bytes.Join([][]byte{
+		mp4Atom("tkhd", tkhd),
+		mp4Atom("mdia", bytes.Join([][]byte{mp4Atom("mdhd", mdhd), mp4Atom("hdlr", hdlr), minf}, nil)),
+	}, nil))
+
+	return bytes.Join([][]byte{
+		mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00M4A mp42isom")),
+		mp4Atom("moov", append(mp4Atom("mvhd", mvhd), trak...)),
+		mp4Atom("mdat", nil),
+	}, nil)
+}
+
+func mp4Atom(name string, data []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	b = append(b, name...)
+	return append(b, data...)
+}
+
+// vorbisFile is an Ogg Vorbis file of the three header packets, and no audio packets.
+func vorbisFile() []byte {
+	id := []byte("\x01vorbis")
+	id = binary.LittleEndian.AppendUint32(id, 0) // version
+	id = append(id, channels)
+	id = binary.LittleEndian.AppendUint32(id, sampleRate)
+	id = binary.LittleEndian.AppendUint32(id, 0)      // maximum bitrate
+	id = binary.LittleEndian.AppendUint32(id, 128000) // nominal bitrate
+	id = binary.LittleEndian.AppendUint32(id, 0)      // minimum bitrate
+	id = append(id, 0xb8, 1)                          // block sizes, framing
+
+	comments := append([]byte("\x03vorbis"), vorbisComments()...)
+	comments = append(comments, 1) // framing
+	setup := []byte("\x05vorbis\x00")
+
+	return append(oggPage(0x02, 0, [][]byte{id}), oggPage(0, 1, [][]byte{comments, setup})...)
+}
+
+// opusFile is an Ogg Opus file of the two header packets, and no audio packets.
+func opusFile() []byte {
+	head := []byte("OpusHead\x01")
+	head = append(head, channels)
+	head = binary.LittleEndian.AppendUint16(head, 312) // pre-skip
+	head = binary.LittleEndian.AppendUint32(head, 48000)
+	head = append(head, 0, 0, 0) // gain, mapping family
+
+	tags := append([]byte("OpusTags"), vorbisComments()...)
+	return append(oggPage(0x02, 0, [][]byte{head}), oggPage(0, 1, [][]byte{tags})...)
+}
+
+// vorbisComments is an empty comment block, which the tags are written to.
+func vorbisComments() []byte {
+	const vendor = "go-taglib"
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(vendor)))
+	b = append(b, vendor...)
+	return binary.LittleEndian.AppendUint32(b, 0)
+}
+
+// oggPage is a page of a single logical stream, holding whole packets.
+func oggPage(headerType byte, sequence uint32, packets [][]byte) []byte {
+	var lacing, body []byte
+	for _, p := range packets {
+		for n := len(p); ; n -= 255 {
+			if n < 255 {
+				lacing = append(lacing, byte(n))
+				break
+			}
+			lacing = append(lacing, 255)
+		}
+		body = append(body, p...)
+	}
+
+	page := []byte("OggS\x00")
+	page = append(page, headerType)
+	page = binary.LittleEndian.AppendUint64(page, 0) // granule position
+	page = binary.LittleEndian.AppendUint32(page, 1) // serial
+	page = binary.LittleEndian.AppendUint32(page, sequence)
+	page = binary.LittleEndian.AppendUint32(page, 0) // CRC, set below
+	page = append(page, byte(len(lacing)))
+	page = append(page, lacing...)
+	page = append(page, body...)
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+	return page
+}
+
+// oggCRC is the CRC-32 of Ogg pages, with polynomial 0x04c11db7 and no reflection.
+func oggCRC(b []byte) uint32 {
+	var crc uint32
+	for _, c := range b {
+		crc ^= uint32(c) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
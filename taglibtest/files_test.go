@@ -0,0 +1,42 @@
+package taglibtest_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.senan.xyz/taglib"
+	"go.senan.xyz/taglib/taglibtest"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"a.flac", "a.mp3", "a.m4a", "a.ogg", "a.opus", "a.wav"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			want := map[string][]string{taglib.Title: {"Title"}, taglib.Artist: {"Artist 1", "Artist 2"}}
+			path := taglibtest.TempFile(t, name, want)
+
+			tags, err := taglib.ReadTags(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tags) != len(want) || tags[taglib.Title][0] != "Title" || len(tags[taglib.Artist]) != 2 {
+				t.Fatalf("tags %q", tags)
+			}
+
+			properties, err := taglib.ReadProperties(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if properties.Channels != 2 || properties.SampleRate == 0 {
+				t.Fatalf("properties %+v", properties)
+			}
+		})
+	}
+
+	err := taglibtest.WriteFile(t.TempDir()+"/a.xyz", nil)
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("err %v", err)
+	}
+}
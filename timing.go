@@ -0,0 +1,66 @@
+package taglib
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CallTiming is the time spent in each layer of a call into the Wasm module. Reading tags or properties makes one
+// call, while some operations make several, one for each exported function they use.
+type CallTiming struct {
+	// Function is the exported function called, such as "taglib_file_tags"
+	Function string
+	// Instantiate is the time spent instantiating the module for the file, including compiling the module on first
+	// use and waiting for [SetMaxInstances]. It is only reported with the first call on a module, and 0 after
+	Instantiate time.Duration
+	// Encode is the time spent copying the arguments into the module memory
+	Encode time.Duration
+	// Call is the time spent inside the Wasm function, where TagLib reads and writes the file
+	Call time.Duration
+	// Decode is the time spent copying the result out of the module memory
+	Decode time.Duration
+}
+
+var timingHook atomic.Pointer[func(CallTiming)]
+
+// SetTimingHook sets a function to be called with the timing of every call into the Wasm module, so slow operations
+// can be attributed to module instantiation, TagLib itself, or copying data across. It may be called from many
+// goroutines at once. A nil fn removes the hook, which is the default.
+func SetTimingHook(fn func(CallTiming)) {
+	if fn == nil {
+		timingHook.Store(nil)
+		return
+	}
+	timingHook.Store(&fn)
+}
+
+// callTimer measures the layers of a call for the timing hook, doing nothing if there is no hook.
+type callTimer struct {
+	hook   *func(CallTiming)
+	timing CallTiming
+	mark   time.Time
+}
+
+func newCallTimer(function string, instantiate time.Duration) callTimer {
+	t := callTimer{hook: timingHook.Load()}
+	if t.hook != nil {
+		t.timing = CallTiming{Function: function, Instantiate: instantiate}
+		t.mark = time.Now()
+	}
+	return t
+}
+
+// lap sets d to the time since the last lap.
+func (t *callTimer) lap(d *time.Duration) {
+	if t.hook != nil {
+		now := time.Now()
+		*d = now.Sub(t.mark)
+		t.mark = now
+	}
+}
+
+func (t *callTimer) report() {
+	if t.hook != nil {
+		(*t.hook)(t.timing)
+	}
+}
@@ -0,0 +1,194 @@
+package taglib
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrWouldDrop is returned by writes with [WithPreserveUnknown] which would drop unknown frames.
+var ErrWouldDrop = fmt.Errorf("write would drop unknown frames")
+
+// UnknownFrame is a frame or atom which isn't mapped to a tag key, such as proprietary DJ software data or store
+// receipts. TagLib carries these through saves byte for byte, unless the write drops the tag holding them.
+type UnknownFrame struct {
+	// Tag is the tag holding the frame, "ID3v2" or "MP4"
+	Tag string
+	// ID is the frame ID, such as "PRIV", or "----:mean:name" for MP4 freeform atoms
+	ID string
+	// Size is the size of the frame in bytes, excluding its header
+	Size int64
+}
+
+// ReadUnknownFrames reads the unknown frames of a file at the given path, in file order. These are the frames of
+// ID3v2 tags, at the start of the file or in WAV and AIFF chunks, which aren't read as tags, pictures, or by the ID3v2
+// functions of this package, and MP4 freeform atoms outside the "com.apple.iTunes" namespace.
+func ReadUnknownFrames(path string) (_ []UnknownFrame, err error) {
+	defer wrapErr(&err, "read unknown frames", path)
+	frames, _, err := readUnknownFrames(path)
+	return frames, err
+}
+
+// DroppedFrames lists the unknown frames, as read by [ReadUnknownFrames], which a write to the file at path with opts
+// would drop. Frames are dropped when the tag holding them isn't saved, as with [SkipID3v2] for WAV files, when an
+// ID3v2.2 frame has no ID3v2.3 or ID3v2.4 equivalent, and when they can't be stored in an older ID3v2 version chosen
+// with [WithID3v2Version].
+func DroppedFrames(path string, opts ...WriteOptionFunc) (_ []UnknownFrame, err error) {
+	defer wrapErr(&err, "read dropped frames", path)
+	cfg := newWriteConfig(0)
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return droppedFrames(path, cfg)
+}
+
+// WithPreserveUnknown fails the write with [ErrWouldDrop] before changing the file if it would drop any of the
+// frames listed by [DroppedFrames].
+func WithPreserveUnknown() WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.preserveUnknown = true }
+}
+
+func droppedFrames(path string, cfg writeConfig) ([]UnknownFrame, error) {
+	frames, wavID3v2, err := readUnknownFrames(path)
+	if err != nil {
+		return nil, err
+	}
+	var dropped []UnknownFrame
+	for _, f := range frames {
+		switch {
+		case f.Tag != "ID3v2":
+		case wavID3v2 && cfg.opts&SkipID3v2 != 0:
+			dropped = append(dropped, f)
+		case len(f.ID) == 3 && id3v22Frames[f.ID] == "":
+			// saving upgrades ID3v2.2 tags, dropping frames with no later equivalent
+			dropped = append(dropped, f)
+		case cfg.id3v2Version == 3 && id3v24OnlyFrames[f.ID]:
+			dropped = append(dropped, f)
+		}
+	}
+	return dropped, nil
+}
+
+// checkDroppedFrames returns an error wrapping [ErrWouldDrop] which names the frames a write would drop.
+func checkDroppedFrames(path string, cfg writeConfig) error {
+	dropped, err := droppedFrames(path, cfg)
+	if err != nil {
+		return fmt.Errorf("read dropped frames: %w", err)
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(dropped))
+	for _, f := range dropped {
+		ids = append(ids, f.ID)
+	}
+	return fmt.Errorf("%w: %s", ErrWouldDrop, strings.Join(ids, ", "))
+}
+
+// readUnknownFrames reads the unknown frames of a file, and whether its ID3v2 tag is in a WAV chunk.
+func readUnknownFrames(path string) ([]UnknownFrame, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	tag, inWAV, err := readID3v2Tag(f)
+	if err != nil {
+		return nil, false, err
+	}
+	if tag != nil {
+		return unknownID3v2Frames(tag), inWAV, nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, fmt.Errorf("stat: %w", err)
+	}
+	var magic [8]byte
+	if _, err := f.ReadAt(magic[:], 0); err != nil && err != io.EOF {
+		return nil, false, fmt.Errorf("read magic: %w", err)
+	}
+	if string(magic[4:8]) == "ftyp" {
+		return unknownMP4Atoms(f, info.Size()), false, nil
+	}
+	return nil, false, nil
+}
+
+// unknownID3v2Frames lists the frames of an ID3v2 tag which aren't in [knownID3v2Frames], after converting ID3v2.2
+// IDs with [id3v22Frames]. ID3v2.2 frames keep their stored ID.
+func unknownID3v2Frames(tag []byte) []UnknownFrame {
+	var frames []UnknownFrame
+	for _, f := range id3v2Frames(tag) {
+		id := f.ID
+		if len(id) == 3 {
+			id = id3v22Frames[id]
+		}
+		if !knownID3v2Frames[id] {
+			frames = append(frames, UnknownFrame{Tag: "ID3v2", ID: f.ID, Size: int64(len(f.Data))})
+		}
+	}
+	return frames
+}
+
+// unknownMP4Atoms lists the freeform atoms of an MP4 file's iTunes metadata outside the "com.apple.iTunes"
+// namespace, whose mean and name are stored in child atoms.
+func unknownMP4Atoms(r io.ReaderAt, end int64) []UnknownFrame {
+	var frames []UnknownFrame
+	for _, ilst := range mp4TagRegions(r, end) {
+		if ilst.Kind != "moov.udta.meta.ilst" {
+			continue
+		}
+		for _, item := range mp4Atoms(r, ilst.Offset+8, ilst.Offset+ilst.Length) {
+			if item.typ != "----" {
+				continue
+			}
+			var mean, name string
+			for _, child := range mp4Atoms(r, item.offset+item.headerSize, item.offset+item.size) {
+				// mean and name are full atoms, with a version and flags before the string
+				if child.typ != "mean" && child.typ != "name" || child.size < child.headerSize+4 {
+					continue
+				}
+				data := make([]byte, child.size-child.headerSize-4)
+				if _, err := r.ReadAt(data, child.offset+child.headerSize+4); err != nil {
+					continue
+				}
+				if child.typ == "mean" {
+					mean = string(data)
+				} else {
+					name = string(data)
+				}
+			}
+			if mean != "com.apple.iTunes" {
+				frames = append(frames, UnknownFrame{Tag: "MP4", ID: "----:" + mean + ":" + name, Size: item.size - item.headerSize})
+			}
+		}
+	}
+	return frames
+}
+
+// knownID3v2Frames are the ID3v2.3 and ID3v2.4 frames read as tags by TagLib, as complex properties, or by the ID3v2
+// functions of this package, which WriteTags may replace.
+var knownID3v2Frames = func() map[string]bool {
+	known := map[string]bool{}
+	for _, id := range strings.Fields(`
+		TALB TBPM TCAT TCMP TCOM TCON TCOP TDAT TDEN TDES TDLY TDOR TDRC TDRL TDTG TENC TEXT TFLT TGID TIME TIPL TIT1
+		TIT2 TIT3 TKEY TLAN TLEN TMCL TMED TMOO TOAL TOFN TOLY TOPE TORY TOWN TPE1 TPE2 TPE3 TPE4 TPOS TPRO TPUB TRCK
+		TRDA TRSN TRSO TSO2 TSOA TSOC TSOP TSOT TSRC TSSE TSST TXXX TYER IPLS
+		WCOM WCOP WFED WOAF WOAR WOAS WORS WPAY WPUB WXXX
+		APIC COMM GEOB GRP1 MVIN MVNM PCST UFID USLT
+	`) {
+		known[id] = true
+	}
+	return known
+}()
+
+// id3v24OnlyFrames are the unknown frames TagLib drops when saving an ID3v2.3 tag, since ID3v2.3 has no equivalent.
+var id3v24OnlyFrames = map[string]bool{
+	"ASPI": true,
+	"EQU2": true,
+	"RVA2": true,
+	"SEEK": true,
+	"SIGN": true,
+}
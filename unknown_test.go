@@ -0,0 +1,125 @@
+package taglib_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestUnknownFramesPreserved(t *testing.T) {
+	t.Parallel()
+
+	// an ID3v2.3 tag with a title, proprietary data, and a relative volume frame only defined in ID3v2.4
+	frame := func(id string, data []byte) []byte {
+		return append(append([]byte(id), 0, 0, byte(len(data)>>8), byte(len(data)), 0, 0), data...)
+	}
+	priv := frame("PRIV", append([]byte("com.example.dj\x00"), 0xde, 0xad, 0x00, 0xbe, 0xef))
+	var frames []byte
+	frames = append(frames, frame("TIT2", []byte("\x00Title"))...)
+	frames = append(frames, priv...)
+	frames = append(frames, frame("RVA2", []byte("track\x00\x01\x00\x10\x00"))...)
+	tag := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(frames))}, frames...)
+
+	audio := egMP3[10+(int(egMP3[6])<<21|int(egMP3[7])<<14|int(egMP3[8])<<7|int(egMP3[9])):]
+	path := tmpf(t, append(tag, audio...), "eg.mp3")
+
+	unknown, err := taglib.ReadUnknownFrames(path)
+	nilErr(t, err)
+	eq(t, len(unknown), 2)
+	eq(t, unknown[0], taglib.UnknownFrame{Tag: "ID3v2", ID: "PRIV", Size: int64(len(priv) - 10)})
+	eq(t, unknown[1].ID, "RVA2")
+
+	dropped, err := taglib.DroppedFrames(path)
+	nilErr(t, err)
+	eq(t, len(dropped), 0)
+
+	dropped, err = taglib.DroppedFrames(path, taglib.WithID3v2Version(3))
+	nilErr(t, err)
+	eq(t, len(dropped), 1)
+	eq(t, dropped[0].ID, "RVA2")
+
+	before, err := os.ReadFile(path)
+	nilErr(t, err)
+	err = taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"New"}}, taglib.WithID3v2Version(3), taglib.WithPreserveUnknown())
+	if !errors.Is(err, taglib.ErrWouldDrop) {
+		t.Fatalf("expected ErrWouldDrop, got %v", err)
+	}
+	after, err := os.ReadFile(path)
+	nilErr(t, err)
+	eq(t, bytes.Equal(before, after), true)
+
+	err = taglib.WriteTagsOptions(path, map[string][]string{taglib.Title: {"New"}}, taglib.WithClear(), taglib.WithPreserveUnknown())
+	nilErr(t, err)
+
+	// ID3v2.4 frame headers use syncsafe sizes, which match for frames under 128 bytes
+	after, err = os.ReadFile(path)
+	nilErr(t, err)
+	eq(t, bytes.Contains(after, priv), true)
+
+	unknown, err = taglib.ReadUnknownFrames(path)
+	nilErr(t, err)
+	eq(t, len(unknown), 2)
+}
+
+func TestID3v22Upgrade(t *testing.T) {
+	t.Parallel()
+
+	// an ID3v2.2 tag as written by old iTunes versions, with three character IDs and sizes, and a deprecated RVA frame
+	frame := func(id string, data []byte) []byte {
+		return append(append([]byte(id), 0, byte(len(data)>>8), byte(len(data))), data...)
+	}
+	var frames []byte
+	frames = append(frames, frame("TT2", []byte("\x00Title"))...)
+	frames = append(frames, frame("TP1", []byte("\x00Artist"))...)
+	frames = append(frames, frame("TAL", []byte("\x00Album"))...)
+	frames = append(frames, frame("TRK", []byte("\x003/12"))...)
+	frames = append(frames, frame("RVA", []byte{0x03, 0x10, 0x01, 0x00, 0x01, 0x00})...)
+	tag := append([]byte{'I', 'D', '3', 2, 0, 0, 0, 0, 0, byte(len(frames))}, frames...)
+
+	audio := egMP3[10+(int(egMP3[6])<<21|int(egMP3[7])<<14|int(egMP3[8])<<7|int(egMP3[9])):]
+	path := tmpf(t, append(tag, audio...), "eg.mp3")
+
+	raw, err := taglib.ReadID3v2Frames(path)
+	nilErr(t, err)
+	eq(t, len(raw), 5)
+	eq(t, raw[0].ID, "TT2")
+	eq(t, string(raw[0].Data), "\x00Title")
+
+	tags, err := taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{
+		taglib.Title:       {"Title"},
+		taglib.Artist:      {"Artist"},
+		taglib.Album:       {"Album"},
+		taglib.TrackNumber: {"3/12"},
+	})
+
+	dropped, err := taglib.DroppedFrames(path)
+	nilErr(t, err)
+	eq(t, len(dropped), 1)
+	eq(t, dropped[0].ID, "RVA")
+
+	err = taglib.WriteTags(path, map[string][]string{taglib.Genre: {"Ambient"}}, 0)
+	nilErr(t, err)
+
+	header, err := taglib.ReadID3v2Header(path)
+	nilErr(t, err)
+	eq(t, header.MajorVersion, 4)
+
+	raw, err = taglib.ReadID3v2Frames(path)
+	nilErr(t, err)
+	var ids []string
+	for _, f := range raw {
+		ids = append(ids, f.ID)
+	}
+	eq(t, strings.Join(ids, " "), "TIT2 TPE1 TALB TRCK TCON")
+
+	tags, err = taglib.ReadTags(path)
+	nilErr(t, err)
+	eq(t, strings.Join(tags[taglib.Title], ""), "Title")
+	eq(t, strings.Join(tags[taglib.Genre], ""), "Ambient")
+}
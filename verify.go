@@ -0,0 +1,66 @@
+package taglib
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrVerifyFailed is returned by writes with [WithVerify] when the saved file doesn't read back as written.
+var ErrVerifyFailed = fmt.Errorf("verify failed")
+
+// WithVerify re-opens the file after saving and checks that each key written reads back with the same values, or not
+// at all if it was written without values, and that the length, channels, and sample rate of the audio are unchanged. A mismatch fails
+// the write with [ErrVerifyFailed], after the file has been changed, so archival pipelines can restore it from a copy.
+func WithVerify() WriteOptionFunc {
+	return func(cfg *writeConfig) { cfg.verify = true }
+}
+
+// readVerifyProperties reads the properties compared by [WithVerify] before a write.
+func readVerifyProperties(path string) (Properties, error) {
+	mod, err := newModuleRO(path)
+	if err != nil {
+		return Properties{}, fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+	return readTagLibProperties(&mod, path)
+}
+
+// verifyWrite checks that the file at path has the tags written, with the audio properties it had before.
+func verifyWrite(path string, tags map[string][]string, m Mapping, before Properties) error {
+	mod, err := newModuleRO(path)
+	if err != nil {
+		return fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	got, err := readFileTagsModule(&mod, path, m)
+	if err != nil {
+		return fmt.Errorf("read tags: %w", err)
+	}
+	for k, vs := range tags {
+		k = strings.ToUpper(k)
+		if !slices.Equal(got[k], vs) {
+			return fmt.Errorf("tag %q read back as %q, not %q: %w", k, got[k], vs, ErrVerifyFailed)
+		}
+	}
+
+	after, err := readTagLibProperties(&mod, path)
+	if err != nil {
+		return fmt.Errorf("read properties: %w", err)
+	}
+	if !sameAudioProperties(before, after) {
+		return fmt.Errorf("audio properties changed from %s to %s: %w", audioPropertiesString(before), audioPropertiesString(after), ErrVerifyFailed)
+	}
+	return nil
+}
+
+// sameAudioProperties compares the length, channels, and sample rate of the audio stream, which TagLib reads for
+// every format. The bitrate is left out, since TagLib computes it from the file size for some formats.
+func sameAudioProperties(a, b Properties) bool {
+	return a.Length == b.Length && a.Channels == b.Channels && a.SampleRate == b.SampleRate
+}
+
+func audioPropertiesString(p Properties) string {
+	return fmt.Sprintf("%v %d ch %d Hz", p.Length, p.Channels, p.SampleRate)
+}
@@ -0,0 +1,340 @@
+package taglib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// FS is a filesystem backend which TagLib reads and writes files through, such as an S3 bucket, an SFTP server, or
+// an encrypted store, without staging the files locally. It is used with [ReadTagsFS], [ReadPropertiesFS], and
+// [WriteTagsFS]. Of the limits set with [SetLimits], only MaxMemory applies to files in an FS.
+type FS interface {
+	// Open opens the file with the given slash separated name. TagLib detects the format from the extension of the
+	// name. write is true if the file will be written to, and an FS which can't write should return an error
+	Open(name string, write bool) (FSFile, error)
+}
+
+// FSFile is a file opened by an [FS]. TagLib reads and writes at offsets, and truncates a file when its tags shrink.
+type FSFile interface {
+	io.ReaderAt
+	io.WriterAt
+	// Truncate changes the size of the file
+	Truncate(size int64) error
+	// Size returns the size of the file
+	Size() (int64, error)
+	Close() error
+}
+
+// ReadTagsFS reads all metadata tags from the file with the given name in fsys, like [ReadTags], including the keys of
+// [RegisterMapping] and the iTunes keys of MP4 files.
+func ReadTagsFS(fsys FS, name string) (_ map[string][]string, err error) {
+	defer wrapFSErr(&err, "read tags", name)
+	mod, err := newModuleFS(fsys, true)
+	if err != nil {
+		return nil, fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	tags, err := readTagsModule(&mod, vfsPath(name))
+	if err != nil {
+		return nil, err
+	}
+	f, err := openVFSFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := readNativeTagsFile(f, name, tags, nil); err != nil {
+		return nil, fmt.Errorf("read native tags: %w", err)
+	}
+	return tags, nil
+}
+
+// ReadPropertiesFS reads the audio properties of the file with the given name in fsys, like [ReadProperties]. The
+// ModTime is left zero, since an [FSFile] has none.
+func ReadPropertiesFS(fsys FS, name string) (_ Properties, err error) {
+	defer wrapFSErr(&err, "read properties", name)
+	mod, err := newModuleFS(fsys, true)
+	if err != nil {
+		return Properties{}, fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	properties, err := readTagLibProperties(&mod, vfsPath(name))
+	if err != nil {
+		return Properties{}, err
+	}
+	f, err := openVFSFile(fsys, name)
+	if err != nil {
+		return Properties{}, err
+	}
+	defer f.Close()
+	if err := readGoProperties(f, &properties); err != nil {
+		return Properties{}, err
+	}
+	if properties.WavPack != nil {
+		if correction, err := openVFSFile(fsys, strings.TrimSuffix(name, path.Ext(name))+".wvc"); err == nil {
+			correction.Close()
+			properties.WavPack.CorrectionFile = true
+		}
+	}
+	return properties, nil
+}
+
+// openVFSFile opens the file with the given name in fsys for reading in Go.
+func openVFSFile(fsys FS, name string) (*vfsFile, error) {
+	f, err := fsys.Open(strings.TrimPrefix(vfsPath(name), "/"), false)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	return &vfsFile{f: f, name: path.Base(name)}, nil
+}
+
+// WriteTagsFS writes the metadata key-values pairs to the file with the given name in fsys, like [WriteTags], but only
+// TagLib saves the file, without the changes [WriteTags] makes in Go after it. The options which rewrite the file,
+// [Compact], [ID3v2Footer], [ID3v2Unsynchronisation], [SkipID3v2], and [SkipRIFFInfo], fail with
+// [errors.ErrUnsupported], as do the keys of [RegisterMapping] and the iTunes keys of MP4 files. Comments of ID3v2
+// tags keep one value for each description, in a frame of unknown language, with the other values of a key written to
+// a TXXX frame. Reads of files in an FS are the same as those of files on disk, other than the zero ModTime.
+func WriteTagsFS(fsys FS, name string, tags map[string][]string, opts WriteOption) (err error) {
+	defer wrapFSErr(&err, "write tags", name)
+	if opts&(Compact|ID3v2Footer|ID3v2Unsynchronisation|SkipID3v2|SkipRIFFInfo) != 0 {
+		return fmt.Errorf("rewrite options with fs: %w", errors.ErrUnsupported)
+	}
+	for k := range tags {
+		if slices.ContainsFunc(nativeMapping(nil), func(km KeyMapping) bool { return strings.EqualFold(km.Key, k) }) {
+			return fmt.Errorf("mapped key %q with fs: %w", k, errors.ErrUnsupported)
+		}
+	}
+
+	f, err := fsys.Open(strings.TrimPrefix(vfsPath(name), "/"), false)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	rule := keyRuleOf(f)
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	cfg := newWriteConfig(opts)
+	tags, keyErrs := validTags(rule, tags, cfg)
+	if len(keyErrs) > 0 && len(tags) == 0 && cfg.opts&Clear == 0 {
+		return errors.Join(keyErrs...)
+	}
+
+	mod, err := newModuleFS(fsys, false)
+	if err != nil {
+		return fmt.Errorf("init module: %w", err)
+	}
+	defer mod.close()
+
+	_, err = writeTagsModule(&mod, vfsPath(name), tags, cfg)
+	return joinKeyErrors(keyErrs, err)
+}
+
+// ReadTagsFile reads all metadata tags from f, like [ReadTags]. The name is only used to detect the format from its
+// extension, and f is left open.
+func ReadTagsFile(f FSFile, name string) (map[string][]string, error) {
+	return ReadTagsFS(singleFS{f, name}, name)
+}
+
+// WriteTagsFile writes the metadata key-values pairs to f, like [WriteTagsFS]. TagLib commits the changes through
+// WriteAt and Truncate, writing only the tags back in place, unless they outgrow their padding and the rest of the
+// file has to move. The name is only used to detect the format from its extension, and f is left open.
+func WriteTagsFile(f FSFile, name string, tags map[string][]string, opts WriteOption) error {
+	return WriteTagsFS(singleFS{f, name}, name, tags, opts)
+}
+
+// singleFS is an [FS] holding a single open file, which is left open when TagLib closes it.
+type singleFS struct {
+	f    FSFile
+	name string
+}
+
+func (s singleFS) Open(name string, _ bool) (FSFile, error) {
+	if name != strings.TrimPrefix(vfsPath(s.name), "/") {
+		return nil, fs.ErrNotExist
+	}
+	return nopCloseFile{s.f}, nil
+}
+
+type nopCloseFile struct{ FSFile }
+
+func (nopCloseFile) Close() error { return nil }
+
+// wrapFSErr is like wrapErr for a file in an [FS], whose name isn't a path on disk.
+func wrapFSErr(err *error, op, name string) {
+	if *err != nil {
+		*err = &Error{Op: op, Path: name, Err: *err}
+	}
+}
+
+// newModuleFS instantiates a module with fsys mounted at its root. wazero's only stable mounts are host directories and
+// read-only fs.FS, so the writable mount uses its experimental sysfs API, which go.mod pins with the wazero version.
+func newModuleFS(fsys FS, readOnly bool) (module, error) {
+	start := time.Now()
+	mount := &vfsMount{fsys: fsys, readOnly: readOnly}
+	fsConfig := wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(mount, "/")
+	return instantiateModule(fsConfig, currentLimits(), start)
+}
+
+// vfsPath is the path in the module of a file in an [FS], which is mounted at the root.
+func vfsPath(name string) string {
+	return path.Join("/", name)
+}
+
+// vfsMount adapts an [FS] to the filesystem of the module, which has a single directory holding its files.
+type vfsMount struct {
+	experimentalsys.UnimplementedFS
+	fsys     FS
+	readOnly bool
+}
+
+func (m *vfsMount) OpenFile(name string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	if name == "." || name == "" {
+		return &vfsDir{}, 0
+	}
+	write := flag&(experimentalsys.O_WRONLY|experimentalsys.O_RDWR) != 0
+	if write && m.readOnly {
+		return nil, experimentalsys.EROFS
+	}
+	f, err := m.fsys.Open(name, write)
+	if err != nil {
+		return nil, vfsErrno(err)
+	}
+
+	// wazero adapts an fs.File to a module file, keeping the offset for reads, writes, and seeks
+	file := &vfsFile{f: f, name: path.Base(name)}
+	adapted, errno := (&sysfs.AdaptFS{FS: file}).OpenFile(name, flag, perm)
+	if errno != 0 {
+		f.Close()
+		return nil, errno
+	}
+	return vfsTruncater{adapted, f}, 0
+}
+
+func (m *vfsMount) Stat(name string) (sys.Stat_t, experimentalsys.Errno) {
+	f, errno := m.OpenFile(name, experimentalsys.O_RDONLY, 0)
+	if errno != 0 {
+		return sys.Stat_t{}, errno
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+func (m *vfsMount) Lstat(name string) (sys.Stat_t, experimentalsys.Errno) {
+	return m.Stat(name)
+}
+
+func vfsErrno(err error) experimentalsys.Errno {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, fs.ErrNotExist):
+		return experimentalsys.ENOENT
+	case errors.Is(err, fs.ErrPermission):
+		return experimentalsys.EPERM
+	default:
+		return experimentalsys.EIO
+	}
+}
+
+// vfsDir is the root directory of a [vfsMount], which can't be listed.
+type vfsDir struct {
+	experimentalsys.UnimplementedFile
+}
+
+func (*vfsDir) IsDir() (bool, experimentalsys.Errno) { return true, 0 }
+
+func (*vfsDir) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	return sys.Stat_t{Mode: fs.ModeDir | 0o755, Nlink: 1}, 0
+}
+
+// vfsTruncater adds truncating to a module file adapted from an fs.File, which has no Truncate.
+type vfsTruncater struct {
+	experimentalsys.File
+	f FSFile
+}
+
+func (t vfsTruncater) Truncate(size int64) experimentalsys.Errno {
+	return vfsErrno(t.f.Truncate(size))
+}
+
+// vfsFile adapts a [FSFile] to an fs.File, and to an fs.FS which opens it, for wazero to adapt to a module file.
+type vfsFile struct {
+	f      FSFile
+	name   string
+	offset int64
+}
+
+func (f *vfsFile) Open(string) (fs.File, error) { return f, nil }
+
+func (f *vfsFile) Stat() (fs.FileInfo, error) {
+	size, err := f.f.Size()
+	if err != nil {
+		return nil, err
+	}
+	return vfsFileInfo{name: f.name, size: size}, nil
+}
+
+func (f *vfsFile) Read(b []byte) (int, error) {
+	n, err := f.f.ReadAt(b, f.offset)
+	f.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *vfsFile) ReadAt(b []byte, offset int64) (int, error) { return f.f.ReadAt(b, offset) }
+
+func (f *vfsFile) Write(b []byte) (int, error) {
+	n, err := f.f.WriteAt(b, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *vfsFile) WriteAt(b []byte, offset int64) (int, error) { return f.f.WriteAt(b, offset) }
+
+func (f *vfsFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size, err := f.f.Size()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	}
+	if offset < 0 {
+		return 0, fs.ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *vfsFile) Close() error { return f.f.Close() }
+
+type vfsFileInfo struct {
+	name string
+	size int64
+}
+
+func (i vfsFileInfo) Name() string       { return i.name }
+func (i vfsFileInfo) Size() int64        { return i.size }
+func (i vfsFileInfo) Mode() fs.FileMode  { return 0o644 }
+func (i vfsFileInfo) ModTime() time.Time { return time.Time{} }
+func (i vfsFileInfo) IsDir() bool        { return false }
+func (i vfsFileInfo) Sys() any           { return nil }
@@ -0,0 +1,175 @@
+package taglib_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := memFS{"music/eg.flac": &memFile{data: append([]byte{}, egFLAC...)}}
+
+	tags, err := taglib.ReadTagsFS(fsys, "music/eg.flac")
+	nilErr(t, err)
+	want, err := taglib.ReadTags(tmpf(t, egFLAC, "eg.flac"))
+	nilErr(t, err)
+	tagEq(t, tags, want)
+
+	properties, err := taglib.ReadPropertiesFS(fsys, "music/eg.flac")
+	nilErr(t, err)
+	eq(t, properties.SampleRate, 48_000)
+
+	err = taglib.WriteTagsFS(fsys, "music/eg.flac", map[string][]string{
+		taglib.Title: {"Title"},
+	}, taglib.Clear)
+	nilErr(t, err)
+
+	tags, err = taglib.ReadTagsFS(fsys, "music/eg.flac")
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}})
+
+	// the written file is the same as one written on disk
+	path := tmpf(t, fsys["music/eg.flac"].data, "eg.flac")
+	tags, err = taglib.ReadTags(path)
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}})
+
+	// keys are folded and checked the same as on disk
+	err = taglib.WriteTagsFS(fsys, "music/eg.flac", map[string][]string{
+		"artist": {"Artist"},
+		"A=B":    {"x"},
+	}, 0)
+	var keyErr *taglib.KeyError
+	eq(t, errors.As(err, &keyErr), true)
+	eq(t, keyErr.Key, "A=B")
+	tags, err = taglib.ReadTagsFS(fsys, "music/eg.flac")
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}, taglib.Artist: {"Artist"}})
+
+	_, err = taglib.ReadTagsFS(fsys, "missing.flac")
+	eq(t, err != nil, true)
+
+	err = taglib.WriteTagsFS(fsys, "music/eg.flac", nil, taglib.Compact)
+	eq(t, errors.Is(err, errors.ErrUnsupported), true)
+}
+
+func TestFSMatchesDisk(t *testing.T) {
+	t.Parallel()
+
+	entries, err := os.ReadDir("testdata")
+	nilErr(t, err)
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".jpg" {
+			continue
+		}
+		t.Run(e.Name(), func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(filepath.Join("testdata", e.Name()))
+			nilErr(t, err)
+			path := tmpf(t, data, e.Name())
+			fsys := memFS{e.Name(): &memFile{data: data}}
+
+			tags, err := taglib.ReadTags(path)
+			nilErr(t, err)
+			fsTags, err := taglib.ReadTagsFS(fsys, e.Name())
+			nilErr(t, err)
+			tagEq(t, fsTags, tags)
+
+			// an FSFile has no modification time
+			properties, err := taglib.ReadProperties(path)
+			nilErr(t, err)
+			properties.ModTime = time.Time{}
+			fsProperties, err := taglib.ReadPropertiesFS(fsys, e.Name())
+			nilErr(t, err)
+			if !reflect.DeepEqual(fsProperties, properties) {
+				t.Errorf("got %+v, want %+v", fsProperties, properties)
+			}
+		})
+	}
+}
+
+// memFS is an in memory [taglib.FS].
+type memFS map[string]*memFile
+
+func (m memFS) Open(name string, _ bool) (taglib.FSFile, error) {
+	f, ok := m[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
+
+type memFile struct {
+	data []byte
+}
+
+func (f *memFile) ReadAt(b []byte, offset int64) (int, error) {
+	if offset >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.data[offset:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(b []byte, offset int64) (int, error) {
+	if end := offset + int64(len(b)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	return copy(f.data[offset:], b), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	if size > int64(len(f.data)) {
+		return os.ErrInvalid
+	}
+	f.data = f.data[:size]
+	return nil
+}
+
+func (f *memFile) Size() (int64, error) { return int64(len(f.data)), nil }
+func (f *memFile) Close() error         { return nil }
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	f := &countingFile{memFile: memFile{data: append([]byte{}, egFLAC...)}}
+	err := taglib.WriteTagsFile(f, "eg.flac", map[string][]string{
+		taglib.Title: {"Title"},
+	}, taglib.Clear)
+	nilErr(t, err)
+
+	tags, err := taglib.ReadTagsFile(f, "eg.flac")
+	nilErr(t, err)
+	tagEq(t, tags, map[string][]string{taglib.Title: {"Title"}})
+
+	// the tags fit in the padding, so only the metadata blocks are written back, not the audio
+	regions, err := taglib.ReadTagRegions(tmpf(t, f.data, "eg.flac"))
+	nilErr(t, err)
+	last := regions[len(regions)-1]
+	eq(t, f.written > 0, true)
+	eq(t, int64(f.written) <= last.Offset+last.Length, true)
+	eq(t, len(f.data), len(egFLAC))
+}
+
+type countingFile struct {
+	memFile
+	written int
+}
+
+func (f *countingFile) WriteAt(b []byte, offset int64) (int, error) {
+	f.written += len(b)
+	return f.memFile.WriteAt(b, offset)
+}
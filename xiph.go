@@ -0,0 +1,38 @@
+package taglib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReadXiphVendor reads the vendor string of the Vorbis comment in a FLAC or Ogg file at the given path. The vendor string
+// identifies the encoder used, such as "reference libFLAC 1.4.3 20230623". Files without a Vorbis comment return an
+// empty string.
+//
+// The vendor string is preserved when writing with [WriteTags].
+func ReadXiphVendor(path string) (_ string, err error) {
+	defer wrapErr(&err, "read xiph vendor", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	tagSize, err := id3v2TagSize(f)
+	if err != nil {
+		return "", err
+	}
+	var magic [4]byte
+	if _, err := f.ReadAt(magic[:], tagSize); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read magic: %w", err)
+	}
+	switch string(magic[:]) {
+	case "fLaC":
+		return flacVendor(f), nil
+	case "OggS":
+		return oggVendor(f), nil
+	}
+	return "", nil
+}
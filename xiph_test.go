@@ -0,0 +1,42 @@
+package taglib_test
+
+import (
+	"testing"
+
+	"go.senan.xyz/taglib"
+)
+
+func TestReadXiphVendor(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		vendor string
+	}{
+		{"eg.flac", egFLAC, "Lavf61.1.100"},
+		{"eg.ogg", egOgg, "Lavf61.7.100"},
+		{"eg.oga", egOggFLAC, "go-taglib"},
+		{"eg.opus", egOpus, "go-taglib"},
+		{"eg.spx", egSpeex, "go-taglib"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := tmpf(t, tc.data, tc.name)
+
+			vendor, err := taglib.ReadXiphVendor(path)
+			nilErr(t, err)
+			eq(t, vendor, tc.vendor)
+
+			// kept when saving
+			nilErr(t, taglib.WriteTags(path, map[string][]string{taglib.Title: {"Title"}}, taglib.Clear))
+			vendor, err = taglib.ReadXiphVendor(path)
+			nilErr(t, err)
+			eq(t, vendor, tc.vendor)
+		})
+	}
+
+	vendor, err := taglib.ReadXiphVendor(tmpf(t, egMP3, "eg.mp3"))
+	nilErr(t, err)
+	eq(t, vendor, "")
+}